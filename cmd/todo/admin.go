@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/srmty09/Todo-App/internal/backup"
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/storage/sqlite"
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+const adminUsage = `usage: todo admin [-config path] [-<field> value ...] <command>
+
+commands:
+  users list                       list users with their task counts
+  users create -name N -email E    create a user
+  users delete ID                  delete a user and their tasks
+  tasks reassign FROM TO           move every task of user FROM to user TO
+  db vacuum                        rebuild the database file
+  db analyze                       refresh query planner statistics
+  db check                         run integrity and foreign key checks
+  db stats                         show database size and table row counts
+  backup create                    take a backup into backup.dir
+  backup list                      list backups, newest first
+  backup restore FILE | -at TIME   replace the database with a verified backup`
+
+// adminCommand is one "todo admin <group> <name>" subcommand
+type adminCommand struct {
+	group string
+	name  string
+	run   func(ctx context.Context, cfg *config.Config, s *sqlite.Sqlite, args []string) error
+	// closed runs without the database open, s is nil
+	closed bool
+}
+
+var adminCommands = []adminCommand{
+	{group: "users", name: "list", run: adminListUsers},
+	{group: "users", name: "create", run: adminCreateUser},
+	{group: "users", name: "delete", run: adminDeleteUser},
+	{group: "tasks", name: "reassign", run: adminReassignTasks},
+	{group: "db", name: "vacuum", run: adminVacuum},
+	{group: "db", name: "analyze", run: adminAnalyze},
+	{group: "db", name: "check", run: adminCheck},
+	{group: "db", name: "stats", run: adminStats},
+	{group: "backup", name: "create", run: adminCreateBackup},
+	{group: "backup", name: "list", run: adminListBackups},
+	{group: "backup", name: "restore", run: adminRestore, closed: true},
+}
+
+// adminUsageError marks a bad invocation, answered with the usage text
+type adminUsageError struct{ msg string }
+
+func (e adminUsageError) Error() string { return e.msg }
+
+// runAdmin implements "todo admin ...". It works on the database file named
+// by the configuration directly and is meant to be run while the server is
+// stopped.
+func runAdmin(args []string) int {
+	cfg, rest, err := config.LoadArgs(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(rest) < 2 {
+		fmt.Fprintln(os.Stderr, adminUsage)
+		return 2
+	}
+
+	var cmd *adminCommand
+	for i := range adminCommands {
+		if adminCommands[i].group == rest[0] && adminCommands[i].name == rest[1] {
+			cmd = &adminCommands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n%s\n", rest[0]+" "+rest[1], adminUsage)
+		return 2
+	}
+
+	var s *sqlite.Sqlite
+	if !cmd.closed {
+		if err := cfg.CheckDirs(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		s, err = sqlite.New(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "open %s: %v\n", cfg.Storage_path, err)
+			return 1
+		}
+		defer s.Close()
+	}
+
+	if err := cmd.run(context.Background(), cfg, s, rest[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if _, ok := err.(adminUsageError); ok {
+			fmt.Fprintln(os.Stderr, adminUsage)
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// parseIds parses exactly n positional user ids
+func parseIds(args []string, n int) ([]int64, error) {
+	if len(args) != n {
+		return nil, adminUsageError{fmt.Sprintf("expected %d id(s), got %d", n, len(args))}
+	}
+	ids := make([]int64, n)
+	for i, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, adminUsageError{fmt.Sprintf("invalid id %q", arg)}
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func adminListUsers(ctx context.Context, cfg *config.Config, s *sqlite.Sqlite, args []string) error {
+	users, err := s.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tEMAIL\tTASKS\tCOMPLETED")
+	for _, u := range users {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%d\n", u.Id, u.Name, u.Email, u.Tasks, u.CompletedTasks)
+	}
+	return tw.Flush()
+}
+
+func adminCreateUser(ctx context.Context, cfg *config.Config, s *sqlite.Sqlite, args []string) error {
+	fs := flag.NewFlagSet("users create", flag.ContinueOnError)
+	var user types.User
+	fs.StringVar(&user.Name, "name", "", "user name")
+	fs.StringVar(&user.Email, "email", "", "user email")
+	if err := fs.Parse(args); err != nil {
+		return adminUsageError{err.Error()}
+	}
+	if err := validator.New().Struct(user); err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+	id, err := s.CreateUser(ctx, user.Name, user.Email)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created user %d\n", id)
+	return nil
+}
+
+func adminDeleteUser(ctx context.Context, cfg *config.Config, s *sqlite.Sqlite, args []string) error {
+	ids, err := parseIds(args, 1)
+	if err != nil {
+		return err
+	}
+	exists, err := s.UserExists(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("user with id %d does not exist", ids[0])
+	}
+	if err := s.DeleteUser(ctx, ids[0]); err != nil {
+		return err
+	}
+	fmt.Printf("deleted user %d\n", ids[0])
+	return nil
+}
+
+func adminReassignTasks(ctx context.Context, cfg *config.Config, s *sqlite.Sqlite, args []string) error {
+	ids, err := parseIds(args, 2)
+	if err != nil {
+		return err
+	}
+	moved, err := s.ReassignTasks(ctx, ids[0], ids[1])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("moved %d task(s) from user %d to user %d\n", moved, ids[0], ids[1])
+	return nil
+}
+
+func adminVacuum(ctx context.Context, cfg *config.Config, s *sqlite.Sqlite, args []string) error {
+	if err := s.Vacuum(ctx); err != nil {
+		return err
+	}
+	fmt.Println("vacuum complete")
+	return nil
+}
+
+func adminAnalyze(ctx context.Context, cfg *config.Config, s *sqlite.Sqlite, args []string) error {
+	if err := s.Analyze(ctx); err != nil {
+		return err
+	}
+	fmt.Println("analyze complete")
+	return nil
+}
+
+func adminCheck(ctx context.Context, cfg *config.Config, s *sqlite.Sqlite, args []string) error {
+	problems, err := s.IntegrityCheck(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problem(s) found", len(problems))
+	}
+	fmt.Println("ok")
+	return nil
+}
+
+func adminStats(ctx context.Context, cfg *config.Config, s *sqlite.Sqlite, args []string) error {
+	stats, err := s.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("schema version  %d\n", stats.SchemaVersion)
+	fmt.Printf("size            %d bytes (%d pages of %d, %d free)\n\n",
+		stats.PageSize*stats.PageCount, stats.PageCount, stats.PageSize, stats.FreePages)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tROWS\tINDEXES")
+	for _, t := range stats.Tables {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", t.Name, t.Rows, t.Indexes)
+	}
+	return tw.Flush()
+}
+
+func adminCreateBackup(ctx context.Context, cfg *config.Config, s *sqlite.Sqlite, args []string) error {
+	info, err := backup.New(s, cfg.Backup).Create(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("backup written to %s (%d bytes)\n", info.Path, info.Size)
+	return nil
+}
+
+func adminListBackups(ctx context.Context, cfg *config.Config, s *sqlite.Sqlite, args []string) error {
+	backups, err := backup.New(s, cfg.Backup).List()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTAKEN\tSIZE")
+	for _, b := range backups {
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", b.Name, b.CreatedAt.Format(time.RFC3339), b.Size)
+	}
+	return tw.Flush()
+}
+
+// adminRestore restores either the named file or, with -at, the newest
+// backup taken at or before the given time. The server must be stopped.
+func adminRestore(ctx context.Context, cfg *config.Config, s *sqlite.Sqlite, args []string) error {
+	fs := flag.NewFlagSet("backup restore", flag.ContinueOnError)
+	at := fs.String("at", "", "restore the newest backup taken at or before this RFC 3339 time")
+	if err := fs.Parse(args); err != nil {
+		return adminUsageError{err.Error()}
+	}
+
+	var file string
+	switch {
+	case *at != "" && fs.NArg() == 0:
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return adminUsageError{fmt.Sprintf("invalid -at time: %v", err)}
+		}
+		info, err := backup.New(nil, cfg.Backup).At(t)
+		if err != nil {
+			return err
+		}
+		file = info.Path
+	case *at == "" && fs.NArg() == 1:
+		file = fs.Arg(0)
+	default:
+		return adminUsageError{"give either a backup file or -at"}
+	}
+
+	kept, err := backup.Restore(ctx, file, cfg.Storage_path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("restored %s from %s\n", cfg.Storage_path, file)
+	if kept != "" {
+		fmt.Printf("previous database kept at %s\n", kept)
+	}
+	return nil
+}
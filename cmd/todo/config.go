@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/srmty09/Todo-App/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// runConfig implements "todo config <subcommand>"
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: todo config print [-config path] [-<field> value ...]")
+		return 2
+	}
+
+	cfg, err := config.Load(args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	source := "defaults, environment and flags"
+	if cfg.Path != "" {
+		source = "defaults, " + cfg.Path + ", environment and flags"
+	}
+	out, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("# effective configuration from %s\n", source)
+	os.Stdout.Write(out)
+	return 0
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"log"
 	"log/slog"
 	"net/http"
@@ -12,45 +13,157 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/srmty09/Todo-App/internal/backup"
+	"github.com/srmty09/Todo-App/internal/certs"
 	"github.com/srmty09/Todo-App/internal/config"
-	"github.com/srmty09/Todo-App/internal/http/handlers/tasks"
-	"github.com/srmty09/Todo-App/internal/http/handlers/users"
+	"github.com/srmty09/Todo-App/internal/events"
+	"github.com/srmty09/Todo-App/internal/http/handlers/health"
+	"github.com/srmty09/Todo-App/internal/http/middleware"
+	"github.com/srmty09/Todo-App/internal/http/openapi"
+	"github.com/srmty09/Todo-App/internal/http/routes"
+	"github.com/srmty09/Todo-App/internal/metrics"
+	"github.com/srmty09/Todo-App/internal/ratelimit"
 	"github.com/srmty09/Todo-App/internal/storage/sqlite"
+	"github.com/srmty09/Todo-App/internal/tracing"
+	"github.com/srmty09/Todo-App/internal/version"
+	"github.com/srmty09/Todo-App/internal/webhooks"
 	// "github.com/srmty09/Todo-App/internal/utils/response"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		os.Exit(runAdmin(os.Args[2:]))
+	}
+
 	// Load config
-	cfg := config.MustLoad()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.CheckDirs(); err != nil {
+		log.Fatal(err)
+	}
+	slog.SetLogLoggerLevel(cfg.Level())
+
+	// Tracing must be set up before anything grabs a tracer
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	storage, err := sqlite.New(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
-	slog.Info("Storage initialized", slog.String("env", cfg.Env), slog.String("version", "1.0.0"))
-	
-	router := http.NewServeMux()
+	slog.Info("Storage initialized", slog.String("env", cfg.Env), slog.String("version", version.Version))
+
+	// Metrics for HTTP traffic, storage calls and the connection pool
+	appMetrics := metrics.New()
+	appMetrics.RegisterDB(storage.Db, "todo")
+	appMetrics.RegisterDB(storage.Reader, "todo_read")
+
+	// Task changes are published to the event streams
+	bus := events.NewBus(cfg.Events.ReplayBuffer, cfg.Events.SubscriberBuffer)
+	store := events.WrapStorage(metrics.WrapStorage(tracing.WrapStorage(storage), appMetrics), bus)
 
-	// User routes
-	router.HandleFunc("POST /api/user", users.New(storage))
-	router.HandleFunc("GET /api/user/{id}", users.GetUserInfo(storage))
-	router.HandleFunc("DELETE /api/user/{id}", users.DeleteUserInfo(storage))
+	// Every event is also queued for the user's webhooks
+	dispatcher := webhooks.NewDispatcher(storage, cfg.Webhooks)
+	bus.Listen(dispatcher.Enqueue)
 	
-	// Task routes
-	router.HandleFunc("POST /api/user/{id}/add_task/",tasks.Add(storage))
-	router.HandleFunc("GET /api/user/{id}/todo/{task_id}",tasks.GetSingleTask(storage))
-	router.HandleFunc("GET /api/user/{id}/todo/",tasks.GetTodo(storage))
-	router.HandleFunc("PATCH /api/user/{id}/todo/completed/{task_id}",tasks.CompletedTask(storage))
-	router.HandleFunc("PATCH /api/user/{id}/todo/incompleted/{task_id}",tasks.IncompletedTask(storage))
-	router.HandleFunc("DELETE /api/user/{id}/todo/{task_id}",tasks.DeleteTask(storage))
-	router.HandleFunc("PATCH /api/user/{id}/todo/{task_id}",tasks.EditTask(storage))
+	// Rate limits are enforced per route, after the mux has matched
+	limiter, err := ratelimit.New(cfg.RateLimit, ratelimit.NewMemoryStore())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	router := routes.New()
+	router.Use(limiter.Middleware)
+	cors := middleware.NewCORS(cfg.CORS, router.Route)
+
+	// Scheduled backups and draining on shutdown need these outside the routes
+	backups := backup.New(storage, cfg.Backup)
+	healthState := &health.State{}
+	registerRoutes(router, &services{
+		cfg:        cfg,
+		storage:    storage,
+		store:      store,
+		bus:        bus,
+		cors:       cors,
+		dispatcher: dispatcher,
+		backups:    backups,
+		metrics:    appMetrics,
+		health:     healthState,
+	})
+
+	// Refuse to start when the spec and the registered routes drift apart
+	if err := openapi.Verify(router.Patterns()); err != nil {
+		log.Fatal(err)
+	}
+
+	// Every request gets a trace span, an id, a scoped logger, an access log
+	// line, panic recovery and CORS handling, including preflights the mux
+	// would otherwise reject
+	handler := middleware.Chain(router,
+		middleware.Tracing(router.Route),
+		middleware.RequestID,
+		middleware.Logger(slog.Default(), router.Route),
+		middleware.Metrics(appMetrics, router.Route),
+		middleware.Recover,
+		cors.Middleware,
+		middleware.MaxBodySize(cfg.HTTPServer.MaxBodyBytes),
+	)
 
 	server := &http.Server{
-		Addr:    cfg.HTTPServer.Addr,
-		Handler: router,
+		Addr:              cfg.HTTPServer.Addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.HTTPServer.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTPServer.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTPServer.WriteTimeout,
+		IdleTimeout:       cfg.HTTPServer.IdleTimeout,
+		MaxHeaderBytes:    cfg.HTTPServer.MaxHeaderBytes,
+	}
+	// Event streams never go idle on their own; end them so Shutdown does
+	// not wait out its timeout
+	server.RegisterOnShutdown(bus.Close)
+
+	// Serve HTTPS when a certificate is configured
+	tlsCfg := cfg.HTTPServer.TLS
+	useTLS := tlsCfg.CertFile != ""
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	var certReloader *certs.Reloader
+	if useTLS {
+		certReloader, err = certs.NewReloader(tlsCfg.CertFile, tlsCfg.KeyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if tlsCfg.ReloadInterval > 0 {
+			go certReloader.Watch(watchCtx, tlsCfg.ReloadInterval)
+		}
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certReloader.GetCertificate,
+		}
 	}
 
-	slog.Info("server starting", slog.String("addr", server.Addr))
+	// Scheduled backups run until shutdown
+	backupsDone := make(chan struct{})
+	go func() {
+		backups.Run(watchCtx)
+		close(backupsDone)
+	}()
+
+	// Webhook deliveries, including retries left over from the last run
+	webhooksDone := make(chan struct{})
+	go func() {
+		dispatcher.Run(watchCtx)
+		close(webhooksDone)
+	}()
+
+	slog.Info("server starting", slog.String("addr", server.Addr), slog.Bool("tls", useTLS))
 
 	// Channel to listen for OS signals
 	done := make(chan os.Signal, 1)
@@ -58,18 +171,48 @@ func main() {
 
 	// Start server in goroutine
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if useTLS {
+			// The certificate comes from TLSConfig.GetCertificate
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			slog.Error("server failed", slog.Any("error", err))
 			os.Exit(1)
 		}
 	}()
 
+	// SIGHUP re-reads the config and applies what can change live
+	live := newLiveReloader(os.Args[1:], cfg, limiter, cors, certReloader)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			slog.Info("reload signal received")
+			live.Reload()
+		}
+	}()
+
 	// Block until signal is received
 	<-done
 	slog.Info("shutdown signal received")
 
+	// Fail readiness while still serving, so probes see the server drain
+	// and stop routing to it before the listener closes. A second signal
+	// skips the wait.
+	healthState.StartDraining()
+	if delay := cfg.HTTPServer.DrainDelay; delay > 0 {
+		slog.Info("draining before shutdown", slog.Duration("delay", delay))
+		select {
+		case <-time.After(delay):
+		case <-done:
+		}
+	}
+
 	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPServer.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
@@ -78,6 +221,17 @@ func main() {
 		slog.Info("server stopped gracefully")
 	}
 
+	// Flush pending spans
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("failed to flush traces", slog.Any("error", err))
+	}
+
+	// Let a running backup and in-flight webhook deliveries stop before the
+	// database goes away
+	stopWatching()
+	<-backupsDone
+	<-webhooksDone
+
 	// Close database connection
 	if err := storage.Close(); err != nil {
 		slog.Error("failed to close database", slog.Any("error", err))
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/srmty09/Todo-App/internal/certs"
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/http/middleware"
+	"github.com/srmty09/Todo-App/internal/ratelimit"
+)
+
+// liveReloader re-reads the configuration (on SIGHUP) and applies the
+// settings that can change while requests are being served. Anything else is
+// reported as needing a restart and keeps its running value. The running
+// config is never changed in place: a reload stores an updated copy.
+type liveReloader struct {
+	args []string
+
+	// mu keeps reloads from overlapping
+	mu      sync.Mutex
+	running atomic.Pointer[config.Config]
+	limiter *ratelimit.Limiter
+	cors    *middleware.CORS
+	// nil when serving plain HTTP
+	certs *certs.Reloader
+}
+
+func newLiveReloader(args []string, running *config.Config, limiter *ratelimit.Limiter, cors *middleware.CORS, certs *certs.Reloader) *liveReloader {
+	l := &liveReloader{args: args, limiter: limiter, cors: cors, certs: certs}
+	l.running.Store(running)
+	return l
+}
+
+// reloadResult lists the config paths a reload applied, those waiting for a
+// restart and those that failed to apply
+type reloadResult struct {
+	applied, restart, failed []string
+}
+
+// isLive reports whether a config path is applied without a restart
+func isLive(path string) bool {
+	return path == "log_level" || strings.HasPrefix(path, "rate_limit.") || strings.HasPrefix(path, "cors.")
+}
+
+func (l *liveReloader) Reload() reloadResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	next, err := config.Load(l.args)
+	if err != nil {
+		slog.Error("config reload failed, keeping the running config", slog.Any("error", err))
+		return reloadResult{}
+	}
+
+	running := l.running.Load()
+	updated := *running
+	var result reloadResult
+	rateLimitChanged, corsChanged := false, false
+	for _, path := range config.Changes(running, next) {
+		switch {
+		case path == "log_level":
+			slog.SetLogLoggerLevel(next.Level())
+			updated.LogLevel = next.LogLevel
+			result.applied = append(result.applied, path)
+		case strings.HasPrefix(path, "cors."):
+			corsChanged = true
+			result.applied = append(result.applied, path)
+		case isLive(path):
+			rateLimitChanged = true
+			result.applied = append(result.applied, path)
+		default:
+			result.restart = append(result.restart, path)
+		}
+	}
+	if rateLimitChanged {
+		if err := l.limiter.Update(next.RateLimit); err != nil {
+			slog.Error("rate limit reload failed", slog.Any("error", err))
+			result.applied = without(result.applied, "rate_limit.")
+			result.failed = append(result.failed, "rate_limit")
+		} else {
+			updated.RateLimit = next.RateLimit
+		}
+	}
+
+	if corsChanged {
+		l.cors.Update(next.CORS)
+		updated.CORS = next.CORS
+	}
+
+	// Certificates may have been renewed in place, so reload them even when
+	// nothing changed. New paths wait for the restart reported above.
+	runningTLS, nextTLS := running.HTTPServer.TLS, next.HTTPServer.TLS
+	if l.certs != nil && runningTLS.CertFile == nextTLS.CertFile && runningTLS.KeyFile == nextTLS.KeyFile {
+		if err := l.certs.Reload(); err != nil {
+			slog.Error("tls certificate reload failed", slog.Any("error", err))
+			result.failed = append(result.failed, "http_server.tls")
+		} else {
+			result.applied = append(result.applied, "tls certificate")
+		}
+	}
+
+	l.running.Store(&updated)
+	slog.Info("config reloaded",
+		slog.Any("applied", result.applied),
+		slog.Any("restart_required", result.restart),
+		slog.Any("failed", result.failed),
+	)
+	return result
+}
+
+// without drops the entries starting with prefix
+func without(paths []string, prefix string) []string {
+	out := paths[:0]
+	for _, p := range paths {
+		if !strings.HasPrefix(p, prefix) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/certs"
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/http/middleware"
+	"github.com/srmty09/Todo-App/internal/ratelimit"
+)
+
+// writeKeyPair writes a self-signed certificate and its key
+func writeKeyPair(t *testing.T, certFile string, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLiveReload(t *testing.T) {
+	level := slog.SetLogLoggerLevel(slog.LevelInfo)
+	t.Cleanup(func() { slog.SetLogLoggerLevel(level) })
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeKeyPair(t, certFile, keyFile)
+	configPath := filepath.Join(dir, "config.yaml")
+	writeConfig := func(logLevel string, addr string, origin string, requests int, certFile string) {
+		t.Helper()
+		yaml := fmt.Sprintf(`log_level: %q
+storage_path: %q
+http_server:
+  addr: %q
+  tls:
+    cert_file: %q
+    key_file: %q
+rate_limit:
+  enabled: true
+  default:
+    key: "ip"
+    requests: %d
+    period: 1m
+cors:
+  allowed_origins: [%q]
+`, logLevel, filepath.Join(dir, "todo.db"), addr, certFile, keyFile, requests, origin)
+		if err := os.WriteFile(configPath, []byte(yaml), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeConfig("info", "localhost:8080", "https://a.example", 100, certFile)
+	args := []string{"-config", configPath}
+	cfg, err := config.Load(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter, err := ratelimit.New(cfg.RateLimit, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cors := middleware.NewCORS(cfg.CORS, func(r *http.Request) string { return r.Pattern })
+	certReloader, err := certs.NewReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := newLiveReloader(args, cfg, limiter, cors, certReloader)
+
+	// Live settings are applied, the rest waits for a restart, and the
+	// certificate is read again
+	writeConfig("debug", "localhost:9090", "https://b.example", 10, certFile)
+	result := live.Reload()
+	for _, path := range []string{"log_level", "cors.allowed_origins", "rate_limit.default.requests", "tls certificate"} {
+		if !slices.Contains(result.applied, path) {
+			t.Errorf("%s not applied: %+v", path, result)
+		}
+	}
+	if !slices.Equal(result.restart, []string{"http_server.addr"}) || len(result.failed) != 0 {
+		t.Errorf("reload: %+v", result)
+	}
+	if !cors.AllowsOrigin("https://b.example") || cors.AllowsOrigin("https://a.example") {
+		t.Error("cors origins not updated")
+	}
+	if !slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("log level not updated")
+	}
+
+	// The running config is replaced, not changed under its readers, and
+	// keeps the settings that need a restart
+	running := live.running.Load()
+	if running == cfg || cfg.LogLevel != "info" || cfg.RateLimit.Default.Requests != 100 || cfg.CORS.AllowedOrigins[0] != "https://a.example" {
+		t.Errorf("the first config was changed in place: %+v", cfg)
+	}
+	if running.LogLevel != "debug" || running.RateLimit.Default.Requests != 10 || running.CORS.AllowedOrigins[0] != "https://b.example" ||
+		running.HTTPServer.Addr != "localhost:8080" {
+		t.Errorf("running config after reload: %+v", running)
+	}
+
+	// New certificate paths need a restart; the files in use are not read
+	// again, so their removal goes unnoticed until then
+	renewed := filepath.Join(dir, "renewed.pem")
+	writeKeyPair(t, renewed, keyFile)
+	if err := os.Remove(certFile); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig("debug", "localhost:9090", "https://b.example", 10, renewed)
+	result = live.Reload()
+	if slices.Contains(result.applied, "tls certificate") || len(result.failed) != 0 || !slices.Contains(result.restart, "http_server.tls.cert_file") {
+		t.Errorf("reload with new certificate paths: %+v", result)
+	}
+
+	// A config that does not load leaves everything as it was
+	running = live.running.Load()
+	if err := os.WriteFile(configPath, []byte("log_level: [\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if result := live.Reload(); len(result.applied)+len(result.restart)+len(result.failed) != 0 || live.running.Load() != running {
+		t.Errorf("reload of a broken config: %+v", result)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/backup"
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/events"
+	"github.com/srmty09/Todo-App/internal/http/handlers/admin"
+	"github.com/srmty09/Todo-App/internal/http/handlers/health"
+	"github.com/srmty09/Todo-App/internal/http/handlers/tasks"
+	"github.com/srmty09/Todo-App/internal/http/handlers/users"
+	hooks "github.com/srmty09/Todo-App/internal/http/handlers/webhooks"
+	"github.com/srmty09/Todo-App/internal/http/middleware"
+	"github.com/srmty09/Todo-App/internal/http/openapi"
+	"github.com/srmty09/Todo-App/internal/http/routes"
+	"github.com/srmty09/Todo-App/internal/metrics"
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/storage/sqlite"
+	"github.com/srmty09/Todo-App/internal/webhooks"
+)
+
+// The v1 API is deprecated in favour of /api/v2 and will be removed at sunset
+var (
+	v1DeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	v1Sunset       = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// services are what the route handlers are built from. storage is the
+// database itself, for the feature stores kept off the Storage interface;
+// store is the same database wrapped with tracing, metrics and events.
+type services struct {
+	cfg        *config.Config
+	storage    *sqlite.Sqlite
+	store      storage.Storage
+	bus        *events.Bus
+	cors       *middleware.CORS
+	dispatcher *webhooks.Dispatcher
+	backups    *backup.Manager
+	metrics    *metrics.Metrics
+	health     *health.State
+}
+
+// registerRoutes registers every route the server serves. Each one must be
+// described in the OpenAPI document; openapi.Verify checks that they match.
+func registerRoutes(router *routes.Router, s *services) {
+	cfg, storage, store, bus := s.cfg, s.storage, s.store, s.bus
+
+	// v1 routes are kept for existing clients and announce their retirement
+	v1 := middleware.Deprecated(v1DeprecatedAt, v1Sunset, "/api/v2")
+
+	// User routes
+	router.Handle("POST /api/user", v1(users.New(store)))
+	router.Handle("GET /api/user/{id}", v1(users.GetUserInfo(store)))
+	router.Handle("DELETE /api/user/{id}", v1(users.DeleteUserInfo(store)))
+	router.Handle("GET /api/user/{id}/export", v1(users.Export(store, storage)))
+	router.Handle("POST /api/user/import", v1(users.Import(store, storage, bus)))
+	router.Handle("POST /api/user/{id}/import", v1(users.Import(store, storage, bus)))
+
+	// Task routes
+	router.Handle("POST /api/user/{id}/add_task/", v1(tasks.Add(store)))
+	router.Handle("GET /api/user/{id}/todo/{task_id}", v1(tasks.GetSingleTask(store)))
+	router.Handle("GET /api/user/{id}/todo/", v1(tasks.GetTodo(store)))
+	router.Handle("PATCH /api/user/{id}/todo/completed/{task_id}", v1(tasks.CompletedTask(store)))
+	router.Handle("PATCH /api/user/{id}/todo/incompleted/{task_id}", v1(tasks.IncompletedTask(store)))
+	router.Handle("DELETE /api/user/{id}/todo/{task_id}", v1(tasks.DeleteTask(store)))
+	router.Handle("PATCH /api/user/{id}/todo/{task_id}", v1(tasks.EditTask(store)))
+	router.Handle("GET /api/user/{id}/events", v1(tasks.Events(store, bus, cfg.Events.Heartbeat)))
+	router.Handle("GET /api/user/{id}/todo/export.csv", v1(tasks.ExportCSV(store)))
+	router.Handle("POST /api/user/{id}/todo/import", v1(tasks.ImportCSV(store, storage)))
+
+	// v2 user routes
+	router.HandleFunc("POST /api/v2/users", users.Create(store))
+	router.HandleFunc("GET /api/v2/users/{id}", users.GetUserInfo(store))
+	router.HandleFunc("DELETE /api/v2/users/{id}", users.Remove(store))
+	router.HandleFunc("GET /api/v2/users/{id}/export", users.Export(store, storage))
+	router.HandleFunc("POST /api/v2/users/import", users.Import(store, storage, bus))
+	router.HandleFunc("POST /api/v2/users/{id}/import", users.Import(store, storage, bus))
+
+	// v2 task routes
+	router.HandleFunc("POST /api/v2/users/{id}/tasks", tasks.Create(store))
+	router.HandleFunc("GET /api/v2/users/{id}/tasks", tasks.List(store))
+	router.HandleFunc("GET /api/v2/users/{id}/tasks/{task_id}", tasks.Get(store))
+	router.HandleFunc("PATCH /api/v2/users/{id}/tasks/{task_id}", tasks.Update(store))
+	router.HandleFunc("PUT /api/v2/users/{id}/tasks/{task_id}", tasks.Replace(store))
+	router.HandleFunc("DELETE /api/v2/users/{id}/tasks/{task_id}", tasks.Remove(store))
+	router.HandleFunc("GET /api/v2/users/{id}/events", tasks.Events(store, bus, cfg.Events.Heartbeat))
+	router.HandleFunc("GET /api/v2/users/{id}/socket", tasks.Socket(store, bus, cfg.WebSocket, s.cors.AllowsOrigin))
+	router.HandleFunc("POST /api/v2/users/{id}/sync", tasks.Sync(store, storage, bus))
+	router.HandleFunc("GET /api/v2/users/{id}/tasks/export.csv", tasks.ExportCSV(store))
+	router.HandleFunc("POST /api/v2/users/{id}/tasks/import", tasks.ImportCSV(store, storage))
+	router.HandleFunc("PUT /api/v2/users/{id}/tasks/{task_id}/due", tasks.SetDue(store, storage, bus))
+	router.HandleFunc("GET /api/v2/users/{id}/tasks/export.ics", tasks.ExportICal(store, storage))
+	router.HandleFunc("POST /api/v2/users/{id}/tasks/import.ics", tasks.ImportICal(store, storage, storage))
+	router.HandleFunc("GET /api/v2/users/{id}/tasks/export.txt", tasks.ExportTodoTxt(store))
+	router.HandleFunc("POST /api/v2/users/{id}/tasks/import.txt", tasks.ImportTodoTxt(store, storage))
+	router.HandleFunc("POST /api/v2/users/{id}/calendar", tasks.CreateCalendarFeed(store, storage))
+	router.HandleFunc("DELETE /api/v2/users/{id}/calendar", tasks.RemoveCalendarFeed(store, storage))
+	router.HandleFunc("GET /api/v2/calendars/{token}/tasks.ics", tasks.CalendarFeed(store, storage, storage))
+
+	// v2 webhook routes
+	router.HandleFunc("POST /api/v2/users/{id}/webhooks", hooks.Create(store, storage))
+	router.HandleFunc("GET /api/v2/users/{id}/webhooks", hooks.List(store, storage))
+	router.HandleFunc("GET /api/v2/users/{id}/webhooks/{webhook_id}", hooks.Get(storage))
+	router.HandleFunc("DELETE /api/v2/users/{id}/webhooks/{webhook_id}", hooks.Remove(storage))
+	router.HandleFunc("GET /api/v2/users/{id}/webhooks/{webhook_id}/deliveries", hooks.Deliveries(storage))
+	router.HandleFunc("GET /api/v2/users/{id}/webhooks/{webhook_id}/deliveries/{delivery_id}", hooks.Delivery(storage))
+	router.HandleFunc("POST /api/v2/users/{id}/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver", hooks.Redeliver(storage, s.dispatcher.Wake))
+
+	// API description
+	router.HandleFunc("GET /api/openapi.json", openapi.Spec())
+	router.HandleFunc("GET /api/docs", openapi.Docs())
+
+	// Probes for the orchestrator
+	router.HandleFunc("GET /healthz", health.Healthz())
+	router.HandleFunc("GET /readyz", health.Readyz(s.health,
+		health.Check{Name: "database", Func: storage.Ping},
+		health.Check{Name: "migrations", Func: storage.CheckMigrations},
+	))
+	router.HandleFunc("GET /version", health.Version())
+
+	// Operator endpoints, behind the admin token
+	adminOnly := middleware.AdminToken(cfg.Admin.Token)
+	router.Handle("POST /api/admin/backups", adminOnly(admin.CreateBackup(s.backups)))
+	router.Handle("GET /api/admin/backups", adminOnly(admin.ListBackups(s.backups)))
+
+	// Prometheus scrape endpoint
+	router.Handle("GET /metrics", s.metrics.Handler())
+}
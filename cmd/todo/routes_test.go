@@ -0,0 +1,48 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/srmty09/Todo-App/internal/backup"
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/events"
+	"github.com/srmty09/Todo-App/internal/http/handlers/health"
+	"github.com/srmty09/Todo-App/internal/http/middleware"
+	"github.com/srmty09/Todo-App/internal/http/openapi"
+	"github.com/srmty09/Todo-App/internal/http/routes"
+	"github.com/srmty09/Todo-App/internal/metrics"
+	"github.com/srmty09/Todo-App/internal/storage/sqlite"
+	"github.com/srmty09/Todo-App/internal/webhooks"
+)
+
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	cfg, err := config.Load([]string{"-storage_path", filepath.Join(t.TempDir(), "todo.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage, err := sqlite.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	bus := events.NewBus(cfg.Events.ReplayBuffer, cfg.Events.SubscriberBuffer)
+	defer bus.Close()
+	router := routes.New()
+	registerRoutes(router, &services{
+		cfg:        cfg,
+		storage:    storage,
+		store:      storage,
+		bus:        bus,
+		cors:       middleware.NewCORS(cfg.CORS, router.Route),
+		dispatcher: webhooks.NewDispatcher(storage, cfg.Webhooks),
+		backups:    backup.New(storage, cfg.Backup),
+		metrics:    metrics.New(),
+		health:     &health.State{},
+	})
+
+	if err := openapi.Verify(router.Patterns()); err != nil {
+		t.Fatal(err)
+	}
+}
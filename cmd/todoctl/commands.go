@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/srmty09/Todo-App/internal/client"
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// parseArgs parses flags appearing anywhere among the positional arguments,
+// so "add -p high title" and "add title -p high" both work
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, &usageError{msg: err.Error()}
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// flags returns a flag set for a command, accepting -o like the global flags
+func (e *env) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Func("o", "output format: table or json", e.setOutput)
+	return fs
+}
+
+func parseTaskIds(args []string) ([]int64, error) {
+	if len(args) == 0 {
+		return nil, &usageError{msg: "missing task id"}
+	}
+	ids := make([]int64, 0, len(args))
+	for _, a := range args {
+		id, err := strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return nil, &usageError{msg: fmt.Sprintf("invalid task id %q", a)}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func runLogin(ctx context.Context, e *env, args []string) error {
+	fs := e.flags("login")
+	server := fs.String("server", "http://localhost:8080", "API base URL")
+	userId := fs.Int64("user", 0, "user id to act as")
+	token := fs.String("token", "", "bearer token sent with every request")
+	name := fs.String("name", "default", "profile name")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *userId <= 0 {
+		return &usageError{msg: "-user is required"}
+	}
+	e.profiles.Profiles[*name] = client.Profile{Server: *server, UserId: *userId, Token: *token}
+	e.profiles.Current = *name
+	if err := e.profiles.Save(e.profilesPath); err != nil {
+		return err
+	}
+	fmt.Printf("profile %q saved to %s\n", *name, e.profilesPath)
+	return nil
+}
+
+func runAdd(ctx context.Context, e *env, args []string) error {
+	fs := e.flags("add")
+	description := fs.String("d", "", "description (defaults to the title)")
+	priority := fs.String("p", "medium", "priority: low, medium or high")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	title := strings.Join(rest, " ")
+	if title == "" {
+		return &usageError{msg: "missing title"}
+	}
+	if *description == "" {
+		*description = title
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	task, err := c.AddTask(ctx, types.TaskMetaData{Title: title, Description: *description, Priority: *priority})
+	if err != nil {
+		return err
+	}
+	return printTasks(e.output, []types.TaskMetaData{*task})
+}
+
+func runList(ctx context.Context, e *env, args []string) error {
+	fs := e.flags("ls")
+	status := fs.String("status", "", "completed or incomplete")
+	search := fs.String("search", "", "text to look for in title and description")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	tasks, err := c.ListTasks(ctx, *status, *search)
+	if err != nil {
+		return err
+	}
+	return printTasks(e.output, tasks)
+}
+
+func runShow(ctx context.Context, e *env, args []string) error {
+	rest, err := parseArgs(e.flags("show"), args)
+	if err != nil {
+		return err
+	}
+	ids, err := parseTaskIds(rest)
+	if err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	task, err := c.GetTask(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	return printTask(e.output, task)
+}
+
+// setCompleted marks every task in args as completed or not
+func setCompleted(ctx context.Context, e *env, args []string, completed bool) error {
+	rest, err := parseArgs(e.flags("done"), args)
+	if err != nil {
+		return err
+	}
+	ids, err := parseTaskIds(rest)
+	if err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	var updated []types.TaskMetaData
+	for _, id := range ids {
+		task, err := c.UpdateTask(ctx, id, client.TaskUpdate{Completed: &completed})
+		if err != nil {
+			return fmt.Errorf("task %d: %w", id, err)
+		}
+		updated = append(updated, *task)
+	}
+	return printTasks(e.output, updated)
+}
+
+func runDone(ctx context.Context, e *env, args []string) error {
+	return setCompleted(ctx, e, args, true)
+}
+
+func runUndo(ctx context.Context, e *env, args []string) error {
+	return setCompleted(ctx, e, args, false)
+}
+
+func runEdit(ctx context.Context, e *env, args []string) error {
+	fs := e.flags("edit")
+	var update client.TaskUpdate
+	fs.Func("t", "new title", func(s string) error { update.Title = &s; return nil })
+	fs.Func("d", "new description", func(s string) error { update.Description = &s; return nil })
+	fs.Func("p", "new priority", func(s string) error { update.Priority = &s; return nil })
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	ids, err := parseTaskIds(rest)
+	if err != nil {
+		return err
+	}
+	if update.Title == nil && update.Description == nil && update.Priority == nil {
+		return &usageError{msg: "nothing to change, pass -t, -d or -p"}
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	task, err := c.UpdateTask(ctx, ids[0], update)
+	if err != nil {
+		return err
+	}
+	return printTask(e.output, task)
+}
+
+func runRemove(ctx context.Context, e *env, args []string) error {
+	rest, err := parseArgs(e.flags("rm"), args)
+	if err != nil {
+		return err
+	}
+	ids, err := parseTaskIds(rest)
+	if err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := c.DeleteTask(ctx, id); err != nil {
+			return fmt.Errorf("task %d: %w", id, err)
+		}
+		if e.output == "table" {
+			fmt.Printf("deleted task %d\n", id)
+		}
+	}
+	return nil
+}
+
+// transferFormat picks the file format: the -format flag if given, else the
+// file's extension, else todo.txt for a file without one. An extension that
+// is none of the formats needs -format rather than a guess.
+func transferFormat(flagValue string, path string) (string, error) {
+	if flagValue == "" {
+		switch ext := filepath.Ext(path); ext {
+		case "":
+			return "txt", nil
+		case ".txt", ".csv", ".ics":
+			return strings.TrimPrefix(ext, "."), nil
+		default:
+			return "", &usageError{msg: fmt.Sprintf("cannot tell the format of %s from its extension, use -format txt, csv or ics", path)}
+		}
+	}
+	switch flagValue {
+	case "txt", "csv", "ics":
+		return flagValue, nil
+	}
+	return "", &usageError{msg: fmt.Sprintf("unknown format %q, expected txt, csv or ics", flagValue)}
+}
+
+func runExport(ctx context.Context, e *env, args []string) error {
+	fs := e.flags("export")
+	formatFlag := fs.String("format", "", "txt (todo.txt), csv or ics (default: from -f, else txt)")
+	file := fs.String("f", "", "file to write (default: standard output)")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	format, err := transferFormat(*formatFlag, *file)
+	if err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+	if *file == "" || *file == "-" {
+		return c.ExportTasks(ctx, format, os.Stdout)
+	}
+	f, err := os.Create(*file)
+	if err != nil {
+		return err
+	}
+	if err := c.ExportTasks(ctx, format, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runImport(ctx context.Context, e *env, args []string) error {
+	fs := e.flags("import")
+	formatFlag := fs.String("format", "", "txt (todo.txt), csv or ics (default: from the file extension, txt if it has none)")
+	dryRun := fs.Bool("dry-run", false, "only check the file")
+	var mapping []string
+	fs.Func("map", "column:field for a CSV column with another name (repeatable)", func(s string) error {
+		mapping = append(mapping, s)
+		return nil
+	})
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return &usageError{msg: "expected one file, or - for standard input"}
+	}
+	format, err := transferFormat(*formatFlag, rest[0])
+	if err != nil {
+		return err
+	}
+	c, err := e.client()
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader = os.Stdin
+	if rest[0] != "-" {
+		f, err := os.Open(rest[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		body = f
+	}
+	// A failed import may still come with a report of the rows it got to
+	report, err := c.ImportTasks(ctx, format, body, *dryRun, mapping)
+	if report != nil {
+		if err := printImportReport(e.output, report); err != nil {
+			return err
+		}
+	}
+	return err
+}
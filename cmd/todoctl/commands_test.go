@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/srmty09/Todo-App/internal/client"
+	"github.com/srmty09/Todo-App/internal/types"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// captureStdout runs f and returns what it printed
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+	f()
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// testEnv is an env whose current profile points at a server answering with
+// handler
+func testEnv(t *testing.T, handler http.HandlerFunc) *env {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &env{
+		profiles: &client.Profiles{
+			Current:  "default",
+			Profiles: map[string]client.Profile{"default": {Server: server.URL, UserId: 7}},
+		},
+		profilesPath: filepath.Join(t.TempDir(), "profiles.json"),
+		output:       "table",
+	}
+}
+
+func TestTransferFormat(t *testing.T) {
+	for _, tc := range []struct {
+		flag, path string
+		want       string
+	}{
+		{"", "todo.txt", "txt"},
+		{"", "tasks.csv", "csv"},
+		{"", "calendar.ics", "ics"},
+		{"", "todo", "txt"},
+		{"", "-", "txt"},
+		{"", "", "txt"},
+		{"csv", "export.dat", "csv"},
+		{"ics", "-", "ics"},
+		{"", "tasks.xlsx", ""},
+		{"", "notes.md", ""},
+		{"xlsx", "tasks.csv", ""},
+	} {
+		got, err := transferFormat(tc.flag, tc.path)
+		var usageErr *usageError
+		if got != tc.want || (tc.want == "") != errors.As(err, &usageErr) {
+			t.Errorf("transferFormat(%q, %q) = %q, %v, want %q", tc.flag, tc.path, got, err, tc.want)
+		}
+	}
+}
+
+func TestRunImport(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "todo.txt")
+	if err := os.WriteFile(file, []byte("Buy milk\nWalk dog\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var got *http.Request
+	var body string
+	e := testEnv(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		response.WriteJson(w, http.StatusCreated, types.ImportReport{Rows: 2, Valid: 2, Imported: 2, TaskIds: []int64{1, 2}, Errors: []types.ImportError{}})
+	})
+	var err error
+	out := captureStdout(t, func() { err = runImport(context.Background(), e, []string{file}) })
+	if err != nil || out != "imported 2 of 2 tasks\n" {
+		t.Errorf("import: %q %v", out, err)
+	}
+	if got.URL.Path != "/api/v2/users/7/tasks/import.txt" || got.Header.Get("Content-Type") != "text/plain" || body != "Buy milk\nWalk dog\n" {
+		t.Errorf("sent %s %v %q", got.URL, got.Header, body)
+	}
+
+	// An unknown extension is refused before anything is sent
+	got = nil
+	if err := runImport(context.Background(), e, []string{filepath.Join(dir, "tasks.xlsx")}); err == nil || got != nil {
+		t.Errorf("import of an .xlsx file: %v, sent %v", err, got)
+	}
+}
+
+func TestRunImportErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tasks.csv")
+	if err := os.WriteFile(file, []byte("title\nBuy milk\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name    string
+		status  int
+		answer  interface{}
+		printed string
+		error   string
+	}{
+		{"stopped part way", http.StatusInternalServerError,
+			types.ImportReport{Rows: 2, Valid: 2, Imported: 1, Errors: []types.ImportError{}, Error: "database or disk is full"},
+			"imported 1 of 2 tasks\n", "database or disk is full (HTTP 500)"},
+		{"no usable row", http.StatusUnprocessableEntity,
+			types.ImportReport{Rows: 1, Errors: []types.ImportError{{Row: 2, Errors: []string{"Title is required"}}}},
+			"imported 0 of 1 tasks\nline 2: Title is required\n", "HTTP 422"},
+		{"unknown user", http.StatusNotFound, response.GeneralError(errors.New("user with id 7 does not exist")),
+			"", "user with id 7 does not exist (HTTP 404)"},
+	} {
+		e := testEnv(t, func(w http.ResponseWriter, r *http.Request) {
+			response.WriteJson(w, tc.status, tc.answer)
+		})
+		var err error
+		out := captureStdout(t, func() { err = runImport(context.Background(), e, []string{file}) })
+		// The report is printed and the server's own error returned
+		if out != tc.printed || err == nil || !strings.Contains(err.Error(), tc.error) {
+			t.Errorf("%s: printed %q, returned %v", tc.name, out, err)
+		}
+	}
+}
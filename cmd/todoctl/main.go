@@ -0,0 +1,133 @@
+// Command todoctl manages tasks from the terminal through the HTTP API.
+//
+//	todoctl login -server http://localhost:8080 -user 1
+//	todoctl add -p high "Write the report"
+//	todoctl ls -status incomplete -search report
+//	todoctl done 3
+//	todoctl export -f todo.txt
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/srmty09/Todo-App/internal/client"
+)
+
+// env holds what every command needs once the global flags are parsed
+type env struct {
+	profiles     *client.Profiles
+	profilesPath string
+	profileName  string
+	output       string
+}
+
+// client builds an API client for the selected profile
+func (e *env) client() (*client.Client, error) {
+	p, err := e.profiles.Get(e.profileName)
+	if err != nil {
+		return nil, err
+	}
+	return client.New(p), nil
+}
+
+func (e *env) setOutput(format string) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("output format must be table or json")
+	}
+	e.output = format
+	return nil
+}
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = map[string]command{
+	"login":  {"login -server URL -user ID [-token T] [-name profile]", runLogin},
+	"add":    {"add [-d description] [-p low|medium|high] title", runAdd},
+	"ls":     {"ls [-status completed|incomplete] [-search text]", runList},
+	"show":   {"show task_id", runShow},
+	"done":   {"done task_id...", runDone},
+	"undo":   {"undo task_id...", runUndo},
+	"edit":   {"edit [-t title] [-d description] [-p priority] task_id", runEdit},
+	"rm":     {"rm task_id...", runRemove},
+	"export": {"export [-format txt|csv|ics] [-f file]", runExport},
+	"import": {"import [-format txt|csv|ics] [-dry-run] [-map column:field] file|-", runImport},
+}
+
+// commandOrder is how commands are listed in the usage text
+var commandOrder = []string{"login", "add", "ls", "show", "done", "undo", "edit", "rm", "export", "import"}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: todoctl [-profile name] [-o table|json] <command> [args]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	defaultPath, err := client.DefaultProfilePath()
+	if err != nil {
+		defaultPath = "profiles.json"
+	}
+
+	e := &env{}
+	fs := flag.NewFlagSet("todoctl", flag.ContinueOnError)
+	fs.Usage = usage
+	fs.StringVar(&e.profilesPath, "profiles", defaultPath, "path of the profile file")
+	fs.StringVar(&e.profileName, "profile", os.Getenv("TODO_PROFILE"), "profile to use (default: current profile)")
+	e.output = "table"
+	fs.Func("o", "output format: table or json", e.setOutput)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		usage()
+		return 2
+	}
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "todoctl: unknown command %q\n", fs.Arg(0))
+		usage()
+		return 2
+	}
+
+	e.profiles, err = client.LoadProfiles(e.profilesPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "todoctl:", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := cmd.run(ctx, e, fs.Args()[1:]); err != nil {
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(os.Stderr, "todoctl: %s\nusage: todoctl %s\n", usageErr.msg, cmd.usage)
+			return 2
+		}
+		fmt.Fprintln(os.Stderr, "todoctl:", err)
+		return 1
+	}
+	return 0
+}
+
+// usageError reports a command invoked with the wrong arguments
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
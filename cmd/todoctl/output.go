@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printTasks renders tasks as an aligned table or a JSON array
+func printTasks(format string, tasks []types.TaskMetaData) error {
+	if format == "json" {
+		if tasks == nil {
+			tasks = []types.TaskMetaData{}
+		}
+		return printJSON(tasks)
+	}
+	if len(tasks) == 0 {
+		fmt.Println("no tasks")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDONE\tPRIORITY\tTITLE\tUPDATED")
+	for _, t := range tasks {
+		done := " "
+		if t.Completed {
+			done = "x"
+		}
+		fmt.Fprintf(tw, "%d\t[%s]\t%s\t%s\t%s\n", t.Id, done, t.Priority, t.Title, t.UpdatedAt.Local().Format("2006-01-02 15:04"))
+	}
+	return tw.Flush()
+}
+
+// printTask renders every field of one task
+func printTask(format string, t *types.TaskMetaData) error {
+	if format == "json" {
+		return printJSON(t)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "id:\t%d\n", t.Id)
+	fmt.Fprintf(tw, "title:\t%s\n", t.Title)
+	fmt.Fprintf(tw, "description:\t%s\n", t.Description)
+	fmt.Fprintf(tw, "priority:\t%s\n", t.Priority)
+	fmt.Fprintf(tw, "completed:\t%t\n", t.Completed)
+	fmt.Fprintf(tw, "created:\t%s\n", t.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(tw, "updated:\t%s\n", t.UpdatedAt.Local().Format("2006-01-02 15:04:05"))
+	if t.DueAt != nil {
+		fmt.Fprintf(tw, "due:\t%s\n", t.DueAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	return tw.Flush()
+}
+
+// printImportReport summarises an import and lists the rows that failed
+func printImportReport(format string, report *types.ImportReport) error {
+	if format == "json" {
+		return printJSON(report)
+	}
+	if report.DryRun {
+		fmt.Printf("dry run: %d of %d tasks valid\n", report.Valid, report.Rows)
+	} else {
+		fmt.Printf("imported %d of %d tasks\n", report.Imported, report.Rows)
+	}
+	if len(report.IgnoredColumns) > 0 {
+		fmt.Printf("ignored columns: %s\n", strings.Join(report.IgnoredColumns, ", "))
+	}
+	for _, e := range report.Errors {
+		fmt.Printf("line %d: %s\n", e.Row, strings.Join(e.Errors, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/storage/sqlite"
+)
+
+// Backup files are named after the moment they were taken, e.g.
+// todo-20261019T120000.000Z.db or, compressed, todo-20261019T120000.000Z.db.gz
+const (
+	filePrefix = "todo-"
+	fileSuffix = ".db"
+	gzipSuffix = ".gz"
+	timeLayout = "20060102T150405.000Z"
+)
+
+// journalSuffixes name the files SQLite keeps next to a database
+var journalSuffixes = []string{"-wal", "-shm", "-journal"}
+
+// Source is the database being backed up
+type Source interface {
+	BackupTo(ctx context.Context, path string) error
+}
+
+// Info describes one backup file
+type Info struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"-"`
+	Size       int64     `json:"size"`
+	CreatedAt  time.Time `json:"created_at"`
+	Compressed bool      `json:"compressed"`
+}
+
+// Manager takes backups into the configured directory and keeps only the
+// newest ones. It is safe for concurrent use; backups never overlap.
+type Manager struct {
+	source Source
+	cfg    config.Backup
+
+	mu sync.Mutex
+}
+
+func New(source Source, cfg config.Backup) *Manager {
+	return &Manager{source: source, cfg: cfg}
+}
+
+// Create takes a backup now and applies the retention policy
+func (m *Manager) Create(ctx context.Context) (Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.cfg.Dir, 0o755); err != nil {
+		return Info{}, err
+	}
+	createdAt := time.Now().UTC().Truncate(time.Millisecond)
+	name := filePrefix + createdAt.Format(timeLayout) + fileSuffix
+	if m.cfg.Compress {
+		name += gzipSuffix
+	}
+	final := filepath.Join(m.cfg.Dir, name)
+
+	// Work on hidden files so a half written backup is never listed
+	raw := filepath.Join(m.cfg.Dir, "."+name+".tmp")
+	defer os.Remove(raw)
+	if err := m.source.BackupTo(ctx, raw); err != nil {
+		return Info{}, fmt.Errorf("backup: %w", err)
+	}
+	written := raw
+	if m.cfg.Compress {
+		written = raw + gzipSuffix
+		defer os.Remove(written)
+		if err := compressFile(raw, written); err != nil {
+			return Info{}, fmt.Errorf("compress backup: %w", err)
+		}
+	}
+	if err := os.Rename(written, final); err != nil {
+		return Info{}, err
+	}
+
+	stat, err := os.Stat(final)
+	if err != nil {
+		return Info{}, err
+	}
+	info := Info{Name: name, Path: final, Size: stat.Size(), CreatedAt: createdAt, Compressed: m.cfg.Compress}
+	if err := m.prune(); err != nil {
+		// The backup itself succeeded
+		slog.Warn("pruning old backups failed", slog.Any("error", err))
+	}
+	return info, nil
+}
+
+// List returns the backups in the directory, newest first
+func (m *Manager) List() ([]Info, error) {
+	entries, err := os.ReadDir(m.cfg.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var backups []Info
+	for _, e := range entries {
+		createdAt, compressed, ok := parseName(e.Name())
+		if !ok || e.IsDir() {
+			continue
+		}
+		stat, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, Info{
+			Name:       e.Name(),
+			Path:       filepath.Join(m.cfg.Dir, e.Name()),
+			Size:       stat.Size(),
+			CreatedAt:  createdAt,
+			Compressed: compressed,
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// At returns the newest backup taken at or before t
+func (m *Manager) At(t time.Time) (Info, error) {
+	backups, err := m.List()
+	if err != nil {
+		return Info{}, err
+	}
+	for _, b := range backups {
+		if !b.CreatedAt.After(t) {
+			return b, nil
+		}
+	}
+	return Info{}, fmt.Errorf("no backup in %s was taken at or before %s", m.cfg.Dir, t.Format(time.RFC3339))
+}
+
+// Run takes a backup every configured interval until ctx is done. It returns
+// immediately when scheduled backups are disabled.
+func (m *Manager) Run(ctx context.Context) {
+	if m.cfg.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := m.Create(ctx)
+			if err != nil {
+				slog.Error("scheduled backup failed", slog.Any("error", err))
+				continue
+			}
+			slog.Info("scheduled backup taken", slog.String("file", info.Path), slog.Int64("size", info.Size))
+		}
+	}
+}
+
+// prune removes the oldest backups beyond the retention count; 0 keeps all
+func (m *Manager) prune() error {
+	if m.cfg.Retain <= 0 {
+		return nil
+	}
+	backups, err := m.List()
+	if err != nil {
+		return err
+	}
+	for i := m.cfg.Retain; i < len(backups); i++ {
+		if err := os.Remove(backups[i].Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore replaces the database at dbPath with the backup file at
+// backupPath, which may be compressed. The backup is unpacked next to the
+// database and verified before anything is touched; the current database is
+// kept beside it with a .pre-restore suffix. The server must not be running.
+func Restore(ctx context.Context, backupPath string, dbPath string) (string, error) {
+	dir := filepath.Dir(dbPath)
+	staged, err := os.CreateTemp(dir, ".restore-*.db")
+	if err != nil {
+		return "", err
+	}
+	stagedPath := staged.Name()
+	staged.Close()
+	defer os.Remove(stagedPath)
+
+	if strings.HasSuffix(backupPath, gzipSuffix) {
+		err = decompressFile(backupPath, stagedPath)
+	} else {
+		err = copyFile(backupPath, stagedPath)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read backup: %w", err)
+	}
+	_, err = sqlite.VerifyBackup(ctx, stagedPath)
+	// Opening the copy may have left journal files of its own beside it
+	for _, sidecar := range journalSuffixes {
+		os.Remove(stagedPath + sidecar)
+	}
+	if err != nil {
+		return "", fmt.Errorf("backup %s failed verification: %w", backupPath, err)
+	}
+
+	// The restored database keeps the permissions of the one it replaces
+	mode := os.FileMode(0o644)
+	if stat, err := os.Stat(dbPath); err == nil {
+		mode = stat.Mode().Perm()
+	}
+	if err := os.Chmod(stagedPath, mode); err != nil {
+		return "", err
+	}
+
+	// Keep the current database, with any journal files, so a restore can
+	// itself be undone
+	kept := ""
+	if _, err := os.Stat(dbPath); err == nil {
+		kept = dbPath + ".pre-restore-" + time.Now().UTC().Format(timeLayout)
+		for _, sidecar := range append([]string{""}, journalSuffixes...) {
+			err := os.Rename(dbPath+sidecar, kept+sidecar)
+			if err != nil && !os.IsNotExist(err) {
+				return "", err
+			}
+		}
+	}
+	if err := os.Rename(stagedPath, dbPath); err != nil {
+		return "", err
+	}
+	return kept, nil
+}
+
+func parseName(name string) (time.Time, bool, bool) {
+	compressed := strings.HasSuffix(name, gzipSuffix)
+	stamp := strings.TrimSuffix(name, gzipSuffix)
+	if !strings.HasPrefix(stamp, filePrefix) || !strings.HasSuffix(stamp, fileSuffix) {
+		return time.Time{}, false, false
+	}
+	stamp = strings.TrimSuffix(strings.TrimPrefix(stamp, filePrefix), fileSuffix)
+	t, err := time.Parse(timeLayout, stamp)
+	if err != nil {
+		return time.Time{}, false, false
+	}
+	return t, compressed, true
+}
+
+func compressFile(src string, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return out.Sync()
+}
+
+func decompressFile(src string, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	return writeFile(dest, zr)
+}
+
+func copyFile(src string, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(dest, in)
+}
+
+func writeFile(dest string, r io.Reader) error {
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, r); err != nil {
+		return err
+	}
+	return out.Sync()
+}
@@ -0,0 +1,150 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/storage/sqlite"
+)
+
+// newTestStore opens a migrated database with one user at dir/todo.db
+func newTestStore(t *testing.T, dir string) *sqlite.Sqlite {
+	t.Helper()
+	cfg, err := config.Load([]string{"-storage_path", filepath.Join(dir, "todo.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := sqlite.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if _, err := s.CreateUser(context.Background(), "Ann", "ann@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// dirNames lists the file names in dir
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestCreateAndPrune(t *testing.T) {
+	s := newTestStore(t, t.TempDir())
+	dir := filepath.Join(t.TempDir(), "backups")
+	m := New(s, config.Backup{Dir: dir, Retain: 2, Compress: true})
+	ctx := context.Background()
+
+	var taken []Info
+	for i := 0; i < 3; i++ {
+		info, err := m.Create(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.Compressed || !strings.HasSuffix(info.Name, ".db.gz") || info.Size == 0 {
+			t.Errorf("backup %+v", info)
+		}
+		if _, err := sqlite.VerifyBackup(ctx, info.Path); err == nil {
+			t.Error("a compressed backup opened as a database")
+		}
+		taken = append(taken, info)
+		// Names are stamped to the millisecond
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// Only the two newest are kept, and no temporary files are left
+	backups, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 || backups[0].Name != taken[2].Name || backups[1].Name != taken[1].Name {
+		t.Fatalf("kept %+v", backups)
+	}
+	if names := dirNames(t, dir); len(names) != 2 {
+		t.Errorf("backup directory holds %v", names)
+	}
+
+	if at, err := m.At(taken[1].CreatedAt.Add(time.Millisecond)); err != nil || at.Name != taken[1].Name {
+		t.Errorf("At: %+v %v", at, err)
+	}
+	if _, err := m.At(taken[0].CreatedAt); err == nil {
+		t.Error("a pruned backup was found")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	s := newTestStore(t, t.TempDir())
+	m := New(s, config.Backup{Dir: t.TempDir(), Compress: true})
+	ctx := context.Background()
+	info, err := m.Create(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "todo.db")
+	if err := os.WriteFile(dbPath, []byte("the old database"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	kept, err := Restore(ctx, info.Path, dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old, err := os.ReadFile(kept); err != nil || string(old) != "the old database" {
+		t.Errorf("kept %s: %q %v", kept, old, err)
+	}
+	stat, err := os.Stat(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Mode().Perm() != 0o640 {
+		t.Errorf("restored with mode %v, want the replaced database's", stat.Mode().Perm())
+	}
+	// Nothing is left over from staging or verifying
+	for _, name := range dirNames(t, dir) {
+		if name != "todo.db" && name != filepath.Base(kept) {
+			t.Errorf("restore left %s behind", name)
+		}
+	}
+	if _, err := sqlite.VerifyBackup(ctx, dbPath); err != nil {
+		t.Errorf("restored database: %v", err)
+	}
+}
+
+func TestRestoreRefusesBadBackup(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	bad := filepath.Join(t.TempDir(), "todo-20260301T090000.000Z.db")
+	if err := os.WriteFile(bad, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(dir, "todo.db")
+	if err := os.WriteFile(dbPath, []byte("the live database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Restore(ctx, bad, dbPath); err == nil || !strings.Contains(err.Error(), "failed verification") {
+		t.Fatalf("restore of a bad backup: %v", err)
+	}
+	if live, err := os.ReadFile(dbPath); err != nil || string(live) != "the live database" {
+		t.Errorf("live database now %q %v", live, err)
+	}
+	if names := dirNames(t, dir); len(names) != 1 {
+		t.Errorf("restore left %v behind", names)
+	}
+}
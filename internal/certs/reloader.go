@@ -0,0 +1,106 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves a certificate loaded from disk and reloads it when the
+// files change, so renewed certificates are picked up without a restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// NewReloader loads the key pair once and fails if it is unusable
+func NewReloader(certFile string, keyFile string) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the key pair from disk. On failure the previous certificate
+// stays in use.
+func (r *Reloader) Reload() error {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading tls key pair: %w", err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.certMod = certMod
+	r.keyMod = keyMod
+	r.mu.Unlock()
+	return nil
+}
+
+// GetCertificate is meant for tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Watch polls the files every interval and reloads them when either changed,
+// until ctx is cancelled
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				slog.Error("tls certificate reload failed", slog.Any("error", err))
+				continue
+			}
+			slog.Info("tls certificate reloaded", slog.String("cert_file", r.certFile))
+		}
+	}
+}
+
+func (r *Reloader) changed() bool {
+	certMod, keyMod, err := r.modTimes()
+	if err != nil {
+		// Files may be mid-rotation; try again on the next tick
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !certMod.Equal(r.certMod) || !keyMod.Equal(r.keyMod)
+}
+
+func (r *Reloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
@@ -0,0 +1,215 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/types"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// Client talks to the /api/v2 endpoints on behalf of one user
+type Client struct {
+	baseURL string
+	userId  int64
+	token   string
+	http    *http.Client
+}
+
+// TaskUpdate is a partial update; nil fields are left unchanged
+type TaskUpdate struct {
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Priority    *string `json:"priority,omitempty"`
+	Completed   *bool   `json:"completed,omitempty"`
+}
+
+// APIError is an error response from the server
+type APIError struct {
+	Status  int
+	Message string
+	// Body is the raw response, for errors carrying more than a message
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.Status)
+}
+
+func New(p Profile) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(p.Server, "/"),
+		userId:  p.UserId,
+		token:   p.Token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *Client) tasksPath() string {
+	return fmt.Sprintf("/api/v2/users/%d/tasks", c.userId)
+}
+
+func (c *Client) taskPath(taskId int64) string {
+	return fmt.Sprintf("%s/%d", c.tasksPath(), taskId)
+}
+
+// AddTask creates a task and returns it as stored
+func (c *Client) AddTask(ctx context.Context, task types.TaskMetaData) (*types.TaskMetaData, error) {
+	var created types.TaskMetaData
+	err := c.do(ctx, http.MethodPost, c.tasksPath(), task, &created)
+	return &created, err
+}
+
+// ListTasks returns the tasks matching status ("", completed, incomplete)
+// and search, mirroring the server side filters
+func (c *Client) ListTasks(ctx context.Context, status string, search string) ([]types.TaskMetaData, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	if search != "" {
+		q.Set("search", search)
+	}
+	path := c.tasksPath()
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var tasks []types.TaskMetaData
+	err := c.do(ctx, http.MethodGet, path, nil, &tasks)
+	return tasks, err
+}
+
+func (c *Client) GetTask(ctx context.Context, taskId int64) (*types.TaskMetaData, error) {
+	var task types.TaskMetaData
+	err := c.do(ctx, http.MethodGet, c.taskPath(taskId), nil, &task)
+	return &task, err
+}
+
+func (c *Client) UpdateTask(ctx context.Context, taskId int64, update TaskUpdate) (*types.TaskMetaData, error) {
+	var task types.TaskMetaData
+	err := c.do(ctx, http.MethodPatch, c.taskPath(taskId), update, &task)
+	return &task, err
+}
+
+func (c *Client) DeleteTask(ctx context.Context, taskId int64) error {
+	return c.do(ctx, http.MethodDelete, c.taskPath(taskId), nil, nil)
+}
+
+// transferTypes are the file formats tasks can be exported and imported in,
+// with their media types
+var transferTypes = map[string]string{
+	"csv": "text/csv",
+	"ics": "text/calendar",
+	"txt": "text/plain",
+}
+
+// ExportTasks writes every task in format (csv, ics or txt) to w
+func (c *Client) ExportTasks(ctx context.Context, format string, w io.Writer) error {
+	mediaType, ok := transferTypes[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	return c.send(ctx, http.MethodGet, c.tasksPath()+"/export."+format, "", nil, mediaType, func(r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// ImportTasks uploads a file in format (csv, ics or txt). mapping is only
+// used for CSV. When no task could be imported, or storage stopped the
+// import part way, the report is returned along with the error.
+func (c *Client) ImportTasks(ctx context.Context, format string, body io.Reader, dryRun bool, mapping []string) (*types.ImportReport, error) {
+	mediaType, ok := transferTypes[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	q := url.Values{}
+	if dryRun {
+		q.Set("dry_run", "true")
+	}
+	for _, m := range mapping {
+		q.Add("map", m)
+	}
+	path := c.tasksPath() + "/import"
+	if format != "csv" {
+		path += "." + format
+	}
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	var report types.ImportReport
+	err := c.send(ctx, http.MethodPost, path, mediaType, body, "application/json", func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&report)
+	})
+	// A 500 is only a report when it lists the row errors, as every report does
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.Status == http.StatusUnprocessableEntity || apiErr.Status == http.StatusInternalServerError) &&
+		json.Unmarshal(apiErr.Body, &report) == nil && report.Errors != nil {
+		return &report, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// do sends a JSON request and decodes the JSON response into out, if given
+func (c *Client) do(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	return c.send(ctx, method, path, "application/json", body, "application/json", func(r io.Reader) error {
+		if out == nil {
+			return nil
+		}
+		return json.NewDecoder(r).Decode(out)
+	})
+}
+
+// send performs a request and hands a successful response body to read
+func (c *Client) send(ctx context.Context, method string, path string, contentType string, body io.Reader, accept string, read func(io.Reader) error) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", accept)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		raw, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+		var apiErr response.Response
+		if err := json.Unmarshal(raw, &apiErr); err != nil || apiErr.Error == "" {
+			return &APIError{Status: res.StatusCode, Message: http.StatusText(res.StatusCode), Body: raw}
+		}
+		return &APIError{Status: res.StatusCode, Message: apiErr.Error, Body: raw}
+	}
+	if res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return read(res.Body)
+}
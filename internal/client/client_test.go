@@ -0,0 +1,126 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/srmty09/Todo-App/internal/types"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// testServer answers every request with handler, for a client of user 7
+// holding a token
+func testServer(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return New(Profile{Server: server.URL + "/", UserId: 7, Token: "secret"})
+}
+
+func TestRequests(t *testing.T) {
+	var got *http.Request
+	var body string
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		if r.Method == http.MethodGet {
+			response.WriteJson(w, http.StatusOK, []types.TaskMetaData{})
+			return
+		}
+		response.WriteJson(w, http.StatusOK, types.TaskMetaData{Id: 3, Title: "Buy oat milk"})
+	})
+
+	title := "Buy oat milk"
+	task, err := c.UpdateTask(context.Background(), 3, TaskUpdate{Title: &title})
+	if err != nil || task.Id != 3 || task.Title != title {
+		t.Fatalf("UpdateTask: %+v %v", task, err)
+	}
+	if got.Method != http.MethodPatch || got.URL.Path != "/api/v2/users/7/tasks/3" || got.Header.Get("Authorization") != "Bearer secret" ||
+		got.Header.Get("Content-Type") != "application/json" || body != `{"title":"Buy oat milk"}` {
+		t.Errorf("sent %s %s %v %s", got.Method, got.URL, got.Header, body)
+	}
+
+	if _, err := c.ListTasks(context.Background(), "incomplete", "milk"); err != nil {
+		t.Fatal(err)
+	}
+	if got.URL.RawQuery != "search=milk&status=incomplete" {
+		t.Errorf("list query %q", got.URL.RawQuery)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		status  int
+		body    string
+		message string
+	}{
+		{"server message", http.StatusNotFound, `{"status":"Error","error":"task with id 3 does not exist"}`, "task with id 3 does not exist (HTTP 404)"},
+		{"no message", http.StatusBadGateway, "<html>bad gateway</html>", "Bad Gateway (HTTP 502)"},
+	} {
+		c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+			io.WriteString(w, tc.body)
+		})
+		_, err := c.GetTask(context.Background(), 3)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Status != tc.status || err.Error() != tc.message || string(apiErr.Body) != tc.body {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+}
+
+func TestImportTasks(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		status     int
+		answer     interface{}
+		wantReport bool
+		wantErr    string
+	}{
+		{"imported", http.StatusCreated, types.ImportReport{Rows: 2, Valid: 2, Imported: 2, Errors: []types.ImportError{}}, true, ""},
+		{"nothing usable", http.StatusUnprocessableEntity, types.ImportReport{Rows: 1, Errors: []types.ImportError{{Row: 1, Errors: []string{"Title is required"}}}}, true, "HTTP 422"},
+		{"stopped part way", http.StatusInternalServerError, types.ImportReport{Rows: 2, Valid: 1, Imported: 1, Errors: []types.ImportError{}, Error: "database or disk is full"}, true, "database or disk is full (HTTP 500)"},
+		{"failed before reading", http.StatusInternalServerError, response.GeneralError(errors.New("database is locked")), false, "database is locked (HTTP 500)"},
+	} {
+		var got *http.Request
+		c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+			got = r
+			response.WriteJson(w, tc.status, tc.answer)
+		})
+		report, err := c.ImportTasks(context.Background(), "csv", strings.NewReader("title\nBuy milk\n"), true, []string{"Headline:title"})
+		if (report != nil) != tc.wantReport || (tc.wantErr == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("%s: %+v %v", tc.name, report, err)
+		}
+		if got.URL.Path != "/api/v2/users/7/tasks/import" || got.URL.RawQuery != "dry_run=true&map=Headline%3Atitle" || got.Header.Get("Content-Type") != "text/csv" {
+			t.Errorf("%s: sent %s %v", tc.name, got.URL, got.Header)
+		}
+	}
+
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request for an unknown format: %s", r.URL)
+	})
+	if _, err := c.ImportTasks(context.Background(), "xlsx", strings.NewReader(""), false, nil); err == nil {
+		t.Error("importing xlsx: no error")
+	}
+}
+
+func TestExportTasks(t *testing.T) {
+	c := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/users/7/tasks/export.ics" || r.Header.Get("Accept") != "text/calendar" {
+			t.Errorf("sent %s %v", r.URL, r.Header)
+		}
+		io.WriteString(w, "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n")
+	})
+	var buf bytes.Buffer
+	if err := c.ExportTasks(context.Background(), "ics", &buf); err != nil || buf.String() != "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n" {
+		t.Errorf("export: %q %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Profile is where and as whom the CLI talks to the API
+type Profile struct {
+	Server string `json:"server"`
+	UserId int64  `json:"user_id"`
+	// Sent as a bearer token when set
+	Token string `json:"token,omitempty"`
+}
+
+// Profiles is the content of the profile file
+type Profiles struct {
+	Current  string             `json:"current"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// DefaultProfilePath is $XDG_CONFIG_HOME/todo/profiles.json or its
+// platform equivalent
+func DefaultProfilePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "todo", "profiles.json"), nil
+}
+
+// LoadProfiles reads the profile file; a missing file yields no profiles
+func LoadProfiles(path string) (*Profiles, error) {
+	p := &Profiles{Profiles: map[string]Profile{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if p.Profiles == nil {
+		p.Profiles = map[string]Profile{}
+	}
+	return p, nil
+}
+
+// Save writes the profiles readable by the owner only, as they may hold a
+// token
+func (p *Profiles) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o600)
+}
+
+// Get returns the named profile, or the current one when name is empty
+func (p *Profiles) Get(name string) (Profile, error) {
+	if name == "" {
+		name = p.Current
+	}
+	if name == "" {
+		return Profile{}, fmt.Errorf("no profile configured, run \"todoctl login\" first")
+	}
+	profile, ok := p.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile %q does not exist", name)
+	}
+	return profile, nil
+}
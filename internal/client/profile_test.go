@@ -0,0 +1,51 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo", "profiles.json")
+
+	// No file yet is no profiles, not an error
+	p, err := LoadProfiles(path)
+	if err != nil || len(p.Profiles) != 0 {
+		t.Fatalf("missing file: %+v %v", p, err)
+	}
+	if _, err := p.Get(""); err == nil {
+		t.Error("current profile of an empty file: no error")
+	}
+
+	p.Profiles["work"] = Profile{Server: "https://todo.example", UserId: 3, Token: "secret"}
+	p.Current = "work"
+	if err := p.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("profile file mode %v, want 0600", info.Mode().Perm())
+	}
+
+	p, err = LoadProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := p.Get(""); err != nil || got.UserId != 3 || got.Token != "secret" {
+		t.Errorf("current profile: %+v %v", got, err)
+	}
+	if _, err := p.Get("home"); err == nil {
+		t.Error("unknown profile: no error")
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadProfiles(path); err == nil {
+		t.Error("corrupt file: no error")
+	}
+}
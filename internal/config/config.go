@@ -1,43 +1,242 @@
 package config
 
 import (
-	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
+// TLS enables HTTPS when both files are set. The files are watched and the
+// certificate is swapped in without a restart when they change.
+type TLS struct{
+	CertFile string `yaml:"cert_file" env:"CERT_FILE"`
+	KeyFile string `yaml:"key_file" env:"KEY_FILE"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"RELOAD_INTERVAL" env-default:"1m"`
+}
+
 type HTTPServer struct{
-	Addr string `yaml:"addr"`
+	Addr string `yaml:"addr" env:"ADDR" env-default:"localhost:8080"`
+	ReadTimeout time.Duration `yaml:"read_timeout" env:"READ_TIMEOUT" env-default:"15s"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"READ_HEADER_TIMEOUT" env-default:"5s"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"WRITE_TIMEOUT" env-default:"30s"`
+	IdleTimeout time.Duration `yaml:"idle_timeout" env:"IDLE_TIMEOUT" env-default:"120s"`
+	MaxHeaderBytes int `yaml:"max_header_bytes" env:"MAX_HEADER_BYTES" env-default:"65536"`
+	// Largest request body accepted, in bytes
+	MaxBodyBytes int64 `yaml:"max_body_bytes" env:"MAX_BODY_BYTES" env-default:"1048576"`
+	// How long in-flight requests get to finish on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" env-default:"10s"`
+	// How long /readyz fails before shutdown starts, so load balancers stop
+	// sending new requests while the listener is still open
+	DrainDelay time.Duration `yaml:"drain_delay" env:"DRAIN_DELAY" env-default:"5s"`
+	TLS TLS `yaml:"tls" env-prefix:"TLS_"`
+}
+
+// Tracing configures OpenTelemetry. Exporter is one of "none", "otlp",
+// "stdout" or "file".
+type Tracing struct{
+	Exporter string `yaml:"exporter" env:"EXPORTER" env-default:"none"`
+	ServiceName string `yaml:"service_name" env:"SERVICE_NAME" env-default:"todo-app"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"SAMPLE_RATIO" env-default:"1"`
+	// OTLP over HTTP, e.g. "localhost:4318"
+	Endpoint string `yaml:"endpoint" env:"ENDPOINT"`
+	Insecure bool `yaml:"insecure" env:"INSECURE"`
+	// Usually carries exporter credentials, hence secret
+	Headers map[string]string `yaml:"headers" env:"HEADERS" secret:"true"`
+	// Destination of the "file" exporter
+	File string `yaml:"file" env:"FILE"`
+}
+
+// RateLimitPolicy is a token bucket shared by a group of routes. Routes are
+// mux patterns exactly as registered, e.g. "POST /api/v2/users". Key is "ip"
+// or "user"; "user" buckets by the {id} path value together with the client
+// IP, as the id is not authenticated, and by the IP alone on routes without
+// one.
+type RateLimitPolicy struct{
+	Name string `yaml:"name" env:"NAME"`
+	Routes []string `yaml:"routes"`
+	Key string `yaml:"key" env:"KEY"`
+	Requests int `yaml:"requests" env:"REQUESTS"`
+	Period time.Duration `yaml:"period" env:"PERIOD"`
+	Burst int `yaml:"burst" env:"BURST"`
+}
+
+// RateLimit configures request rate limiting. Default applies to every route
+// not covered by a policy; leave its requests at 0 to not limit those.
+// Policies can only be set in the config file.
+type RateLimit struct{
+	Enabled bool `yaml:"enabled" env:"ENABLED"`
+	TrustForwardedFor bool `yaml:"trust_forwarded_for" env:"TRUST_FORWARDED_FOR"`
+	Default RateLimitPolicy `yaml:"default" env-prefix:"DEFAULT_"`
+	Policies []RateLimitPolicy `yaml:"policies"`
+}
+
+// CORS controls which browser origins may call the API. An origin entry may
+// be "*" or contain a leading wildcard label, e.g. "https://*.example.com".
+type CORS struct{
+	AllowedOrigins []string `yaml:"allowed_origins" env:"ALLOWED_ORIGINS"`
+	AllowedMethods []string `yaml:"allowed_methods" env:"ALLOWED_METHODS" env-default:"GET,POST,PUT,PATCH,DELETE"`
+	AllowedHeaders []string `yaml:"allowed_headers" env:"ALLOWED_HEADERS" env-default:"Content-Type,Authorization,X-Request-ID,Last-Event-ID"`
+	ExposedHeaders []string `yaml:"exposed_headers" env:"EXPOSED_HEADERS" env-default:"Location,X-Request-ID,Retry-After,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy,Deprecation,Sunset,Link"`
+	AllowCredentials bool `yaml:"allow_credentials" env:"ALLOW_CREDENTIALS"`
+	// How long browsers may cache a preflight response
+	MaxAge time.Duration `yaml:"max_age" env:"MAX_AGE" env-default:"10m"`
+}
+
+// SQLite tunes the database connections. The pragmas are set on every
+// connection as it is opened. Writes share a single connection so they queue
+// in the pool rather than fail with SQLITE_BUSY; reads use their own pool of
+// up to MaxReadConns connections, which in WAL mode never wait on a writer.
+type SQLite struct{
+	// One of DELETE, TRUNCATE, PERSIST, MEMORY, WAL, OFF
+	JournalMode string `yaml:"journal_mode" env:"JOURNAL_MODE" env-default:"WAL"`
+	// One of OFF, NORMAL, FULL, EXTRA
+	Synchronous string `yaml:"synchronous" env:"SYNCHRONOUS" env-default:"NORMAL"`
+	// How long a connection waits on a lock held by another process
+	BusyTimeout time.Duration `yaml:"busy_timeout" env:"BUSY_TIMEOUT" env-default:"5s"`
+	ForeignKeys bool `yaml:"foreign_keys" env:"FOREIGN_KEYS" env-default:"true"`
+	MaxReadConns int `yaml:"max_read_conns" env:"MAX_READ_CONNS" env-default:"4"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"CONN_MAX_IDLE_TIME" env-default:"5m"`
+}
+
+// Events sizes the task event streams. ReplayBuffer is how many recent
+// events are kept for clients resuming with Last-Event-ID; a client falling
+// more than SubscriberBuffer events behind is disconnected and resumes.
+type Events struct{
+	ReplayBuffer int `yaml:"replay_buffer" env:"REPLAY_BUFFER" env-default:"1024"`
+	SubscriberBuffer int `yaml:"subscriber_buffer" env:"SUBSCRIBER_BUFFER" env-default:"64"`
+	Heartbeat time.Duration `yaml:"heartbeat" env:"HEARTBEAT" env-default:"15s"`
+}
+
+// WebSocket tunes the task sync socket. A ping goes out every PingInterval
+// and a connection that stays silent for PongWait is closed. SendBuffer
+// bounds the replies and events queued per connection; when it is full the
+// connection stops reading requests until the client catches up.
+type WebSocket struct{
+	PingInterval time.Duration `yaml:"ping_interval" env:"PING_INTERVAL" env-default:"30s"`
+	PongWait time.Duration `yaml:"pong_wait" env:"PONG_WAIT" env-default:"60s"`
+	WriteWait time.Duration `yaml:"write_wait" env:"WRITE_WAIT" env-default:"10s"`
+	SendBuffer int `yaml:"send_buffer" env:"SEND_BUFFER" env-default:"64"`
+	MaxMessageBytes int64 `yaml:"max_message_bytes" env:"MAX_MESSAGE_BYTES" env-default:"65536"`
+}
+
+// Backup configures online database backups. Scheduled backups are taken
+// every Interval when it is set; Retain is how many of the newest backups to
+// keep, 0 keeping all of them.
+type Backup struct{
+	Dir string `yaml:"dir" env:"DIR" env-default:"storage/backups"`
+	Interval time.Duration `yaml:"interval" env:"INTERVAL"`
+	Retain int `yaml:"retain" env:"RETAIN" env-default:"7"`
+	Compress bool `yaml:"compress" env:"COMPRESS"`
+}
+
+// Admin protects the /api/admin endpoints. They answer 403 until a token is
+// set and then require it as a bearer token.
+type Admin struct{
+	Token string `yaml:"token" env:"TOKEN" secret:"true"`
 }
 
+// Webhooks tunes delivery of outgoing webhooks. A failed delivery is retried
+// after BackoffBase, doubling per attempt up to BackoffMax, and is given up
+// after MaxAttempts. Receivers on loopback or private addresses are refused
+// unless AllowPrivateNetworks is set.
+type Webhooks struct{
+	Workers int `yaml:"workers" env:"WORKERS" env-default:"4"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"POLL_INTERVAL" env-default:"5s"`
+	Timeout time.Duration `yaml:"timeout" env:"TIMEOUT" env-default:"10s"`
+	MaxAttempts int `yaml:"max_attempts" env:"MAX_ATTEMPTS" env-default:"8"`
+	BackoffBase time.Duration `yaml:"backoff_base" env:"BACKOFF_BASE" env-default:"30s"`
+	BackoffMax time.Duration `yaml:"backoff_max" env:"BACKOFF_MAX" env-default:"1h"`
+	BatchSize int `yaml:"batch_size" env:"BATCH_SIZE" env-default:"50"`
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" env:"ALLOW_PRIVATE_NETWORKS"`
+}
+
+// Config is assembled in layers, each overriding the previous one: the
+// env-default tags, the YAML file, environment variables and finally
+// command-line flags. Environment variable names are the env tags joined
+// with their env-prefix, e.g. HTTP_SERVER_TLS_CERT_FILE.
 type Config struct{
-	Env string `yaml:"env" env:"ENV" env-required:"true"`
-	Storage_path string `yaml:"storage_path" env-required:"true"`
-	HTTPServer `yaml:"http_server"`
+	Env string `yaml:"env" env:"ENV" env-default:"dev"`
+	// One of debug, info, warn, error
+	LogLevel string `yaml:"log_level" env:"LOG_LEVEL" env-default:"info"`
+	Storage_path string `yaml:"storage_path" env:"STORAGE_PATH" env-default:"storage/storage.db"`
+	SQLite SQLite `yaml:"sqlite" env-prefix:"SQLITE_"`
+	HTTPServer `yaml:"http_server" env-prefix:"HTTP_SERVER_"`
+	Tracing Tracing `yaml:"tracing" env-prefix:"TRACING_"`
+	RateLimit RateLimit `yaml:"rate_limit" env-prefix:"RATE_LIMIT_"`
+	CORS CORS `yaml:"cors" env-prefix:"CORS_"`
+	Events Events `yaml:"events" env-prefix:"EVENTS_"`
+	WebSocket WebSocket `yaml:"websocket" env-prefix:"WEBSOCKET_"`
+	Backup Backup `yaml:"backup" env-prefix:"BACKUP_"`
+	Admin Admin `yaml:"admin" env-prefix:"ADMIN_"`
+	Webhooks Webhooks `yaml:"webhooks" env-prefix:"WEBHOOKS_"`
+
+	// Path of the file the config was read from, if any
+	Path string `yaml:"-"`
 }
 
+// Load builds the configuration from args (usually os.Args[1:]). The config
+// file is taken from the -config flag or CONFIG_PATH and is optional. Every
+// field can also be set with a flag named after its YAML path, such as
+// -http_server.addr. The result is validated before being returned.
+func Load(args []string) (*Config, error){
+	cfg, _, err := LoadArgs(args)
+	return cfg, err
+}
 
-func MustLoad() *Config{
-	var configPath string
-
-	configPath = os.Getenv("CONFIG_PATH")
-	if configPath == ""{
-		flags := flag.String("config","","path to config file")
-		flag.Parse()
-		configPath = *flags
-		if configPath == ""{
-			log.Fatal("Config path is not set")
-		}
+// LoadArgs is Load for commands taking their own arguments after the config
+// flags; those remaining arguments are returned alongside the config.
+func LoadArgs(args []string) (*Config, []string, error){
+	fs, overrides := newFlagSet("todo")
+	configPath := fs.String("config", "", "path to config file (or CONFIG_PATH)")
+	if err := fs.Parse(args); err != nil{
+		return nil, nil, err
 	}
-	if _,err := os.Stat(configPath); os.IsNotExist(err){
-		log.Fatalf("config file doesn't exist: %s",configPath)
+	if *configPath == ""{
+		*configPath = os.Getenv("CONFIG_PATH")
 	}
+
 	var cfg Config
-	err := cleanenv.ReadConfig(configPath,&cfg)
+	if *configPath != ""{
+		if _,err := os.Stat(*configPath); err != nil{
+			return nil, nil, fmt.Errorf("config file %s: %w", *configPath, err)
+		}
+		// defaults, then the file, then the environment
+		if err := cleanenv.ReadConfig(*configPath,&cfg); err != nil{
+			return nil, nil, fmt.Errorf("can't read the config file: %w", err)
+		}
+		cfg.Path = *configPath
+	} else{
+		// defaults, then the environment
+		if err := cleanenv.ReadEnv(&cfg); err != nil{
+			return nil, nil, fmt.Errorf("can't read the environment: %w", err)
+		}
+	}
+
+	overrides.apply(&cfg)
+	if err := cfg.Validate(); err != nil{
+		return nil, nil, err
+	}
+	return &cfg, fs.Args(), nil
+}
+
+// Level returns the slog level named by LogLevel
+func (c *Config) Level() slog.Level{
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil{
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// MustLoad is Load on the process arguments, exiting on error
+func MustLoad() *Config{
+	cfg, err := Load(os.Args[1:])
 	if err != nil{
-		log.Fatalf("Can't read the config file: %s",err.Error())
+		log.Fatal(err)
 	}
-	return &cfg
-}
\ No newline at end of file
+	return cfg
+}
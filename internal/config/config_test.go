@@ -0,0 +1,284 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a YAML config file and returns its path
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	file := writeConfig(t, `
+log_level: warn
+storage_path: from-file.db
+http_server:
+  addr: "localhost:9000"
+  read_timeout: 20s
+rate_limit:
+  policies:
+    - name: signup
+      routes: ["POST /api/v2/users"]
+      requests: 10
+      period: 1m
+`)
+	type want struct {
+		logLevel    string
+		storagePath string
+		addr        string
+		readTimeout time.Duration
+		policies    int
+	}
+	for _, tc := range []struct {
+		name string
+		env  map[string]string
+		args []string
+		want want
+	}{
+		{
+			name: "defaults",
+			want: want{"info", "storage/storage.db", "localhost:8080", 15 * time.Second, 0},
+		},
+		{
+			name: "file over defaults",
+			args: []string{"-config", file},
+			want: want{"warn", "from-file.db", "localhost:9000", 20 * time.Second, 1},
+		},
+		{
+			name: "file from CONFIG_PATH",
+			env:  map[string]string{"CONFIG_PATH": file},
+			want: want{"warn", "from-file.db", "localhost:9000", 20 * time.Second, 1},
+		},
+		{
+			name: "environment over file",
+			env:  map[string]string{"LOG_LEVEL": "debug", "HTTP_SERVER_READ_TIMEOUT": "25s"},
+			args: []string{"-config", file},
+			want: want{"debug", "from-file.db", "localhost:9000", 25 * time.Second, 1},
+		},
+		{
+			name: "environment without a file",
+			env:  map[string]string{"HTTP_SERVER_ADDR": "0.0.0.0:80"},
+			want: want{"info", "storage/storage.db", "0.0.0.0:80", 15 * time.Second, 0},
+		},
+		{
+			name: "flags over everything",
+			env:  map[string]string{"LOG_LEVEL": "debug", "HTTP_SERVER_ADDR": "0.0.0.0:80"},
+			args: []string{"-config", file, "-log_level", "error", "-http_server.addr", ":7000", "-storage_path", "flag.db"},
+			want: want{"error", "flag.db", ":7000", 20 * time.Second, 1},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load(tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := want{cfg.LogLevel, cfg.Storage_path, cfg.HTTPServer.Addr, cfg.HTTPServer.ReadTimeout, len(cfg.RateLimit.Policies)}
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadFlags(t *testing.T) {
+	cfg, rest, err := LoadArgs([]string{
+		"-sqlite.foreign_keys=false",
+		"-sqlite.max_read_conns", "2",
+		"-tracing.sample_ratio", "0.5",
+		"-http_server.max_body_bytes", "2048",
+		"-http_server.tls.reload_interval", "30s",
+		"-cors.allowed_origins", "https://a.example,https://b.example",
+		"-tracing.headers", "authorization:Bearer x,team:todo",
+		"user", "list",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SQLite.ForeignKeys || cfg.SQLite.MaxReadConns != 2 || cfg.Tracing.SampleRatio != 0.5 ||
+		cfg.HTTPServer.MaxBodyBytes != 2048 || cfg.HTTPServer.TLS.ReloadInterval != 30*time.Second {
+		t.Errorf("scalar flags not applied: %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.CORS.AllowedOrigins, []string{"https://a.example", "https://b.example"}) {
+		t.Errorf("allowed origins %v", cfg.CORS.AllowedOrigins)
+	}
+	if !reflect.DeepEqual(cfg.Tracing.Headers, map[string]string{"authorization": "Bearer x", "team": "todo"}) {
+		t.Errorf("headers %v", cfg.Tracing.Headers)
+	}
+	if !reflect.DeepEqual(rest, []string{"user", "list"}) {
+		t.Errorf("remaining arguments %v", rest)
+	}
+
+	for _, args := range [][]string{
+		{"-http_server.read_timeout", "soon"},
+		{"-sqlite.max_read_conns", "many"},
+		{"-tracing.headers", "no-colon"},
+		{"-no_such_field", "x"},
+		{"-config", filepath.Join(t.TempDir(), "missing.yaml")},
+	} {
+		if _, err := Load(args); err == nil {
+			t.Errorf("%v: no error", args)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	byPath := make(map[string]field)
+	for _, f := range fields() {
+		byPath[f.Path] = f
+	}
+	for path, env := range map[string]string{
+		"log_level":                   "LOG_LEVEL",
+		"http_server.addr":            "HTTP_SERVER_ADDR",
+		"http_server.tls.cert_file":   "HTTP_SERVER_TLS_CERT_FILE",
+		"rate_limit.default.requests": "RATE_LIMIT_DEFAULT_REQUESTS",
+		"admin.token":                 "ADMIN_TOKEN",
+	} {
+		if f, ok := byPath[path]; !ok || f.Env != env {
+			t.Errorf("%s: %+v, want env %s", path, f, env)
+		}
+	}
+	// Policies are file-only and the config path is not a setting
+	for _, path := range []string{"rate_limit.policies", "path", "http_server.tls"} {
+		if _, ok := byPath[path]; ok {
+			t.Errorf("%s should not be a field", path)
+		}
+	}
+	if !byPath["admin.token"].Secret || !byPath["tracing.headers"].Secret || byPath["log_level"].Secret {
+		t.Error("secret fields not marked")
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg, err := Load([]string{"-admin.token", "s3cret", "-tracing.headers", "authorization:Bearer x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := cfg.Redacted()
+	if out.Admin.Token != "REDACTED" || out.Tracing.Headers["authorization"] != "REDACTED" || out.LogLevel != "info" {
+		t.Errorf("redacted %+v", out)
+	}
+	// The original is untouched
+	if cfg.Admin.Token != "s3cret" || cfg.Tracing.Headers["authorization"] != "Bearer x" {
+		t.Errorf("original changed: %+v", cfg)
+	}
+
+	// Unset secrets stay empty so it is clear they are not set
+	cfg, err = Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := cfg.Redacted(); out.Admin.Token != "" || out.Tracing.Headers != nil {
+		t.Errorf("unset secrets redacted as %q %v", out.Admin.Token, out.Tracing.Headers)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	old, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed := Changes(old, old); len(changed) != 0 {
+		t.Errorf("no changes reported as %v", changed)
+	}
+	next, err := Load([]string{"-log_level", "debug", "-cors.allowed_origins", "https://a.example", "-tracing.headers", "a:b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next.RateLimit.Policies = append(next.RateLimit.Policies, RateLimitPolicy{Name: "signup"})
+	want := []string{"log_level", "tracing.headers", "cors.allowed_origins", "rate_limit.policies"}
+	if changed := Changes(old, next); !reflect.DeepEqual(changed, want) {
+		t.Errorf("changes %v, want %v", changed, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	certFile := writeConfig(t, "not really a certificate")
+	for _, tc := range []struct {
+		args []string
+		// substrings of the error; none for a valid config
+		errs []string
+	}{
+		{nil, nil},
+		{[]string{"-log_level", "loud"}, []string{"log_level"}},
+		{[]string{"-storage_path", ""}, []string{"storage_path must be set"}},
+		{[]string{"-sqlite.journal_mode", "wal", "-sqlite.synchronous", "full"}, nil},
+		{[]string{"-sqlite.journal_mode", "fast"}, []string{"sqlite.journal_mode"}},
+		{[]string{"-sqlite.max_read_conns", "0"}, []string{"sqlite.max_read_conns"}},
+		{[]string{"-http_server.addr", "8080"}, []string{"http_server.addr"}},
+		{[]string{"-http_server.addr", "localhost:99999"}, []string{"invalid port"}},
+		{[]string{"-http_server.read_timeout", "-1s"}, []string{"http_server.read_timeout"}},
+		{[]string{"-http_server.tls.cert_file", certFile}, []string{"set together"}},
+		{[]string{"-http_server.tls.cert_file", certFile, "-http_server.tls.key_file", "missing.key"}, []string{"http_server.tls.key_file"}},
+		{[]string{"-tracing.exporter", "file"}, []string{"tracing.file"}},
+		{[]string{"-tracing.exporter", "zipkin", "-tracing.sample_ratio", "2"}, []string{"tracing.exporter", "tracing.sample_ratio"}},
+		{[]string{"-events.heartbeat", "0s"}, []string{"events.heartbeat"}},
+		{[]string{"-websocket.pong_wait", "10s"}, []string{"websocket.pong_wait"}},
+		{[]string{"-backup.dir", "", "-backup.retain", "-1"}, []string{"backup.dir", "backup.retain"}},
+		{[]string{"-webhooks.backoff_max", "1s"}, []string{"webhooks.backoff_max"}},
+	} {
+		_, err := Load(tc.args)
+		if len(tc.errs) == 0 {
+			if err != nil {
+				t.Errorf("%v: %v", tc.args, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%v: no error", tc.args)
+			continue
+		}
+		for _, want := range tc.errs {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%v: %q does not mention %s", tc.args, err, want)
+			}
+		}
+	}
+}
+
+func TestValidateLeavesFilesystemAlone(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := Load([]string{
+		"-storage_path", filepath.Join(dir, "data", "todo.db"),
+		"-backup.dir", filepath.Join(dir, "backups"),
+		"-backup.interval", "1h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("loading the config created %v", entries)
+	}
+
+	if err := cfg.CheckDirs(); err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []string{"data", "backups"} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil || len(entries) != 0 {
+			t.Errorf("%s: %v %v", sub, entries, err)
+		}
+	}
+
+	// A file where a directory should be is reported
+	cfg.Storage_path = filepath.Join(dir, "data", "todo.db", "nested.db")
+	if err := os.WriteFile(filepath.Join(dir, "data", "todo.db"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.CheckDirs(); err == nil || !strings.Contains(err.Error(), "storage_path") {
+		t.Errorf("CheckDirs: %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"reflect"
+)
+
+// Changes lists the YAML paths whose values differ between old and new.
+// Rate limit policies are compared as a whole and reported as
+// "rate_limit.policies".
+func Changes(old *Config, new *Config) []string {
+	var changed []string
+	oldRoot := reflect.ValueOf(old).Elem()
+	newRoot := reflect.ValueOf(new).Elem()
+	for _, f := range fields() {
+		if !reflect.DeepEqual(oldRoot.FieldByIndex(f.index).Interface(), newRoot.FieldByIndex(f.index).Interface()) {
+			changed = append(changed, f.Path)
+		}
+	}
+	if !reflect.DeepEqual(old.RateLimit.Policies, new.RateLimit.Policies) {
+		changed = append(changed, "rate_limit.policies")
+	}
+	return changed
+}
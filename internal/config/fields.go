@@ -0,0 +1,206 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// field is a leaf of the config tree
+type field struct {
+	// Path is the dotted YAML path, e.g. "http_server.tls.cert_file"
+	Path string
+	// Env is the environment variable overriding the field, if any
+	Env    string
+	Secret bool
+	index  []int
+	typ    reflect.Type
+}
+
+// fields lists every scalar or map leaf of Config. Slices of structs, like
+// rate limit policies, are file-only and not listed.
+func fields() []field {
+	var out []field
+	walk(reflect.TypeOf(Config{}), nil, "", "", &out)
+	return out
+}
+
+func walk(t reflect.Type, index []int, pathPrefix string, envPrefix string, out *[]field) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		idx := append(append([]int{}, index...), i)
+		path := pathPrefix + name
+
+		if f.Type.Kind() == reflect.Struct && f.Type != durationType {
+			walk(f.Type, idx, path+".", envPrefix+f.Tag.Get("env-prefix"), out)
+			continue
+		}
+		if f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct {
+			continue
+		}
+		env := ""
+		if tag := f.Tag.Get("env"); tag != "" {
+			env = envPrefix + tag
+		}
+		*out = append(*out, field{
+			Path:   path,
+			Env:    env,
+			Secret: f.Tag.Get("secret") == "true",
+			index:  idx,
+			typ:    f.Type,
+		})
+	}
+}
+
+// parseValue converts a flag string into a value of type t. Maps use the
+// same "key:value,key:value" form as the environment.
+func parseValue(t reflect.Type, raw string) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	switch {
+	case t == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(int64(d))
+	case t.Kind() == reflect.String:
+		v.SetString(raw)
+	case t.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return v, err
+		}
+		v.SetBool(b)
+	case t.Kind() == reflect.Int || t.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(n)
+	case t.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return v, err
+		}
+		v.SetFloat(f)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		parts := strings.Split(raw, ",")
+		v.Set(reflect.ValueOf(parts))
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String:
+		m := make(map[string]string)
+		for _, pair := range strings.Split(raw, ",") {
+			if pair == "" {
+				continue
+			}
+			k, val, ok := strings.Cut(pair, ":")
+			if !ok {
+				return v, fmt.Errorf("%q is not a key:value pair", pair)
+			}
+			m[k] = val
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return v, fmt.Errorf("unsupported type %s", t)
+	}
+	return v, nil
+}
+
+// fieldFlag records a flag value until it can be applied on top of the
+// file and environment layers
+type fieldFlag struct {
+	field field
+	raw   string
+	value reflect.Value
+	set   bool
+}
+
+func (f *fieldFlag) String() string {
+	return f.raw
+}
+
+func (f *fieldFlag) Set(raw string) error {
+	v, err := parseValue(f.field.typ, raw)
+	if err != nil {
+		return err
+	}
+	f.raw, f.value, f.set = raw, v, true
+	return nil
+}
+
+func (f *fieldFlag) IsBoolFlag() bool {
+	return f.field.typ.Kind() == reflect.Bool
+}
+
+// overrides holds the flags registered for the config fields
+type overrides []*fieldFlag
+
+// newFlagSet returns a flag set with one flag per config field
+func newFlagSet(name string) (*flag.FlagSet, overrides) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	var out overrides
+	for _, f := range fields() {
+		ff := &fieldFlag{field: f}
+		usage := "sets " + f.Path
+		if f.Env != "" {
+			usage += " (env " + f.Env + ")"
+		}
+		fs.Var(ff, f.Path, usage)
+		out = append(out, ff)
+	}
+	return fs, out
+}
+
+// apply writes the flags that were given onto cfg
+func (o overrides) apply(cfg *Config) {
+	root := reflect.ValueOf(cfg).Elem()
+	for _, ff := range o {
+		if !ff.set {
+			continue
+		}
+		root.FieldByIndex(ff.field.index).Set(ff.value)
+	}
+}
+
+// Redacted returns a copy of the config with secret fields masked, suitable
+// for printing or logging
+func (c *Config) Redacted() Config {
+	out := *c
+	root := reflect.ValueOf(&out).Elem()
+	for _, f := range fields() {
+		if !f.Secret {
+			continue
+		}
+		v := root.FieldByIndex(f.index)
+		switch v.Kind() {
+		case reflect.String:
+			if v.Len() > 0 {
+				v.SetString("REDACTED")
+			}
+		case reflect.Map:
+			if v.Len() == 0 {
+				continue
+			}
+			masked := reflect.MakeMap(v.Type())
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+			for _, k := range keys {
+				masked.SetMapIndex(k, reflect.ValueOf("REDACTED"))
+			}
+			v.Set(masked)
+		}
+	}
+	return out
+}
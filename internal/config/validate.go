@@ -0,0 +1,173 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the values make sense together, beyond what the types
+// guarantee, and reports every problem found at once. It never changes the
+// filesystem; see CheckDirs.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Env == "" {
+		add("env must be set")
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		add("log_level must be one of debug, info, warn, error")
+	}
+
+	if c.Storage_path == "" {
+		add("storage_path must be set")
+	}
+
+	if !oneOf(c.SQLite.JournalMode, "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF") {
+		add("sqlite.journal_mode must be one of DELETE, TRUNCATE, PERSIST, MEMORY, WAL, OFF")
+	}
+	if !oneOf(c.SQLite.Synchronous, "OFF", "NORMAL", "FULL", "EXTRA") {
+		add("sqlite.synchronous must be one of OFF, NORMAL, FULL, EXTRA")
+	}
+	if c.SQLite.BusyTimeout < 0 || c.SQLite.ConnMaxIdleTime < 0 {
+		add("sqlite.busy_timeout and sqlite.conn_max_idle_time must not be negative")
+	}
+	if c.SQLite.MaxReadConns < 1 {
+		add("sqlite.max_read_conns must be at least 1")
+	}
+
+	if _, port, err := net.SplitHostPort(c.HTTPServer.Addr); err != nil {
+		add("http_server.addr: %v", err)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		add("http_server.addr: invalid port %q", port)
+	}
+	for name, d := range map[string]int64{
+		"read_timeout":        int64(c.HTTPServer.ReadTimeout),
+		"read_header_timeout": int64(c.HTTPServer.ReadHeaderTimeout),
+		"write_timeout":       int64(c.HTTPServer.WriteTimeout),
+		"idle_timeout":        int64(c.HTTPServer.IdleTimeout),
+		"shutdown_timeout":    int64(c.HTTPServer.ShutdownTimeout),
+		"drain_delay":         int64(c.HTTPServer.DrainDelay),
+		"max_header_bytes":    int64(c.HTTPServer.MaxHeaderBytes),
+		"max_body_bytes":      c.HTTPServer.MaxBodyBytes,
+	} {
+		if d < 0 {
+			add("http_server.%s must not be negative", name)
+		}
+	}
+	tls := c.HTTPServer.TLS
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		add("http_server.tls: cert_file and key_file must be set together")
+	}
+	for name, path := range map[string]string{"cert_file": tls.CertFile, "key_file": tls.KeyFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			add("http_server.tls.%s: %v", name, err)
+		}
+	}
+
+	switch c.Tracing.Exporter {
+	case "", "none", "otlp", "stdout":
+	case "file":
+		if c.Tracing.File == "" {
+			add("tracing.file must be set for the file exporter")
+		}
+	default:
+		add("tracing.exporter must be one of none, otlp, stdout, file")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio must be between 0 and 1")
+	}
+
+	if c.Events.ReplayBuffer < 0 || c.Events.SubscriberBuffer < 1 {
+		add("events.replay_buffer must not be negative and events.subscriber_buffer must be at least 1")
+	}
+	if c.Events.Heartbeat <= 0 {
+		add("events.heartbeat must be positive")
+	}
+
+	ws := c.WebSocket
+	if ws.PingInterval <= 0 || ws.PongWait <= 0 || ws.WriteWait <= 0 {
+		add("websocket.ping_interval, pong_wait and write_wait must be positive")
+	} else if ws.PongWait <= ws.PingInterval {
+		add("websocket.pong_wait must be longer than websocket.ping_interval")
+	}
+	if ws.SendBuffer < 1 || ws.MaxMessageBytes < 1 {
+		add("websocket.send_buffer and websocket.max_message_bytes must be at least 1")
+	}
+
+	if c.Backup.Dir == "" {
+		add("backup.dir must be set")
+	}
+	if c.Backup.Interval < 0 {
+		add("backup.interval must not be negative")
+	}
+	if c.Backup.Retain < 0 {
+		add("backup.retain must not be negative")
+	}
+
+	hooks := c.Webhooks
+	if hooks.Workers < 1 || hooks.MaxAttempts < 1 || hooks.BatchSize < 1 {
+		add("webhooks.workers, max_attempts and batch_size must be at least 1")
+	}
+	if hooks.PollInterval <= 0 || hooks.Timeout <= 0 || hooks.BackoffBase <= 0 {
+		add("webhooks.poll_interval, timeout and backoff_base must be positive")
+	} else if hooks.BackoffMax < hooks.BackoffBase {
+		add("webhooks.backoff_max must not be shorter than webhooks.backoff_base")
+	}
+
+	return errors.Join(errs...)
+}
+
+// CheckDirs makes sure the directory of the database, and the backup
+// directory when scheduled backups are on, exist and are writable, creating
+// them if needed. The server runs it at startup, after Validate.
+func (c *Config) CheckDirs() error {
+	var errs []error
+	if err := checkWritableDir(filepath.Dir(c.Storage_path)); err != nil {
+		errs = append(errs, fmt.Errorf("storage_path: %w", err))
+	}
+	if c.Backup.Interval > 0 {
+		if err := checkWritableDir(c.Backup.Dir); err != nil {
+			errs = append(errs, fmt.Errorf("backup.dir: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkWritableDir makes sure files can be created in dir, creating it if
+// needed
+func checkWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// oneOf reports whether value matches one of the options, ignoring case
+func oneOf(value string, options ...string) bool {
+	for _, option := range options {
+		if strings.EqualFold(value, option) {
+			return true
+		}
+	}
+	return false
+}
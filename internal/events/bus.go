@@ -0,0 +1,196 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// Event types published for task and user changes
+const (
+	TaskCreated    = "task.created"
+	TaskUpdated    = "task.updated"
+	TaskCompleted  = "task.completed"
+	TaskIncomplete = "task.incomplete"
+	TaskDeleted    = "task.deleted"
+	UserDeleted    = "user.deleted"
+)
+
+// Types lists every event type, in the order above
+var Types = []string{TaskCreated, TaskUpdated, TaskCompleted, TaskIncomplete, TaskDeleted, UserDeleted}
+
+// Event is one change to a user's tasks. Task holds the task as it is after
+// the change and is empty for deletions.
+type Event struct {
+	ID     uint64              `json:"id"`
+	Type   string              `json:"type"`
+	UserId int64               `json:"user_id"`
+	TaskId int64               `json:"task_id,omitempty"`
+	Task   *types.TaskMetaData `json:"task,omitempty"`
+	At     time.Time           `json:"at"`
+}
+
+// Bus fans events out to subscribers and keeps the most recent ones so a
+// reconnecting subscriber can catch up. Event ids increase by one per event
+// and start from the boot time in microseconds, so ids from before a restart
+// are always older than anything in the buffer.
+type Bus struct {
+	mu        sync.Mutex
+	nextID    uint64
+	replay    []Event
+	head      int
+	evicted   uint64
+	subBuffer int
+	subs      map[*Subscription]struct{}
+	listeners []func(Event)
+	closed    bool
+	done      chan struct{}
+}
+
+// Subscription receives the events of one user. C is closed when the
+// subscriber falls too far behind or the bus shuts down.
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	userId int64
+	bus    *Bus
+}
+
+// NewBus keeps up to replaySize events for resumption and buffers up to
+// subscriberBuffer undelivered events per subscriber
+func NewBus(replaySize int, subscriberBuffer int) *Bus {
+	first := uint64(time.Now().UnixMicro())
+	return &Bus{
+		nextID:    first,
+		replay:    make([]Event, 0, replaySize),
+		evicted:   first - 1,
+		subBuffer: subscriberBuffer,
+		subs:      make(map[*Subscription]struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Listen registers fn to be called with every published event, from the
+// publishing goroutine once subscribers have been served. Unlike
+// subscribers, listeners are never dropped, so fn should return quickly.
+func (b *Bus) Listen(fn func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, fn)
+}
+
+// Publish assigns the event its id and timestamp, records it for replay and
+// delivers it to the user's subscribers and listeners. A subscriber whose
+// buffer is full is dropped rather than blocking the publisher; it can resume
+// from the replay buffer.
+func (b *Bus) Publish(ev Event) Event {
+	ev, listeners := b.publish(ev)
+	for _, fn := range listeners {
+		fn(ev)
+	}
+	return ev
+}
+
+func (b *Bus) publish(ev Event) (Event, []func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ev.ID = b.nextID
+	b.nextID++
+	ev.At = time.Now().UTC()
+
+	if cap(b.replay) > 0 {
+		if len(b.replay) < cap(b.replay) {
+			b.replay = append(b.replay, ev)
+		} else {
+			b.evicted = b.replay[b.head].ID
+			b.replay[b.head] = ev
+			b.head = (b.head + 1) % len(b.replay)
+		}
+	} else {
+		b.evicted = ev.ID
+	}
+
+	for sub := range b.subs {
+		if sub.userId != ev.UserId {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			b.drop(sub)
+		}
+	}
+	return ev, b.listeners
+}
+
+// Subscribe starts delivering the user's events. When resuming after
+// lastID, the buffered events the subscriber missed are returned; complete
+// is false when some of them have already been evicted, or lastID is not an
+// id this bus handed out, in which case the caller should refetch its state.
+func (b *Bus) Subscribe(userId int64, lastID uint64, resume bool) (sub *Subscription, missed []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, b.subBuffer)
+	sub = &Subscription{C: ch, ch: ch, userId: userId, bus: b}
+	if b.closed {
+		close(ch)
+		return sub, nil, true
+	}
+	b.subs[sub] = struct{}{}
+
+	if !resume {
+		return sub, nil, true
+	}
+	complete = lastID >= b.evicted && lastID < b.nextID
+	for i := 0; i < len(b.replay); i++ {
+		ev := b.replay[(b.head+i)%len(b.replay)]
+		if ev.ID > lastID && ev.UserId == userId {
+			missed = append(missed, ev)
+		}
+	}
+	return sub, missed, complete
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subs[s]; ok {
+		s.bus.drop(s)
+	}
+}
+
+// Close ends every subscription; later subscriptions end immediately
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	close(b.done)
+	for sub := range b.subs {
+		b.drop(sub)
+	}
+}
+
+// Done is closed once the bus shuts down, for connections that should end
+// with it whether or not they subscribed
+func (b *Bus) Done() <-chan struct{} {
+	return b.done
+}
+
+// Subscribers returns the number of open subscriptions
+func (b *Bus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func (b *Bus) drop(sub *Subscription) {
+	delete(b.subs, sub)
+	close(sub.ch)
+}
@@ -0,0 +1,141 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+)
+
+// ids lists the ids of events
+func ids(evs []Event) []uint64 {
+	out := make([]uint64, 0, len(evs))
+	for _, ev := range evs {
+		out = append(out, ev.ID)
+	}
+	return out
+}
+
+func TestPublishAssignsIds(t *testing.T) {
+	bus := NewBus(8, 8)
+	first := bus.Publish(Event{Type: TaskCreated, UserId: 1, TaskId: 1})
+	second := bus.Publish(Event{Type: TaskUpdated, UserId: 2, TaskId: 2})
+	if second.ID != first.ID+1 || first.At.IsZero() || second.At.Before(first.At) {
+		t.Errorf("published %+v then %+v", first, second)
+	}
+	// A bus started later hands out later ids than anything before it
+	if later := NewBus(8, 8).Publish(Event{UserId: 1}); later.ID <= second.ID {
+		t.Errorf("a new bus started at id %d, after %d", later.ID, second.ID)
+	}
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	bus := NewBus(4, 8)
+	var published []Event
+	for i := 0; i < 3; i++ {
+		published = append(published, bus.Publish(Event{Type: TaskCreated, UserId: 1, TaskId: int64(i)}))
+		bus.Publish(Event{Type: TaskCreated, UserId: 2})
+	}
+	// The buffer now holds user 1's second and third events and two of user 2's
+
+	for _, tc := range []struct {
+		name     string
+		lastID   uint64
+		missed   []uint64
+		complete bool
+	}{
+		{"caught up", published[2].ID, nil, true},
+		{"missed one", published[1].ID, ids(published[2:]), true},
+		{"missed evicted events", published[0].ID, ids(published[1:]), false},
+		{"last evicted event", published[1].ID - 1, ids(published[1:]), true},
+		{"id from before a restart", 1, ids(published[1:]), false},
+		{"id this bus never handed out", published[2].ID + 100, nil, false},
+	} {
+		sub, missed, complete := bus.Subscribe(1, tc.lastID, true)
+		sub.Close()
+		if fmt.Sprint(ids(missed)) != fmt.Sprint(tc.missed) || complete != tc.complete {
+			t.Errorf("%s: missed %v complete %v, want %v %v", tc.name, ids(missed), complete, tc.missed, tc.complete)
+		}
+	}
+
+	// Without resuming nothing is replayed
+	sub, missed, complete := bus.Subscribe(1, 0, false)
+	sub.Close()
+	if len(missed) != 0 || !complete {
+		t.Errorf("fresh subscription: %v %v", missed, complete)
+	}
+}
+
+func TestSubscribeWithoutReplayBuffer(t *testing.T) {
+	bus := NewBus(0, 8)
+	ev := bus.Publish(Event{UserId: 1})
+	if _, missed, complete := bus.Subscribe(1, ev.ID, true); len(missed) != 0 || !complete {
+		t.Errorf("caught up: %v %v", missed, complete)
+	}
+	if _, missed, complete := bus.Subscribe(1, ev.ID-1, true); len(missed) != 0 || complete {
+		t.Errorf("one behind: %v %v", missed, complete)
+	}
+}
+
+func TestDeliveryAndSlowSubscribers(t *testing.T) {
+	bus := NewBus(16, 2)
+	slow, _, _ := bus.Subscribe(1, 0, false)
+	fast, _, _ := bus.Subscribe(1, 0, false)
+	other, _, _ := bus.Subscribe(2, 0, false)
+	var heard []uint64
+	bus.Listen(func(ev Event) { heard = append(heard, ev.ID) })
+
+	var published []Event
+	for i := 0; i < 3; i++ {
+		ev := bus.Publish(Event{Type: TaskUpdated, UserId: 1})
+		published = append(published, ev)
+		// The fast subscriber keeps up
+		if got := <-fast.C; got.ID != ev.ID {
+			t.Fatalf("fast subscriber got %d, want %d", got.ID, ev.ID)
+		}
+	}
+
+	// The slow one got what fit in its buffer and was then dropped
+	var got []Event
+	for ev := range slow.C {
+		got = append(got, ev)
+	}
+	if fmt.Sprint(ids(got)) != fmt.Sprint(ids(published[:2])) {
+		t.Errorf("slow subscriber got %v", ids(got))
+	}
+	if n := bus.Subscribers(); n != 2 {
+		t.Errorf("%d subscribers after dropping one, want 2", n)
+	}
+	// Other users' events never reach a subscriber, and listeners hear all
+	select {
+	case ev := <-other.C:
+		t.Errorf("user 2 got %+v", ev)
+	default:
+	}
+	if fmt.Sprint(heard) != fmt.Sprint(ids(published)) {
+		t.Errorf("listener heard %v", heard)
+	}
+	// Closing a dropped subscription is harmless
+	slow.Close()
+}
+
+func TestClose(t *testing.T) {
+	bus := NewBus(16, 2)
+	sub, _, _ := bus.Subscribe(1, 0, false)
+	bus.Close()
+	bus.Close()
+	if _, ok := <-sub.C; ok {
+		t.Error("subscription open after the bus closed")
+	}
+	select {
+	case <-bus.Done():
+	default:
+		t.Error("Done not closed")
+	}
+	late, _, _ := bus.Subscribe(1, 0, false)
+	if _, ok := <-late.C; ok {
+		t.Error("subscription after close is open")
+	}
+	if n := bus.Subscribers(); n != 0 {
+		t.Errorf("%d subscribers after close", n)
+	}
+	sub.Close()
+}
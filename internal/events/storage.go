@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/storage"
+)
+
+// Storage decorates a storage.Storage, publishing an event for every
+// successful task mutation. Reads pass straight through.
+type Storage struct {
+	storage.Storage
+	bus *Bus
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+func WrapStorage(next storage.Storage, bus *Bus) *Storage {
+	return &Storage{Storage: next, bus: bus}
+}
+
+// publishTask publishes a change along with the task's current state. The
+// change has already happened, so a failed lookup only leaves Task empty.
+func (s *Storage) publishTask(ctx context.Context, eventType string, userId int64, taskId int64) {
+	ev := Event{Type: eventType, UserId: userId, TaskId: taskId}
+	if task, err := s.Storage.GetSingleTask(ctx, userId, taskId); err == nil {
+		ev.Task = task
+	}
+	s.bus.Publish(ev)
+}
+
+func (s *Storage) AddNewTask(ctx context.Context, userId int64, title string, description string, priority string, completed bool, createdAt time.Time, updatedAt time.Time) (int64, error) {
+	taskId, err := s.Storage.AddNewTask(ctx, userId, title, description, priority, completed, createdAt, updatedAt)
+	if err == nil {
+		s.publishTask(ctx, TaskCreated, userId, taskId)
+	}
+	return taskId, err
+}
+
+func (s *Storage) EditTask(ctx context.Context, userId int64, taskId int64, title string, description string, priority string) error {
+	err := s.Storage.EditTask(ctx, userId, taskId, title, description, priority)
+	if err == nil {
+		s.publishTask(ctx, TaskUpdated, userId, taskId)
+	}
+	return err
+}
+
+func (s *Storage) MarkComplete(ctx context.Context, userId int64, taskId int64) error {
+	err := s.Storage.MarkComplete(ctx, userId, taskId)
+	if err == nil {
+		s.publishTask(ctx, TaskCompleted, userId, taskId)
+	}
+	return err
+}
+
+func (s *Storage) MarkIncomplete(ctx context.Context, userId int64, taskId int64) error {
+	err := s.Storage.MarkIncomplete(ctx, userId, taskId)
+	if err == nil {
+		s.publishTask(ctx, TaskIncomplete, userId, taskId)
+	}
+	return err
+}
+
+func (s *Storage) DeletingTask(ctx context.Context, userId int64, taskId int64) error {
+	err := s.Storage.DeletingTask(ctx, userId, taskId)
+	if err == nil {
+		s.bus.Publish(Event{Type: TaskDeleted, UserId: userId, TaskId: taskId})
+	}
+	return err
+}
+
+func (s *Storage) DeleteUser(ctx context.Context, userId int64) error {
+	err := s.Storage.DeleteUser(ctx, userId)
+	if err == nil {
+		s.bus.Publish(Event{Type: UserDeleted, UserId: userId})
+	}
+	return err
+}
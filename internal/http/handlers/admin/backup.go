@@ -0,0 +1,38 @@
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/srmty09/Todo-App/internal/backup"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// CreateBackup handles POST /api/admin/backups, taking an online backup now
+func CreateBackup(backups *backup.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info, err := backups.Create(r.Context())
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		logger.FromContext(r.Context()).Info("backup taken", slog.String("file", info.Path), slog.Int64("size", info.Size))
+		response.WriteJson(w, http.StatusCreated, info)
+	}
+}
+
+// ListBackups handles GET /api/admin/backups, newest first
+func ListBackups(backups *backup.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list, err := backups.List()
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if list == nil {
+			list = []backup.Info{}
+		}
+		response.WriteJson(w, http.StatusOK, list)
+	}
+}
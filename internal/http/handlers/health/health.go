@@ -0,0 +1,87 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/utils/response"
+	"github.com/srmty09/Todo-App/internal/version"
+)
+
+// checkTimeout bounds how long a readiness probe may wait on a dependency
+const checkTimeout = 2 * time.Second
+
+// Check is a named readiness condition
+type Check struct {
+	Name string
+	Func func(ctx context.Context) error
+}
+
+// State tracks whether the server is shutting down
+type State struct {
+	draining atomic.Bool
+}
+
+// StartDraining makes readiness fail so traffic is routed elsewhere while
+// in-flight requests finish
+func (s *State) StartDraining() {
+	s.draining.Store(true)
+}
+
+func (s *State) Draining() bool {
+	return s.draining.Load()
+}
+
+// Healthz answers as long as the process is able to serve requests
+func Healthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJson(w, http.StatusOK, map[string]string{
+			"status": "ok",
+		})
+	}
+}
+
+// Readyz runs every check and answers 503 when any of them fails or the
+// server is draining
+func Readyz(state *State, checks ...Check) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		ready := true
+		results := make(map[string]string, len(checks)+1)
+		if state.Draining() {
+			ready = false
+			results["shutdown"] = "server is shutting down"
+		} else {
+			results["shutdown"] = "ok"
+		}
+		for _, check := range checks {
+			if err := check.Func(ctx); err != nil {
+				ready = false
+				results[check.Name] = fmt.Sprintf("failed: %s", err)
+				continue
+			}
+			results[check.Name] = "ok"
+		}
+
+		status, code := "ready", http.StatusOK
+		if !ready {
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+		response.WriteJson(w, code, map[string]interface{}{
+			"status": status,
+			"checks": results,
+		})
+	}
+}
+
+// Version reports the build information
+func Version() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response.WriteJson(w, http.StatusOK, version.Get())
+	}
+}
@@ -0,0 +1,94 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/srmty09/Todo-App/internal/version"
+)
+
+// get serves one GET request and decodes the JSON answer into v
+func get(t *testing.T, handler http.HandlerFunc, v interface{}) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type %q", ct)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("%s: %v", rec.Body, err)
+	}
+	return rec.Code
+}
+
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func TestHealthz(t *testing.T) {
+	var body map[string]string
+	if code := get(t, Healthz(), &body); code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("healthz: %d %v", code, body)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	ok := Check{Name: "database", Func: func(ctx context.Context) error { return nil }}
+	failing := Check{Name: "migrations", Func: func(ctx context.Context) error { return errors.New("schema version 3, want 4") }}
+	deadline := Check{Name: "deadline", Func: func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			return errors.New("no deadline")
+		}
+		return nil
+	}}
+
+	var body readiness
+	if code := get(t, Readyz(&State{}, ok, deadline), &body); code != http.StatusOK || body.Status != "ready" ||
+		body.Checks["database"] != "ok" || body.Checks["deadline"] != "ok" || body.Checks["shutdown"] != "ok" {
+		t.Errorf("all checks passing: %d %+v", code, body)
+	}
+
+	// Every check runs and is reported, not only the first failure
+	body = readiness{}
+	if code := get(t, Readyz(&State{}, failing, ok), &body); code != http.StatusServiceUnavailable || body.Status != "unavailable" ||
+		body.Checks["migrations"] != "failed: schema version 3, want 4" || body.Checks["database"] != "ok" {
+		t.Errorf("failing check: %d %+v", code, body)
+	}
+}
+
+func TestReadyzDraining(t *testing.T) {
+	state := &State{}
+	ready := Readyz(state)
+	var body readiness
+	if code := get(t, ready, &body); code != http.StatusOK {
+		t.Fatalf("before draining: %d %+v", code, body)
+	}
+
+	state.StartDraining()
+	body = readiness{}
+	if code := get(t, ready, &body); code != http.StatusServiceUnavailable || body.Checks["shutdown"] != "server is shutting down" {
+		t.Errorf("while draining: %d %+v", code, body)
+	}
+	// Liveness is unaffected; the process is still fine
+	var live map[string]string
+	if code := get(t, Healthz(), &live); code != http.StatusOK {
+		t.Errorf("healthz while draining: %d", code)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	old := version.Commit
+	version.Commit = "abc123"
+	defer func() { version.Commit = old }()
+
+	var info version.Info
+	if code := get(t, Version(), &info); code != http.StatusOK || info.Version != version.Version || info.Commit != "abc123" || info.GoVersion != runtime.Version() {
+		t.Errorf("version: %d %+v", code, info)
+	}
+}
@@ -0,0 +1,284 @@
+package tasks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/events"
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/taskio"
+	"github.com/srmty09/Todo-App/internal/types"
+	"github.com/srmty09/Todo-App/internal/utils/helpers"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// CalendarStore keeps the tokens of calendar feeds, by their hash
+type CalendarStore interface {
+	SetCalendarToken(ctx context.Context, userId int64, hash string) error
+	CalendarUser(ctx context.Context, hash string) (userId int64, found bool, err error)
+}
+
+// UIDStore remembers the calendar UIDs tasks were imported under and gives
+// the instance id the UIDs of exported tasks carry
+type UIDStore interface {
+	InstanceId(ctx context.Context) (string, error)
+	TaskForUID(ctx context.Context, userId int64, taskId int64, uid string) (id int64, found bool, err error)
+	SetTaskUID(ctx context.Context, userId int64, taskId int64, uid string) error
+}
+
+// calendarRefresh is how often subscribed calendar apps are asked to poll
+const calendarRefresh = time.Hour
+
+type dueRequest struct {
+	DueAt *time.Time `json:"due_at"`
+}
+
+type calendarFeed struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// hashCalendarToken is what is stored of a feed token, so a copy of the
+// database does not give access to the feeds
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// calendarFeedPath is where the feed of a token is served
+func calendarFeedPath(token string) string {
+	return fmt.Sprintf("/api/v2/calendars/%s/tasks.ics", token)
+}
+
+// parseComponents reads the components query parameter, a comma separated
+// list of "todo" and "event" that defaults to both
+func parseComponents(r *http.Request) (taskio.ICalOptions, error) {
+	value := r.URL.Query().Get("components")
+	if value == "" {
+		return taskio.ICalOptions{Todos: true, Events: true}, nil
+	}
+	var opts taskio.ICalOptions
+	for _, component := range strings.Split(value, ",") {
+		switch strings.TrimSpace(component) {
+		case "todo":
+			opts.Todos = true
+		case "event":
+			opts.Events = true
+		default:
+			return opts, fmt.Errorf("components must be a list of todo, event")
+		}
+	}
+	return opts, nil
+}
+
+// writeCalendar answers with the tasks as an iCalendar file
+func writeCalendar(w http.ResponseWriter, r *http.Request, uids UIDStore, userId int64, tasks []types.TaskMetaData, opts taskio.ICalOptions) {
+	instance, err := uids.InstanceId(r.Context())
+	if err != nil {
+		response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+		return
+	}
+	opts.Now = time.Now()
+	opts.Instance = instance
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if err := taskio.WriteICal(w, tasks, opts); err != nil {
+		// The status line is already out; all that is left is to log
+		logger.FromContext(r.Context()).Error("ical export failed", slog.Int64("userId", userId), slog.Any("error", err))
+	}
+}
+
+// SetDue handles PUT /api/v2/users/{id}/tasks/{task_id}/due. A null or
+// omitted due_at clears the due date. The updated task is returned.
+func SetDue(storage storage.Storage, dues DueStore, bus *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, taskId, err := parseTaskPath(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		var req dueRequest
+		if err := decodeBody(r, &req); err != nil {
+			writeError(w, err)
+			return
+		}
+		if _, err := getTask(r.Context(), storage, userId, taskId); err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := dues.SetTaskDue(r.Context(), userId, taskId, req.DueAt, time.Now()); err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		task, err := storage.GetSingleTask(r.Context(), userId, taskId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		bus.Publish(events.Event{Type: events.TaskUpdated, UserId: userId, TaskId: taskId, Task: task})
+		logger.FromContext(r.Context()).Info("task due date set", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
+		response.WriteJson(w, http.StatusOK, task)
+	}
+}
+
+// ExportICal handles GET /api/v2/users/{id}/tasks/export.ics, sending every
+// task of the user as an iCalendar file
+func ExportICal(storage storage.Storage, uids UIDStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		opts, err := parseComponents(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		if !requireUser(w, r, storage, userId) {
+			return
+		}
+		tasks, err := storage.GetTaskForId(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"tasks-%d.ics\"", userId))
+		writeCalendar(w, r, uids, userId, tasks, opts)
+	}
+}
+
+// ImportICal handles POST /api/v2/users/{id}/tasks/import.ics. Every VTODO
+// of the calendar in the body becomes a task, validated like a created one.
+// A VTODO whose UID belongs to a task of the user, exported from here or
+// imported before, is skipped. With dry_run=true they are only checked.
+func ImportICal(storage storage.Storage, dues DueStore, uids UIDStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		dryRun, err := parseDryRun(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		if !requireUser(w, r, storage, userId) {
+			return
+		}
+
+		rows, err := taskio.ReadICal(r.Body)
+		if err != nil {
+			response.WriteJson(w, helpers.BodyErrorStatus(err), response.GeneralError(fmt.Errorf("ical: %w", err)))
+			return
+		}
+		report, err := importTasks(r.Context(), storage, dues, uids, userId, rows, dryRun)
+		if err != nil {
+			writeImportFailure(w, r, userId, "ical", report, err)
+			return
+		}
+		writeImportReport(w, r, userId, "ical", report)
+	}
+}
+
+// CreateCalendarFeed handles POST /api/v2/users/{id}/calendar. It issues a
+// new secret feed URL, which stops any earlier one from working; the token
+// is only shown here.
+func CreateCalendarFeed(storage storage.Storage, calendars CalendarStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		if !requireUser(w, r, storage, userId) {
+			return
+		}
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		token := base64.RawURLEncoding.EncodeToString(b)
+		if err := calendars.SetCalendarToken(r.Context(), userId, hashCalendarToken(token)); err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		logger.FromContext(r.Context()).Info("calendar feed issued", slog.Int64("userId", userId))
+		response.WriteJson(w, http.StatusCreated, calendarFeed{
+			URL:   fmt.Sprintf("%s://%s%s", scheme, r.Host, calendarFeedPath(token)),
+			Token: token,
+		})
+	}
+}
+
+// RemoveCalendarFeed handles DELETE /api/v2/users/{id}/calendar, turning
+// the user's feed off
+func RemoveCalendarFeed(storage storage.Storage, calendars CalendarStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		if !requireUser(w, r, storage, userId) {
+			return
+		}
+		if err := calendars.SetCalendarToken(r.Context(), userId, ""); err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		logger.FromContext(r.Context()).Info("calendar feed removed", slog.Int64("userId", userId))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CalendarFeed handles GET /api/v2/calendars/{token}/tasks.ics, the feed
+// calendar apps subscribe to. The token in the URL is the only credential,
+// so an unknown one gets the same 404 as a feed that was turned off.
+func CalendarFeed(storage storage.Storage, calendars CalendarStore, uids UIDStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts, err := parseComponents(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		userId, found, err := calendars.CalendarUser(r.Context(), hashCalendarToken(r.PathValue("token")))
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if !found {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("calendar not found")))
+			return
+		}
+		user, err := storage.GetUser(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		tasks, err := storage.GetTaskForId(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		opts.Name = "Tasks of " + user.Name
+		opts.Refresh = calendarRefresh
+		w.Header().Set("Cache-Control", "private, max-age=300")
+		writeCalendar(w, r, uids, userId, tasks, opts)
+	}
+}
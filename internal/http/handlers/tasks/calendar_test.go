@@ -0,0 +1,124 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/srmty09/Todo-App/internal/taskio"
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+func TestImportICalByUID(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	userId, err := store.CreateUser(ctx, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v2/users/{id}/tasks/import.ics", ImportICal(store, store, store))
+	mux.HandleFunc("GET /api/v2/users/{id}/tasks/export.ics", ExportICal(store, store))
+	base := fmt.Sprintf("/api/v2/users/%d/tasks", userId)
+	importICal := func(query string, body string) (int, types.ImportReport) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", base+"/import.ics"+query, strings.NewReader(body)))
+		var report types.ImportReport
+		decode(t, rec, &report)
+		return rec.Code, report
+	}
+	count := func() int {
+		tasks, err := store.GetTaskForId(ctx, userId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(tasks)
+	}
+
+	todo := func(uid, summary string) string {
+		return "BEGIN:VTODO\r\nUID:" + uid + "\r\nSUMMARY:" + summary + "\r\nEND:VTODO\r\n"
+	}
+	calendar := func(todos ...string) string {
+		return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + strings.Join(todos, "") + "END:VCALENDAR\r\n"
+	}
+
+	// No DESCRIPTION is needed, and a UID repeated in the file is one task
+	feed := calendar(todo("a@example.com", "Call the bank"), todo("b@example.com", "Renew passport"), todo("a@example.com", "Call the bank"))
+	code, report := importICal("", feed)
+	if code != http.StatusCreated || report.Imported != 2 || report.Skipped != 1 || len(report.Errors) != 0 {
+		t.Fatalf("first import: %d %+v", code, report)
+	}
+	task, err := store.GetSingleTask(ctx, userId, report.TaskIds[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Description != "Call the bank" {
+		t.Errorf("description %q, want the summary", task.Description)
+	}
+
+	// The same feed again adds nothing; one more task adds just that
+	code, report = importICal("", feed)
+	if code != http.StatusOK || report.Imported != 0 || report.Skipped != 3 {
+		t.Errorf("second import: %d %+v", code, report)
+	}
+	code, report = importICal("?dry_run=true", calendar(todo("a@example.com", "Call the bank"), todo("c@example.com", "Book dentist")))
+	if code != http.StatusOK || report.Valid != 1 || report.Skipped != 1 {
+		t.Errorf("dry run: %d %+v", code, report)
+	}
+	code, report = importICal("", calendar(todo("a@example.com", "Call the bank"), todo("c@example.com", "Book dentist")))
+	if code != http.StatusCreated || report.Imported != 1 || report.Skipped != 1 {
+		t.Errorf("import with one new task: %d %+v", code, report)
+	}
+	if n := count(); n != 3 {
+		t.Fatalf("%d tasks, want 3", n)
+	}
+
+	// An export of the user's own tasks is recognised by the UIDs it has
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", base+"/export.ics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: %d %s", rec.Code, rec.Body)
+	}
+	code, report = importICal("", rec.Body.String())
+	if code != http.StatusOK || report.Imported != 0 || report.Skipped != 3 {
+		t.Errorf("importing the export: %d %+v", code, report)
+	}
+
+	// Another server's tasks are new here even when their ids are taken,
+	// and are then known by their UID like any other
+	tasks, err := store.GetTaskForId(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign := calendar(todo(taskio.TaskUID(tasks[0].Id, "ffffffffffffffff"), "Water plants"))
+	code, report = importICal("", foreign)
+	if code != http.StatusCreated || report.Imported != 1 {
+		t.Errorf("import of another server's task %d: %d %+v", tasks[0].Id, code, report)
+	}
+	code, report = importICal("", foreign)
+	if code != http.StatusOK || report.Skipped != 1 {
+		t.Errorf("second import of another server's task: %d %+v", code, report)
+	}
+	if n := count(); n != 4 {
+		t.Fatalf("%d tasks, want 4", n)
+	}
+
+	// Another user's task ids are not the user's
+	other, err := store.CreateUser(ctx, "Bob", "bob@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported := httptest.NewRecorder()
+	mux.ServeHTTP(exported, httptest.NewRequest("GET", base+"/export.ics", nil))
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", fmt.Sprintf("/api/v2/users/%d/tasks/import.ics", other), exported.Body))
+	if rec.Code != http.StatusCreated {
+		t.Errorf("import of Ann's export for Bob: %d %s", rec.Code, rec.Body)
+	}
+	if n := count(); n != 4 {
+		t.Errorf("Ann has %d tasks after Bob's import, want 4", n)
+	}
+}
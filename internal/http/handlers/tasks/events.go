@@ -0,0 +1,136 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/events"
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/utils/helpers"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// sseRetry is how long browsers wait before reconnecting a dropped stream
+const sseRetry = 3 * time.Second
+
+// Events handles GET /api/v2/users/{id}/events, streaming the user's task
+// changes as Server-Sent Events. A client reconnecting with Last-Event-ID
+// (or ?last_event_id) first receives what it missed; when that is no longer
+// buffered it gets a "reset" event and should refetch its tasks. Comment
+// lines are sent every heartbeat to keep proxies from closing idle streams.
+func Events(storage storage.Storage, bus *events.Bus, heartbeat time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		lastID, resume, err := lastEventID(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		exists, err := storage.UserExists(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if !exists {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("user with id %d does not exist", userId)))
+			return
+		}
+
+		// The stream outlives the server's write timeout
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		sub, missed, complete := bus.Subscribe(userId, lastID, resume)
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+
+		log := logger.FromContext(r.Context())
+		log.Info("event stream opened", slog.Int64("userId", userId), slog.Int("missed", len(missed)), slog.Bool("complete", complete))
+
+		if !complete {
+			fmt.Fprint(w, "event: reset\ndata: {\"reason\":\"missed events are no longer available, refetch the tasks\"}\n\n")
+		}
+		for _, ev := range missed {
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case ev, ok := <-sub.C:
+				if !ok {
+					// Fell behind or shutting down; the client resumes
+					// from its last event id
+					return
+				}
+				if err := writeEvent(w, ev); err != nil {
+					return
+				}
+				if ev.Type == events.UserDeleted {
+					rc.Flush()
+					return
+				}
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// lastEventID reads the id a reconnecting client saw last. Browsers send the
+// header by themselves; the query parameter serves the first connection of
+// clients that kept the id elsewhere.
+func lastEventID(r *http.Request) (uint64, bool, error) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	if raw == "" {
+		return 0, false, nil
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid last event id %q", raw)
+	}
+	return id, true, nil
+}
+
+func writeEvent(w http.ResponseWriter, ev events.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	return err
+}
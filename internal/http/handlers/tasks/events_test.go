@@ -0,0 +1,165 @@
+package tasks
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/events"
+)
+
+// sseMessage is one block of an event stream
+type sseMessage struct {
+	id, event, data, retry, comment string
+}
+
+// readSSE reads the next block of the stream, up to its blank line
+func readSSE(t *testing.T, r *bufio.Reader) sseMessage {
+	t.Helper()
+	var msg sseMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading the stream: %v (so far %+v)", err, msg)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return msg
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			msg.id = value
+		case "event":
+			msg.event = value
+		case "data":
+			msg.data = value
+		case "retry":
+			msg.retry = value
+		case "":
+			msg.comment = value
+		default:
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	store := newTestStore(t)
+	userId, err := store.CreateUser(context.Background(), "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := events.NewBus(2, 8)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/users/{id}/events", Events(store, bus, 50*time.Millisecond))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	url := fmt.Sprintf("%s/api/v2/users/%d/events", server.URL, userId)
+
+	open := func(lastEventID string) (*http.Response, *bufio.Reader) {
+		t.Helper()
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("opening the stream: %d %v", resp.StatusCode, resp.Header)
+		}
+		r := bufio.NewReader(resp.Body)
+		if msg := readSSE(t, r); msg.retry != "3000" {
+			t.Fatalf("first block %+v, want the retry interval", msg)
+		}
+		return resp, r
+	}
+	expectEvent := func(r *bufio.Reader, want events.Event) {
+		t.Helper()
+		msg := readSSE(t, r)
+		for msg.comment == "ping" {
+			msg = readSSE(t, r)
+		}
+		var got events.Event
+		if err := json.Unmarshal([]byte(msg.data), &got); err != nil {
+			t.Fatalf("%+v: %v", msg, err)
+		}
+		if msg.id != fmt.Sprint(want.ID) || msg.event != want.Type || got.ID != want.ID || got.TaskId != want.TaskId || got.UserId != userId {
+			t.Errorf("got %+v, want event %d %s for task %d", msg, want.ID, want.Type, want.TaskId)
+		}
+	}
+
+	first := bus.Publish(events.Event{Type: events.TaskCreated, UserId: userId, TaskId: 1})
+	bus.Publish(events.Event{Type: events.TaskCreated, UserId: userId + 1, TaskId: 2})
+	missed := bus.Publish(events.Event{Type: events.TaskCompleted, UserId: userId, TaskId: 1})
+
+	// Resuming after the first event replays the one missed, then goes live
+	_, stream := open(fmt.Sprint(first.ID))
+	expectEvent(stream, missed)
+	live := bus.Publish(events.Event{Type: events.TaskDeleted, UserId: userId, TaskId: 1})
+	expectEvent(stream, live)
+	// Idle streams get heartbeats
+	if msg := readSSE(t, stream); msg.comment != "ping" {
+		t.Errorf("idle stream sent %+v, want a ping", msg)
+	}
+
+	// Further back than the buffer reaches, the client is told to refetch
+	// before getting what is left
+	_, stream = open(fmt.Sprint(first.ID - 1))
+	if msg := readSSE(t, stream); msg.event != "reset" || !strings.Contains(msg.data, "refetch") {
+		t.Errorf("resuming from an evicted id: %+v, want a reset", msg)
+	}
+	expectEvent(stream, missed)
+	expectEvent(stream, live)
+
+	// The stream ends when the bus shuts down
+	resp, stream := open("")
+	bus.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := stream.ReadString(0)
+		done <- err
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("stream still open after the bus closed")
+		resp.Body.Close()
+	}
+}
+
+func TestEventStreamErrors(t *testing.T) {
+	store := newTestStore(t)
+	userId, err := store.CreateUser(context.Background(), "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/users/{id}/events", Events(store, events.NewBus(2, 8), time.Minute))
+	for _, tc := range []struct {
+		target string
+		status int
+	}{
+		{fmt.Sprintf("/api/v2/users/%d/events?last_event_id=abc", userId), http.StatusBadRequest},
+		{"/api/v2/users/x/events", http.StatusBadRequest},
+		{fmt.Sprintf("/api/v2/users/%d/events", userId+1), http.StatusNotFound},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", tc.target, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%s: %d %s, want %d", tc.target, rec.Code, rec.Body, tc.status)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package tasks
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/taskio"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// listFormats are the representations a task list can be rendered in
+var listFormats = []string{"json", "markdown", "html"}
+
+// listMediaTypes maps the media types a client may ask for to a format
+var listMediaTypes = map[string]string{
+	"application/json": "json",
+	"text/markdown":    "markdown",
+	"text/html":        "html",
+}
+
+// listView is how a task list is to be rendered
+type listView struct {
+	Format string
+	Group  string
+}
+
+// parseListView picks the representation of a task list: the format query
+// parameter when given, otherwise the type in the Accept header with the
+// highest q-value that is offered, the earlier one on a tie, otherwise JSON.
+// Anything that prefers JSON, including */*, keeps getting JSON as before.
+// The group parameter sections a Markdown or HTML list by priority (the
+// default) or status.
+func parseListView(r *http.Request) (listView, error) {
+	view := listView{Format: "json", Group: "priority"}
+	if group := r.URL.Query().Get("group"); group != "" {
+		if !slices.Contains(taskio.ReportGroups, group) {
+			return view, fmt.Errorf("group must be one of %s", strings.Join(taskio.ReportGroups, ", "))
+		}
+		view.Group = group
+	}
+	if format := r.URL.Query().Get("format"); format != "" {
+		if !slices.Contains(listFormats, format) {
+			return view, fmt.Errorf("format must be one of %s", strings.Join(listFormats, ", "))
+		}
+		view.Format = format
+		return view, nil
+	}
+	for _, mediaType := range acceptedTypes(r.Header.Get("Accept")) {
+		if mediaType == "*/*" || mediaType == "application/*" {
+			break
+		}
+		if format, ok := listMediaTypes[mediaType]; ok {
+			view.Format = format
+			break
+		}
+	}
+	return view, nil
+}
+
+// acceptedTypes lists the media types of an Accept header, most wanted
+// first and otherwise in header order. Types with a q of 0, which the
+// client refuses, and entries that do not parse are left out.
+func acceptedTypes(header string) []string {
+	type accepted struct {
+		mediaType string
+		q         float64
+	}
+	var entries []accepted
+	for _, entry := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(entry))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		if q > 0 {
+			entries = append(entries, accepted{mediaType, q})
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b accepted) int { return cmp.Compare(b.q, a.q) })
+	mediaTypes := make([]string, len(entries))
+	for i, entry := range entries {
+		mediaTypes[i] = entry.mediaType
+	}
+	return mediaTypes
+}
+
+// describeFilters says in words which tasks a filtered list holds
+func describeFilters(status string, keyword string) string {
+	tasks := "tasks"
+	switch status {
+	case "completed":
+		tasks = "completed tasks"
+	case "incomplete", "incompleted":
+		tasks = "incomplete tasks"
+	}
+	if keyword != "" {
+		return fmt.Sprintf("Showing %s matching %q", tasks, keyword)
+	}
+	if tasks == "tasks" {
+		return ""
+	}
+	return "Showing " + tasks
+}
+
+// writeTaskReport answers with the user's tasks, selected by the same
+// filters as the JSON list, as a Markdown checklist or printable HTML page
+func writeTaskReport(w http.ResponseWriter, r *http.Request, storage storage.Storage, userId int64, view listView, status string, keyword string) {
+	user, err := storage.GetUser(r.Context(), userId)
+	if err != nil {
+		response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+		return
+	}
+	tasks, err := listTasks(r.Context(), storage, userId, status, keyword)
+	if err != nil {
+		response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+		return
+	}
+	opts := taskio.ReportOptions{
+		Title:  "Tasks of " + user.Name,
+		Filter: describeFilters(status, keyword),
+		Group:  view.Group,
+		Now:    time.Now(),
+	}
+
+	write, contentType := taskio.WriteMarkdown, "text/markdown; charset=utf-8"
+	if view.Format == "html" {
+		write, contentType = taskio.WriteHTML, "text/html; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if err := write(w, tasks, opts); err != nil {
+		// The status line is already out; all that is left is to log
+		logger.FromContext(r.Context()).Error("task report failed", slog.Int64("userId", userId), slog.String("format", view.Format), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,93 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseListView(t *testing.T) {
+	for _, tc := range []struct {
+		query  string
+		accept string
+		want   string
+	}{
+		{"", "", "json"},
+		{"", "*/*", "json"},
+		{"", "text/markdown", "markdown"},
+		{"", "text/html, text/markdown", "html"},
+		{"", "text/html;q=0.1, text/markdown", "markdown"},
+		{"", "text/html;q=0.5, text/markdown;q=0.5", "html"},
+		{"", "text/markdown;q=0.9, application/json", "json"},
+		{"", "text/markdown;q=0.9, */*;q=0.1", "markdown"},
+		{"", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "html"},
+		{"", "text/html;q=0, text/markdown;q=0.2", "markdown"},
+		{"", "text/html;q=2, text/markdown;q=0.2", "markdown"},
+		{"", "text/html;q=abc", "json"},
+		{"", "image/png", "json"},
+		{"?format=markdown", "text/html", "markdown"},
+	} {
+		r := httptest.NewRequest("GET", "/tasks"+tc.query, nil)
+		if tc.accept != "" {
+			r.Header.Set("Accept", tc.accept)
+		}
+		view, err := parseListView(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if view.Format != tc.want {
+			t.Errorf("Accept %q%s: %s, want %s", tc.accept, tc.query, view.Format, tc.want)
+		}
+	}
+	for _, query := range []string{"?format=pdf", "?group=colour"} {
+		if _, err := parseListView(httptest.NewRequest("GET", "/tasks"+query, nil)); err == nil {
+			t.Errorf("%s was accepted", query)
+		}
+	}
+}
+
+func TestListReports(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	userId, err := store.CreateUser(ctx, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, title := range []string{"Buy milk", "Pay rent"} {
+		if _, err := store.AddNewTask(ctx, userId, title, title, "high", false, now, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/users/{id}/tasks", List(store))
+	path := fmt.Sprintf("/api/v2/users/%d/tasks", userId)
+
+	for _, tc := range []struct {
+		target      string
+		accept      string
+		contentType string
+		contains    string
+	}{
+		{path, "text/html;q=0.1, text/markdown", "text/markdown; charset=utf-8", "- [ ] Buy milk\n"},
+		{path, "text/markdown;q=0.5, text/html", "text/html; charset=utf-8", `<span class="title">Pay rent</span>`},
+		{path + "?search=rent&format=markdown", "", "text/markdown; charset=utf-8", `_Showing tasks matching "rent"_`},
+		{path, "text/markdown;q=0.5, application/json", "application/json", `"title":"Buy milk"`},
+	} {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", tc.target, nil)
+		r.Header.Set("Accept", tc.accept)
+		mux.ServeHTTP(rec, r)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != tc.contentType || rec.Header().Get("Vary") != "Accept" {
+			t.Errorf("%s with %q: %d %s %v", tc.target, tc.accept, rec.Code, rec.Header().Get("Content-Type"), rec.Header())
+			continue
+		}
+		if !strings.Contains(rec.Body.String(), tc.contains) {
+			t.Errorf("%s with %q does not contain %q:\n%s", tc.target, tc.accept, tc.contains, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/websocket"
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/events"
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/types"
+	"github.com/srmty09/Todo-App/internal/utils/helpers"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// socketRequest is a message sent by the client. Id is chosen by the client
+// and echoed in the ack or error answering the request.
+type socketRequest struct {
+	Type   string `json:"type"`
+	Id     string `json:"id"`
+	TaskId int64  `json:"task_id"`
+	// create
+	Task *types.TaskMetaData `json:"task"`
+	// update
+	Patch *taskPatch `json:"patch"`
+	// complete; omitted means true
+	Completed *bool `json:"completed"`
+	// subscribe; resumes after this event when set
+	LastEventId *uint64 `json:"last_event_id"`
+}
+
+// socketReply is a message sent by the server: an "ack" or "error" for a
+// request, or an "event" for a task change once subscribed
+type socketReply struct {
+	Type  string              `json:"type"`
+	Id    string              `json:"id,omitempty"`
+	Task  *types.TaskMetaData `json:"task,omitempty"`
+	Event *events.Event       `json:"event,omitempty"`
+	Error string              `json:"error,omitempty"`
+	// Status is the HTTP status the error corresponds to
+	Status int `json:"status,omitempty"`
+	// ReplayComplete answers a resuming subscribe; false means events were
+	// missed and the client should refetch its tasks
+	ReplayComplete *bool `json:"replay_complete,omitempty"`
+}
+
+// Socket handles GET /api/v2/users/{id}/socket, upgrading to a WebSocket
+// carrying the user's task mutations and their acknowledgements, plus the
+// task events once the client subscribes. Mutations go through the same
+// operations as the v2 handlers, so they publish events like any other.
+// Browser origins are accepted when allowOrigin approves them.
+func Socket(storage storage.Storage, bus *events.Bus, cfg config.WebSocket, allowOrigin func(string) bool) http.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || allowOrigin(origin)
+		},
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		exists, err := storage.UserExists(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if !exists {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("user with id %d does not exist", userId)))
+			return
+		}
+
+		// Upgrade answers failed handshakes itself
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		log := logger.FromContext(r.Context()).With(slog.Int64("userId", userId))
+		log.Info("socket opened")
+
+		c := &socketConn{
+			conn:    conn,
+			storage: storage,
+			bus:     bus,
+			cfg:     cfg,
+			userId:  userId,
+			log:     log,
+			out:     make(chan socketReply, cfg.SendBuffer),
+			done:    make(chan struct{}),
+			gone:    make(chan struct{}),
+		}
+		// The request context ends with the handshake's handler, not the
+		// connection, so work is bound to a context of our own
+		ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+		defer cancel()
+		c.run(ctx)
+		log.Info("socket closed")
+	}
+}
+
+// socketConn is one client connection. The read loop handles requests one at
+// a time and a single write loop owns the outgoing side; both meet at out,
+// whose bound is the per-connection backpressure. A request holds order
+// until its answer is queued, so the events it publishes follow its ack.
+type socketConn struct {
+	conn    *websocket.Conn
+	storage storage.Storage
+	bus     *events.Bus
+	cfg     config.WebSocket
+	userId  int64
+	log     *slog.Logger
+
+	out   chan socketReply
+	order sync.Mutex
+	// done is closed when the read loop ends, gone when the write loop does
+	done chan struct{}
+	gone chan struct{}
+
+	// sub is set by subscribe; forwarding starts once its ack is queued,
+	// replaying missed first
+	sub        *events.Subscription
+	missed     []events.Event
+	forwarding bool
+}
+
+func (c *socketConn) run(ctx context.Context) {
+	go func() {
+		c.writeLoop()
+		close(c.gone)
+	}()
+	c.readLoop(ctx)
+	close(c.done)
+	if c.sub != nil {
+		c.sub.Close()
+	}
+	<-c.gone
+	c.conn.Close()
+}
+
+// send queues a reply, waiting while the queue is full. It gives up once the
+// connection is going away.
+func (c *socketConn) send(reply socketReply) bool {
+	select {
+	case c.out <- reply:
+		return true
+	case <-c.done:
+		return false
+	case <-c.gone:
+		return false
+	}
+}
+
+func (c *socketConn) readLoop(ctx context.Context) {
+	c.conn.SetReadLimit(c.cfg.MaxMessageBytes)
+	c.conn.SetReadDeadline(time.Now().Add(c.cfg.PongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.cfg.PongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.log.Info("socket read failed", slog.Any("error", err))
+			}
+			return
+		}
+		// Any message proves the client is alive
+		c.conn.SetReadDeadline(time.Now().Add(c.cfg.PongWait))
+
+		var req socketRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			if !c.send(socketReply{Type: "error", Status: http.StatusBadRequest, Error: fmt.Sprintf("invalid message: %s", err)}) {
+				return
+			}
+			continue
+		}
+		c.order.Lock()
+		sent := c.send(c.handle(ctx, req))
+		c.order.Unlock()
+		if !sent {
+			return
+		}
+		// Events of a new subscription follow its ack
+		if c.sub != nil && !c.forwarding {
+			c.forwarding = true
+			go c.forward(c.sub, c.missed)
+			c.missed = nil
+		}
+	}
+}
+
+// handle runs one request and builds its answer
+func (c *socketConn) handle(ctx context.Context, req socketRequest) socketReply {
+	var task *types.TaskMetaData
+	var err error
+	switch req.Type {
+	case "subscribe":
+		return c.subscribe(req)
+	case "create":
+		if req.Task == nil {
+			err = badMessage("create needs a task")
+			break
+		}
+		task, err = createTask(ctx, c.storage, c.userId, *req.Task)
+	case "update":
+		if req.Patch == nil {
+			err = badMessage("update needs a patch")
+			break
+		}
+		task, err = updateTask(ctx, c.storage, c.userId, req.TaskId, *req.Patch)
+	case "complete":
+		completed := true
+		if req.Completed != nil {
+			completed = *req.Completed
+		}
+		task, err = updateTask(ctx, c.storage, c.userId, req.TaskId, taskPatch{Completed: &completed})
+	case "delete":
+		err = removeTask(ctx, c.storage, c.userId, req.TaskId)
+	default:
+		err = badMessage(fmt.Sprintf("unknown message type %q", req.Type))
+	}
+	if err != nil {
+		return errorReply(req.Id, err)
+	}
+	taskId := req.TaskId
+	if task != nil {
+		taskId = task.Id
+	}
+	c.log.Info("socket request handled", slog.String("type", req.Type), slog.Int64("taskId", taskId))
+	return socketReply{Type: "ack", Id: req.Id, Task: task}
+}
+
+// subscribe starts forwarding the user's events, after replaying the ones
+// missed since last_event_id when given
+func (c *socketConn) subscribe(req socketRequest) socketReply {
+	if c.sub != nil {
+		return errorReply(req.Id, badMessage("already subscribed"))
+	}
+	var lastID uint64
+	if req.LastEventId != nil {
+		lastID = *req.LastEventId
+	}
+	sub, missed, complete := c.bus.Subscribe(c.userId, lastID, req.LastEventId != nil)
+	c.sub, c.missed = sub, missed
+
+	reply := socketReply{Type: "ack", Id: req.Id}
+	if req.LastEventId != nil {
+		reply.ReplayComplete = &complete
+	}
+	return reply
+}
+
+// forward relays events to the client. It waits on a full send queue like
+// any reply; if that lets the bus buffer overflow, the bus drops the
+// subscription and the client is told to subscribe again.
+func (c *socketConn) forward(sub *events.Subscription, missed []events.Event) {
+	for i := range missed {
+		if !c.sendEvent(&missed[i]) {
+			return
+		}
+	}
+	for ev := range sub.C {
+		if !c.sendEvent(&ev) {
+			return
+		}
+	}
+	select {
+	case <-c.done:
+	default:
+		c.send(socketReply{Type: "error", Status: http.StatusServiceUnavailable, Error: "subscription dropped, subscribe again with last_event_id"})
+	}
+}
+
+// sendEvent queues an event once no request is in flight
+func (c *socketConn) sendEvent(ev *events.Event) bool {
+	c.order.Lock()
+	defer c.order.Unlock()
+	return c.send(socketReply{Type: "event", Event: ev})
+}
+
+func (c *socketConn) writeLoop() {
+	ping := time.NewTicker(c.cfg.PingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-c.done:
+			c.closeWith(websocket.CloseNormalClosure, "")
+			return
+		case <-c.bus.Done():
+			c.closeWith(websocket.CloseGoingAway, "server shutting down")
+			return
+		case reply := <-c.out:
+			c.conn.SetWriteDeadline(time.Now().Add(c.cfg.WriteWait))
+			if err := c.conn.WriteJSON(reply); err != nil {
+				// Unblocks the read loop
+				c.conn.Close()
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.cfg.WriteWait)); err != nil {
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// closeWith sends a close frame and, unless the read loop is already done,
+// gives the client a moment to answer before the connection is dropped
+func (c *socketConn) closeWith(code int, text string) {
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(c.cfg.WriteWait))
+	select {
+	case <-c.done:
+	case <-time.After(time.Second):
+		c.conn.Close()
+	}
+}
+
+// badMessage is a request the protocol cannot carry out as sent
+func badMessage(msg string) error {
+	return &statusError{status: http.StatusBadRequest, err: errors.New(msg)}
+}
+
+// errorReply describes a failed request with the status the HTTP API would
+// have answered
+func errorReply(id string, err error) socketReply {
+	var invalid validator.ValidationErrors
+	var withStatus *statusError
+	switch {
+	case errors.As(err, &invalid):
+		return socketReply{Type: "error", Id: id, Status: http.StatusBadRequest, Error: response.ValidationError(invalid).Error}
+	case errors.As(err, &withStatus):
+		return socketReply{Type: "error", Id: id, Status: withStatus.status, Error: withStatus.err.Error()}
+	default:
+		return socketReply{Type: "error", Id: id, Status: http.StatusInternalServerError, Error: err.Error()}
+	}
+}
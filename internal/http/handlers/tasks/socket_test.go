@@ -0,0 +1,233 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/events"
+)
+
+// socketServer serves the socket of a new user over a bus that sees every
+// change made through it
+func socketServer(t *testing.T, sendBuffer int) (*events.Bus, string, int64) {
+	t.Helper()
+	store := newTestStore(t)
+	userId, err := store.CreateUser(context.Background(), "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := events.NewBus(16, 16)
+	cfg := config.WebSocket{PingInterval: time.Minute, PongWait: 2 * time.Minute, WriteWait: 5 * time.Second, SendBuffer: sendBuffer, MaxMessageBytes: 4096}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/users/{id}/socket", Socket(events.WrapStorage(store, bus), bus, cfg, func(origin string) bool { return origin == "https://app.example" }))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return bus, "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v2/users/%d/socket", userId
+}
+
+func dial(t *testing.T, url string, header http.Header) *websocket.Conn {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("dial %s: %v (%d)", url, err, status)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// exchange sends a request and reads the next message
+func exchange(t *testing.T, conn *websocket.Conn, req interface{}) socketReply {
+	t.Helper()
+	if err := conn.WriteJSON(req); err != nil {
+		t.Fatal(err)
+	}
+	return readReply(t, conn)
+}
+
+func readReply(t *testing.T, conn *websocket.Conn) socketReply {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var reply socketReply
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func TestSocketMutations(t *testing.T) {
+	bus, url, userId := socketServer(t, 4)
+	conn := dial(t, fmt.Sprintf(url, userId), nil)
+
+	// Subscribing first means every mutation's event follows its ack
+	if reply := exchange(t, conn, map[string]string{"type": "subscribe", "id": "s"}); reply.Type != "ack" || reply.Id != "s" || reply.ReplayComplete != nil {
+		t.Fatalf("subscribe: %+v", reply)
+	}
+	expectEvent := func(eventType string, taskId int64) *events.Event {
+		t.Helper()
+		reply := readReply(t, conn)
+		if reply.Type != "event" || reply.Event == nil || reply.Event.Type != eventType || reply.Event.TaskId != taskId {
+			t.Fatalf("got %+v, want a %s event for task %d", reply, eventType, taskId)
+		}
+		return reply.Event
+	}
+
+	reply := exchange(t, conn, map[string]interface{}{"type": "create", "id": "1", "task": map[string]string{"title": "Buy milk", "description": "2 litres", "priority": "high"}})
+	if reply.Type != "ack" || reply.Id != "1" || reply.Task == nil || reply.Task.Title != "Buy milk" {
+		t.Fatalf("create: %+v", reply)
+	}
+	taskId := reply.Task.Id
+	created := expectEvent(events.TaskCreated, taskId)
+
+	reply = exchange(t, conn, map[string]interface{}{"type": "update", "id": "2", "task_id": taskId, "patch": map[string]string{"title": "Buy oat milk"}})
+	if reply.Type != "ack" || reply.Id != "2" || reply.Task.Title != "Buy oat milk" || reply.Task.Description != "2 litres" {
+		t.Fatalf("update: %+v", reply)
+	}
+	expectEvent(events.TaskUpdated, taskId)
+
+	reply = exchange(t, conn, map[string]interface{}{"type": "complete", "id": "3", "task_id": taskId})
+	if reply.Type != "ack" || !reply.Task.Completed {
+		t.Fatalf("complete: %+v", reply)
+	}
+	expectEvent(events.TaskCompleted, taskId)
+	reply = exchange(t, conn, map[string]interface{}{"type": "complete", "id": "4", "task_id": taskId, "completed": false})
+	if reply.Type != "ack" || reply.Task.Completed {
+		t.Fatalf("uncomplete: %+v", reply)
+	}
+	expectEvent(events.TaskIncomplete, taskId)
+
+	reply = exchange(t, conn, map[string]interface{}{"type": "delete", "id": "5", "task_id": taskId})
+	if reply.Type != "ack" || reply.Id != "5" || reply.Task != nil {
+		t.Fatalf("delete: %+v", reply)
+	}
+	expectEvent(events.TaskDeleted, taskId)
+
+	// A second connection resuming after the create gets everything since,
+	// with the same ids
+	resumed := dial(t, fmt.Sprintf(url, userId), nil)
+	reply = exchange(t, resumed, map[string]interface{}{"type": "subscribe", "id": "r", "last_event_id": created.ID})
+	if reply.Type != "ack" || reply.ReplayComplete == nil || !*reply.ReplayComplete {
+		t.Fatalf("resumed subscribe: %+v", reply)
+	}
+	for i, want := range []string{events.TaskUpdated, events.TaskCompleted, events.TaskIncomplete, events.TaskDeleted} {
+		reply := readReply(t, resumed)
+		if reply.Type != "event" || reply.Event.Type != want || reply.Event.ID != created.ID+uint64(i)+1 {
+			t.Errorf("replayed %+v, want %s", reply, want)
+		}
+	}
+	// From an id older than the buffer the client is told to refetch
+	stale := dial(t, fmt.Sprintf(url, userId), nil)
+	reply = exchange(t, stale, map[string]interface{}{"type": "subscribe", "id": "r", "last_event_id": 1})
+	if reply.Type != "ack" || reply.ReplayComplete == nil || *reply.ReplayComplete {
+		t.Fatalf("stale subscribe: %+v", reply)
+	}
+
+	// Live events reach every subscriber
+	bus.Publish(events.Event{Type: events.TaskCreated, UserId: userId, TaskId: 99})
+	if reply := readReply(t, resumed); reply.Type != "event" || reply.Event.TaskId != 99 {
+		t.Errorf("live event: %+v", reply)
+	}
+}
+
+func TestSocketErrors(t *testing.T) {
+	_, url, userId := socketServer(t, 4)
+	conn := dial(t, fmt.Sprintf(url, userId), nil)
+
+	for _, tc := range []struct {
+		req    interface{}
+		status int
+		error  string
+	}{
+		{map[string]string{"type": "rename", "id": "1"}, http.StatusBadRequest, `unknown message type "rename"`},
+		{map[string]string{"type": "create", "id": "2"}, http.StatusBadRequest, "create needs a task"},
+		{map[string]interface{}{"type": "create", "id": "3", "task": map[string]string{"title": "x", "description": "y", "priority": "urgent"}}, http.StatusBadRequest, "Priority"},
+		{map[string]interface{}{"type": "update", "id": "4", "task_id": 42}, http.StatusBadRequest, "update needs a patch"},
+		{map[string]interface{}{"type": "update", "id": "5", "task_id": 42, "patch": map[string]string{"title": "x"}}, http.StatusNotFound, "does not exist"},
+		{map[string]interface{}{"type": "complete", "id": "6", "task_id": 42}, http.StatusNotFound, "does not exist"},
+		{map[string]interface{}{"type": "delete", "id": "7", "task_id": 42}, http.StatusNotFound, "does not exist"},
+		{map[string]string{"type": "subscribe", "id": "8"}, 0, ""},
+		{map[string]string{"type": "subscribe", "id": "9"}, http.StatusBadRequest, "already subscribed"},
+	} {
+		reply := exchange(t, conn, tc.req)
+		if tc.status == 0 {
+			if reply.Type != "ack" {
+				t.Errorf("%v: %+v", tc.req, reply)
+			}
+			continue
+		}
+		if reply.Type != "error" || reply.Status != tc.status || !strings.Contains(reply.Error, tc.error) {
+			t.Errorf("%v: %+v, want %d %q", tc.req, reply, tc.status, tc.error)
+		}
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("{not json")); err != nil {
+		t.Fatal(err)
+	}
+	if reply := readReply(t, conn); reply.Type != "error" || reply.Status != http.StatusBadRequest || reply.Id != "" {
+		t.Errorf("invalid JSON: %+v", reply)
+	}
+
+	// The handshake itself is refused for unknown users and foreign origins
+	for _, tc := range []struct {
+		url    string
+		origin string
+		status int
+	}{
+		{fmt.Sprintf(url, userId+1), "", http.StatusNotFound},
+		{fmt.Sprintf(url, userId), "https://evil.example", http.StatusForbidden},
+	} {
+		header := http.Header{}
+		if tc.origin != "" {
+			header.Set("Origin", tc.origin)
+		}
+		_, resp, err := websocket.DefaultDialer.Dial(tc.url, header)
+		if err == nil || resp == nil || resp.StatusCode != tc.status {
+			t.Errorf("%s from %q: %v %v, want %d", tc.url, tc.origin, err, resp, tc.status)
+		}
+	}
+	dial(t, fmt.Sprintf(url, userId), http.Header{"Origin": {"https://app.example"}})
+}
+
+func TestSocketBackpressure(t *testing.T) {
+	// With room for one reply, requests wait on the client instead of
+	// replies being dropped
+	_, url, userId := socketServer(t, 1)
+	conn := dial(t, fmt.Sprintf(url, userId), nil)
+	const n = 20
+	for i := 0; i < n; i++ {
+		req := map[string]interface{}{"type": "create", "id": fmt.Sprint(i), "task": map[string]string{"title": fmt.Sprintf("task %d", i), "description": "d", "priority": "low"}}
+		if err := conn.WriteJSON(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if reply := readReply(t, conn); reply.Type != "ack" || reply.Id != fmt.Sprint(i) || reply.Task.Title != fmt.Sprintf("task %d", i) {
+			t.Fatalf("reply %d: %+v", i, reply)
+		}
+	}
+}
+
+func TestSocketClosesWithBus(t *testing.T) {
+	bus, url, userId := socketServer(t, 4)
+	conn := dial(t, fmt.Sprintf(url, userId), nil)
+	if reply := exchange(t, conn, map[string]string{"type": "subscribe"}); reply.Type != "ack" {
+		t.Fatalf("subscribe: %+v", reply)
+	}
+	bus.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	var closed *websocket.CloseError
+	if !errors.As(err, &closed) || closed.Code != websocket.CloseGoingAway {
+		t.Errorf("read after the bus closed: %v, want a going away close", err)
+	}
+}
@@ -0,0 +1,50 @@
+package tasks
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/storage/sqlite"
+)
+
+// newTestStore opens a migrated database in a temporary directory
+func newTestStore(t *testing.T) *sqlite.Sqlite {
+	t.Helper()
+	cfg, err := config.Load([]string{"-storage_path", filepath.Join(t.TempDir(), "todo.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := sqlite.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// do sends a request with an optional JSON body through handler
+func do(t *testing.T, handler http.Handler, method string, target string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, target, &buf))
+	return rec
+}
+
+// decode reads a JSON response body into v
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("%s: %v", rec.Body, err)
+	}
+}
@@ -0,0 +1,180 @@
+package tasks
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/srmty09/Todo-App/internal/events"
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/types"
+	"github.com/srmty09/Todo-App/internal/utils/helpers"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+const (
+	maxSyncMutations = 500
+	defaultSyncLimit = 500
+	maxSyncLimit     = 1000
+
+	// syncTokenPrefix versions the token format
+	syncTokenPrefix = "c1."
+)
+
+// SyncStore keeps the task change log and applies client mutations
+type SyncStore interface {
+	ChangeSeq(ctx context.Context) (int64, error)
+	ChangesSince(ctx context.Context, userId int64, seq int64, limit int) (*types.SyncChanges, error)
+	ApplySync(ctx context.Context, userId int64, mutations []types.SyncMutation) ([]types.SyncResult, error)
+}
+
+type syncRequest struct {
+	// Token is the change token of the previous sync; empty for a first sync
+	Token     string               `json:"token"`
+	Limit     int                  `json:"limit"`
+	Mutations []types.SyncMutation `json:"mutations"`
+}
+
+type syncResponse struct {
+	Token   string                `json:"token"`
+	More    bool                  `json:"more"`
+	User    *types.User           `json:"user"`
+	Tasks   []types.SyncTask      `json:"tasks"`
+	Deleted []types.SyncTombstone `json:"deleted"`
+	Results []types.SyncResult    `json:"results"`
+}
+
+// encodeSyncToken makes a change log position opaque to clients
+func encodeSyncToken(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(syncTokenPrefix + strconv.FormatInt(seq, 10)))
+}
+
+func decodeSyncToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		if value, ok := strings.CutPrefix(string(raw), syncTokenPrefix); ok {
+			if seq, err := strconv.ParseInt(value, 10, 64); err == nil && seq >= 0 {
+				return seq, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid change token")
+}
+
+// Sync handles POST /api/v2/users/{id}/sync for offline clients. The
+// client's mutations are applied first, then the changes since its token
+// are returned, its own included, with the token to send next time. A
+// response with more set was cut at limit and should be followed up at once
+// with the new token. A token the database no longer knows, such as one
+// from before a restore, is answered with 410 and the client should sync
+// again without one.
+func Sync(storage storage.Storage, sync SyncStore, bus *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		var req syncRequest
+		if err := decodeBody(r, &req); err != nil {
+			writeError(w, err)
+			return
+		}
+		seq, err := decodeSyncToken(req.Token)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		if len(req.Mutations) > maxSyncMutations {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("at most %d mutations per sync", maxSyncMutations)))
+			return
+		}
+		limit := req.Limit
+		if limit == 0 {
+			limit = defaultSyncLimit
+		}
+		if limit < 1 || limit > maxSyncLimit {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("limit must be between 1 and %d", maxSyncLimit)))
+			return
+		}
+
+		user, err := getUser(r.Context(), storage, userId)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		latest, err := sync.ChangeSeq(r.Context())
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if seq > latest {
+			response.WriteJson(w, http.StatusGone, response.GeneralError(fmt.Errorf("change token is no longer valid, sync again without a token")))
+			return
+		}
+
+		results := []types.SyncResult{}
+		if len(req.Mutations) > 0 {
+			results, err = sync.ApplySync(r.Context(), userId, req.Mutations)
+			if err != nil {
+				response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+				return
+			}
+			publishSyncResults(bus, userId, results)
+		}
+
+		changes, err := sync.ChangesSince(r.Context(), userId, seq, limit)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		logger.FromContext(r.Context()).Info("tasks synced", slog.Int64("userId", userId), slog.Int("mutations", len(req.Mutations)),
+			slog.Int("changed", len(changes.Tasks)), slog.Int("deleted", len(changes.Deleted)), slog.Bool("more", changes.More))
+		response.WriteJson(w, http.StatusOK, syncResponse{
+			Token:   encodeSyncToken(changes.Seq),
+			More:    changes.More,
+			User:    user,
+			Tasks:   changes.Tasks,
+			Deleted: changes.Deleted,
+			Results: results,
+		})
+	}
+}
+
+// publishSyncResults announces applied mutations like the matching task
+// operations would: an update event for edited fields and a completion
+// event when the completion state changed
+func publishSyncResults(bus *events.Bus, userId int64, results []types.SyncResult) {
+	for _, result := range results {
+		var task *types.TaskMetaData
+		if result.Task != nil {
+			task = &result.Task.TaskMetaData
+		}
+		switch result.Status {
+		case "created":
+			bus.Publish(events.Event{Type: events.TaskCreated, UserId: userId, TaskId: result.TaskId, Task: task})
+		case "deleted":
+			bus.Publish(events.Event{Type: events.TaskDeleted, UserId: userId, TaskId: result.TaskId})
+		case "updated":
+			if slices.ContainsFunc(result.AppliedFields, func(field string) bool { return field != "completed" }) {
+				bus.Publish(events.Event{Type: events.TaskUpdated, UserId: userId, TaskId: result.TaskId, Task: task})
+			}
+			if slices.Contains(result.AppliedFields, "completed") {
+				eventType := events.TaskIncomplete
+				if task.Completed {
+					eventType = events.TaskCompleted
+				}
+				bus.Publish(events.Event{Type: eventType, UserId: userId, TaskId: result.TaskId, Task: task})
+			}
+		}
+	}
+}
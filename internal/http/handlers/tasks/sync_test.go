@@ -0,0 +1,82 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/srmty09/Todo-App/internal/events"
+)
+
+func TestSyncHandler(t *testing.T) {
+	store := newTestStore(t)
+	userId, err := store.CreateUser(context.Background(), "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := events.NewBus(16, 16)
+	defer bus.Close()
+	var mu sync.Mutex
+	var published []string
+	bus.Listen(func(ev events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, ev.Type)
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v2/users/{id}/sync", Sync(store, store, bus))
+	path := fmt.Sprintf("/api/v2/users/%d/sync", userId)
+
+	rec := do(t, mux, "POST", path, map[string]interface{}{
+		"mutations": []map[string]interface{}{
+			{"op": "upsert", "client_id": "c-1", "title": "t", "description": "d", "priority": "low", "updated_at": "2026-01-01T00:00:00Z"},
+			{"op": "upsert", "client_id": "c-2", "title": "t", "priority": "nope", "updated_at": "2026-01-01T00:00:00Z"},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sync: %d %s", rec.Code, rec.Body)
+	}
+	var res syncResponse
+	decode(t, rec, &res)
+	if len(res.Results) != 2 || res.Results[0].Status != "created" || res.Results[1].Status != "invalid" {
+		t.Errorf("results: %+v", res.Results)
+	}
+	if len(res.Tasks) != 1 || res.Tasks[0].ClientId != "c-1" || res.Token == "" || res.User == nil {
+		t.Errorf("changes: %+v", res)
+	}
+	mu.Lock()
+	if len(published) != 1 || published[0] != events.TaskCreated {
+		t.Errorf("published %v, want one %s", published, events.TaskCreated)
+	}
+	mu.Unlock()
+
+	// The token picks up where the last sync ended
+	decode(t, do(t, mux, "POST", path, map[string]string{"token": res.Token}), &res)
+	if len(res.Tasks) != 0 || len(res.Deleted) != 0 {
+		t.Errorf("changes after the token: %+v", res)
+	}
+
+	for _, tc := range []struct {
+		name string
+		path string
+		body interface{}
+		want int
+	}{
+		{"garbled token", path, map[string]string{"token": "not a token"}, http.StatusBadRequest},
+		{"token from the future", path, map[string]string{"token": encodeSyncToken(1 << 40)}, http.StatusGone},
+		{"limit too large", path, map[string]int{"limit": maxSyncLimit + 1}, http.StatusBadRequest},
+		{"unknown user", "/api/v2/users/999/sync", map[string]string{}, http.StatusNotFound},
+	} {
+		if rec := do(t, mux, "POST", tc.path, tc.body); rec.Code != tc.want {
+			t.Errorf("%s: %d %s, want %d", tc.name, rec.Code, rec.Body, tc.want)
+		}
+	}
+
+	// A failing database is not a missing user
+	store.Close()
+	if rec := do(t, mux, "POST", path, map[string]string{}); rec.Code != http.StatusInternalServerError {
+		t.Errorf("with the database closed: %d %s, want 500", rec.Code, rec.Body)
+	}
+}
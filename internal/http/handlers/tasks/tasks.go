@@ -1,21 +1,34 @@
 package tasks
 
 import (
-	"encoding/json"
-	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
-	"time"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/srmty09/Todo-App/internal/storage"
 	"github.com/srmty09/Todo-App/internal/types"
 	"github.com/srmty09/Todo-App/internal/utils/helpers"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
 	"github.com/srmty09/Todo-App/internal/utils/response"
 )
 
+// The v1 handlers share the task logic of v2.go and only keep the request
+// and response shapes of v1.
+
+// emptyListMessage is what v1 says when the filters match no task
+func emptyListMessage(status string, keyword string) string {
+	switch {
+	case keyword != "" && status != "":
+		return fmt.Sprintf("No %s tasks found matching '%s'", status, keyword)
+	case keyword != "":
+		return "No task found"
+	case status == "completed":
+		return "No completed tasks found"
+	case status == "incomplete" || status == "incompleted":
+		return "All tasks completed!"
+	}
+	return ""
+}
 
 func Add(storage storage.Storage) http.HandlerFunc{
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -24,42 +37,21 @@ func Add(storage storage.Storage) http.HandlerFunc{
 			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
 			return
 		} 
-		slog.Info("Adding new task to user",slog.Int64("userId", userId))
+		logger.FromContext(r.Context()).Info("Adding new task to user",slog.Int64("userId", userId))
 		var task types.TaskMetaData
-		err = json.NewDecoder(r.Body).Decode(&task)
-		if errors.Is(err,io.EOF){
-			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(fmt.Errorf("empty body")))
-			return
-		}
-		if err != nil{
-			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
-			return
-		}
-		validate := validator.New()
-		if err := validate.Struct(task);err!=nil{
-			validateErrs := err.(validator.ValidationErrors)
-			response.WriteJson(w,http.StatusBadRequest,response.ValidationError(validateErrs))
-			return 
-		}
-		// Check if user exists
-		exists,err := storage.UserExists(userId)
-		if err != nil{
-			response.WriteJson(w,http.StatusInternalServerError,response.GeneralError(err))
-			return
-		}
-		if !exists{
-			response.WriteJson(w,http.StatusNotFound,response.GeneralError(fmt.Errorf("user with id %d does not exist",userId)))
+		if err := decodeBody(r, &task); err != nil{
+			writeError(w,err)
 			return
 		}
-		lastId,err := storage.AddNewTask(userId,task.Title,task.Description,task.Priority,task.Completed,time.Now(),time.Now())
+		created,err := createTask(r.Context(), storage, userId, task)
 		if err != nil{
-			response.WriteJson(w,http.StatusInternalServerError,response.GeneralError(err))
+			writeError(w,err)
 			return
 		}
-		slog.Info("task added successfully",slog.String("taskId",fmt.Sprint(lastId)))
+		logger.FromContext(r.Context()).Info("task added successfully",slog.String("taskId",fmt.Sprint(created.Id)))
 		response.WriteJson(w,http.StatusCreated,map[string]interface{}{
 			"status": "OK",
-			"id": lastId,
+			"id": created.Id,
 		})
 	}
 }
@@ -75,156 +67,83 @@ func GetTodo(storage storage.Storage) http.HandlerFunc{
 		
 		status := r.URL.Query().Get("status")
 		keyword := r.URL.Query().Get("search")
-		
-		slog.Info("Getting tasks for user", slog.Int64("userId", userId), slog.String("status", status))
-		
-		exist,err:= storage.UserExists(userId)
+		w.Header().Add("Vary", "Accept")
+		view, err := parseListView(r)
 		if err!= nil{
-			response.WriteJson(w,http.StatusInternalServerError,response.GeneralError(err))
+			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
 			return 
 		}
-		if !exist{
-			response.WriteJson(w,http.StatusNotFound,response.GeneralError(fmt.Errorf("user with id %d does not exist",userId)))
+		
+		logger.FromContext(r.Context()).Info("Getting tasks for user", slog.Int64("userId", userId), slog.String("status", status))
+		
+		if !requireUser(w, r, storage, userId){
 			return 
 		}
-
-	var tasks []types.TaskMetaData
-	
-	if keyword != "" && status != "" {
-		tasks, err = storage.GetTaskWithFilters(userId, "%"+keyword+"%", status)
-		if err != nil {
-			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
-			return
-		}
-		if tasks == nil {
-			response.WriteJson(w, http.StatusOK, map[string]interface{}{
-				"message": fmt.Sprintf("No %s tasks found matching '%s'", status, keyword),
-				"tasks":   []types.TaskMetaData{},
-			})
-			return
+		if view.Format != "json"{
+			writeTaskReport(w,r,storage,userId,view,status,keyword)
+			return 
 		}
-	} else if keyword != "" {
-		tasks, err = storage.GetTaskWithTitle(userId, "%"+keyword+"%")
+		tasks, err := listTasks(r.Context(), storage, userId, status, keyword)
 		if err != nil {
 			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
 			return
 		}
 		if tasks == nil {
-			response.WriteJson(w, http.StatusOK, map[string]interface{}{
-				"message": "No task found",
-				"tasks":   []types.TaskMetaData{},
-			})
-			return
-		}
-	} else {
-		switch status {
-		case "completed":
-			tasks, err = storage.GetCompletedTask(userId)
-			if err != nil {
-				response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
-				return
-			}
-			if tasks == nil {
-				response.WriteJson(w, http.StatusOK, map[string]interface{}{
-					"message": "No completed tasks found",
-					"tasks":   []types.TaskMetaData{},
-				})
-				return
-			}
-		case "incomplete", "incompleted":
-			tasks, err = storage.GetIncompletedTask(userId)
-			if err != nil {
-				response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
-				return
-			}
-			if tasks == nil {
+			if message := emptyListMessage(status, keyword); message != "" {
 				response.WriteJson(w, http.StatusOK, map[string]interface{}{
-					"message": "All tasks completed!",
+					"message": message,
 					"tasks":   []types.TaskMetaData{},
 				})
 				return
 			}
-		default:
-			tasks, err = storage.GetTaskForId(userId)
-			if err != nil {
-				response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
-				return
-			}
+			tasks = []types.TaskMetaData{}
 		}
-	}
-
 		response.WriteJson(w,http.StatusOK,tasks)
 	}
 }
 
 
-
-func CompletedTask(storage storage.Storage) http.HandlerFunc{
+// markTask is CompletedTask and IncompletedTask, which differ in the state
+// they set and the status they answer with
+func markTask(storage storage.Storage, completed bool, status string) http.HandlerFunc{
 	return func(w http.ResponseWriter, r *http.Request) {
-		userId, err := helpers.ParsePathInt64(r, "id")
+		userId, taskId, err := parseTaskPath(r)
 		if err!= nil{
 			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
 			return 
-		}	
-		taskId, err := helpers.ParsePathInt64(r, "task_id")
-		if err!=nil{
-			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
-			return 
 		}
-		slog.Info("Marking task as complete", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
-		err = storage.MarkComplete(userId, taskId)
-		if err!= nil{
-			response.WriteJson(w,http.StatusInternalServerError,response.GeneralError(err))
+		logger.FromContext(r.Context()).Info("Marking task", slog.Int64("userId", userId), slog.Int64("taskId", taskId), slog.Bool("completed", completed))
+		if _, err := updateTask(r.Context(), storage, userId, taskId, taskPatch{Completed: &completed}); err != nil{
+			writeError(w,err)
 			return 
 		}
 		response.WriteJson(w,http.StatusOK,map[string]interface{}{
-			"status": "Completed",
+			"status": status,
 		})
 	}
 }
 
 
+func CompletedTask(storage storage.Storage) http.HandlerFunc{
+	return markTask(storage, true, "Completed")
+}
+
 func IncompletedTask(storage storage.Storage) http.HandlerFunc{
-	return func(w http.ResponseWriter, r *http.Request) {
-		userId, err := helpers.ParsePathInt64(r, "id")
-		if err!= nil{
-			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
-			return 
-		}	
-		taskId, err := helpers.ParsePathInt64(r, "task_id")
-		if err!=nil{
-			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
-			return 
-		}
-		slog.Info("Marking task as incomplete", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
-		err = storage.MarkIncomplete(userId, taskId)
-		if err!= nil{
-			response.WriteJson(w,http.StatusInternalServerError,response.GeneralError(err))
-			return 
-		}
-		response.WriteJson(w,http.StatusOK,map[string]interface{}{
-			"status": "Incompleted",
-		})
-	}
+	return markTask(storage, false, "Incompleted")
 }
 
 
 func GetSingleTask(storage storage.Storage) http.HandlerFunc{
 	return func(w http.ResponseWriter, r *http.Request) {
-		userId, err := helpers.ParsePathInt64(r, "id")
+		userId, taskId, err := parseTaskPath(r)
 		if err!= nil{
 			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
 			return 
 		}
-		taskId, err := helpers.ParsePathInt64(r, "task_id")
-		if err!=nil{
-			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
-			return 
-		}
-		slog.Info("Getting single task", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
-		task,err := storage.GetSingleTask(userId, taskId)
+		logger.FromContext(r.Context()).Info("Getting single task", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
+		task,err := getTask(r.Context(), storage, userId, taskId)
 		if err!= nil{
-			response.WriteJson(w,http.StatusNotFound,response.GeneralError(err))
+			writeError(w,err)
 			return 
 		}
 		response.WriteJson(w,http.StatusOK,task)
@@ -233,20 +152,14 @@ func GetSingleTask(storage storage.Storage) http.HandlerFunc{
 
 func DeleteTask(storage storage.Storage) http.HandlerFunc{
 	return func(w http.ResponseWriter, r *http.Request) {
-		userId, err := helpers.ParsePathInt64(r, "id")
+		userId, taskId, err := parseTaskPath(r)
 		if err!= nil{
 			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
 			return 
-		}	
-		taskId, err := helpers.ParsePathInt64(r, "task_id")
-		if err!=nil{
-			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
-			return 
 		}
-		slog.Info("Deleting task", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
-		err = storage.DeletingTask(userId, taskId)
-		if err!= nil{
-			response.WriteJson(w,http.StatusInternalServerError,response.GeneralError(err))
+		logger.FromContext(r.Context()).Info("Deleting task", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
+		if err := removeTask(r.Context(), storage, userId, taskId); err!= nil{
+			writeError(w,err)
 			return 
 		}
 		response.WriteJson(w,http.StatusOK,map[string]interface{}{
@@ -256,67 +169,40 @@ func DeleteTask(storage storage.Storage) http.HandlerFunc{
 }
 
 
+// EditTask is a partial update like the v2 PATCH, except that v1 treats an
+// empty field as absent and does not change the completion state
 func EditTask(storage storage.Storage)http.HandlerFunc{
 	return  func(w http.ResponseWriter, r *http.Request) {
-		userId, err := helpers.ParsePathInt64(r, "id")
-		if err!=nil{
-			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
-			return
-		}
-		taskId, err := helpers.ParsePathInt64(r, "task_id")
+		userId, taskId, err := parseTaskPath(r)
 		if err!=nil{
 			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
 			return 
 		}
-		slog.Info("Editing task", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
-		
-		// Get existing task first
-		existingTask, err := storage.GetSingleTask(userId, taskId)
-		if err!=nil{
-			response.WriteJson(w,http.StatusNotFound,response.GeneralError(err))
-			return
-		}
+		logger.FromContext(r.Context()).Info("Editing task", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
 		
-		// Decode the update request
 		var updateRequest types.TaskMetaData
-		err = json.NewDecoder(r.Body).Decode(&updateRequest)
-		if errors.Is(err,io.EOF){
-			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(fmt.Errorf("empty body")))
-			return
-		}
-		if err!=nil{
-			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
+		if err := decodeBody(r, &updateRequest); err!=nil{
+			writeError(w,err)
 			return
 		}
-		
-		// Update only provided fields (partial update)
+		var patch taskPatch
 		if updateRequest.Title != "" {
-			existingTask.Title = updateRequest.Title
+			patch.Title = &updateRequest.Title
 		}
 		if updateRequest.Description != "" {
-			existingTask.Description = updateRequest.Description
+			patch.Description = &updateRequest.Description
 		}
 		if updateRequest.Priority != "" {
-			existingTask.Priority = updateRequest.Priority
+			patch.Priority = &updateRequest.Priority
 		}
-		
-		// Validate the final task
-		validate := validator.New()
-		if err:= validate.Struct(existingTask);err!=nil{
-			validateErrs := err.(validator.ValidationErrors)
-			response.WriteJson(w,http.StatusBadRequest,response.ValidationError(validateErrs))
-			return 
-		}
-		
-		err = storage.EditTask(userId,taskId,existingTask.Title,existingTask.Description,existingTask.Priority)
-		if err!=nil{
-			response.WriteJson(w,http.StatusInternalServerError,response.GeneralError(err))
+		if _, err := updateTask(r.Context(), storage, userId, taskId, patch); err!=nil{
+			writeError(w,err)
 			return
 		}
-		slog.Info("task edited successfully", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
+		logger.FromContext(r.Context()).Info("task edited successfully", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
 		response.WriteJson(w,http.StatusOK,map[string]interface{}{
 			"status": "Updated",
 			"message": "Task updated successfully",
 		})
 	}
-}
\ No newline at end of file
+}
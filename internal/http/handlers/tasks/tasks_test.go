@@ -0,0 +1,234 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/http/middleware"
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// v1And2 serves the v1 task routes next to the v2 ones they adapt
+func v1And2(t *testing.T) (http.Handler, int64) {
+	store := newTestStore(t)
+	userId, err := store.CreateUser(context.Background(), "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/user/{id}/add_task/", Add(store))
+	mux.HandleFunc("GET /api/user/{id}/todo/{task_id}", GetSingleTask(store))
+	mux.HandleFunc("GET /api/user/{id}/todo/", GetTodo(store))
+	mux.HandleFunc("PATCH /api/user/{id}/todo/completed/{task_id}", CompletedTask(store))
+	mux.HandleFunc("PATCH /api/user/{id}/todo/incompleted/{task_id}", IncompletedTask(store))
+	mux.HandleFunc("DELETE /api/user/{id}/todo/{task_id}", DeleteTask(store))
+	mux.HandleFunc("PATCH /api/user/{id}/todo/{task_id}", EditTask(store))
+	mux.HandleFunc("GET /api/v2/users/{id}/tasks", List(store))
+	mux.HandleFunc("GET /api/v2/users/{id}/tasks/{task_id}", Get(store))
+	return mux, userId
+}
+
+func TestV1Adapters(t *testing.T) {
+	mux, userId := v1And2(t)
+	v1 := fmt.Sprintf("/api/user/%d", userId)
+	v2 := fmt.Sprintf("/api/v2/users/%d", userId)
+
+	rec := do(t, mux, "POST", v1+"/add_task/", map[string]string{"title": "Buy milk", "description": "2 litres", "priority": "high"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add: %d %s", rec.Code, rec.Body)
+	}
+	var added struct {
+		Status string `json:"status"`
+		Id     int64  `json:"id"`
+	}
+	decode(t, rec, &added)
+	if added.Status != "OK" || added.Id == 0 {
+		t.Fatalf("add answered %s", rec.Body)
+	}
+	task := fmt.Sprintf("/todo/%d", added.Id)
+
+	// Validation is the v2 validation
+	if rec := do(t, mux, "POST", v1+"/add_task/", map[string]string{"title": "x", "description": "y", "priority": "urgent"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid priority: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, mux, "POST", "/api/user/999/add_task/", map[string]string{"title": "x", "description": "y", "priority": "low"}); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user: %d %s", rec.Code, rec.Body)
+	}
+
+	// Empty fields are left alone by a v1 edit
+	if rec := do(t, mux, "PATCH", v1+task, map[string]string{"title": "Buy oat milk"}); rec.Code != http.StatusOK {
+		t.Fatalf("edit: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, mux, "PATCH", v1+"/todo/completed/"+fmt.Sprint(added.Id), nil); rec.Code != http.StatusOK {
+		t.Fatalf("complete: %d %s", rec.Code, rec.Body)
+	}
+	var got types.TaskMetaData
+	decode(t, do(t, mux, "GET", v2+"/tasks/"+fmt.Sprint(added.Id), nil), &got)
+	if got.Title != "Buy oat milk" || got.Description != "2 litres" || got.Priority != "high" || !got.Completed {
+		t.Errorf("after v1 edit and complete, v2 sees %+v", got)
+	}
+
+	// Both versions list the same tasks
+	var v1List, v2List []types.TaskMetaData
+	decode(t, do(t, mux, "GET", v1+"/todo/?status=completed", nil), &v1List)
+	decode(t, do(t, mux, "GET", v2+"/tasks?status=completed", nil), &v2List)
+	if len(v1List) != 1 || len(v2List) != 1 || v1List[0].Id != v2List[0].Id {
+		t.Errorf("v1 lists %+v, v2 lists %+v", v1List, v2List)
+	}
+	var empty struct {
+		Message string               `json:"message"`
+		Tasks   []types.TaskMetaData `json:"tasks"`
+	}
+	decode(t, do(t, mux, "GET", v1+"/todo/?status=incomplete", nil), &empty)
+	if empty.Message != "All tasks completed!" || empty.Tasks == nil {
+		t.Errorf("empty v1 list: %+v", empty)
+	}
+
+	if rec := do(t, mux, "PATCH", v1+"/todo/incompleted/"+fmt.Sprint(added.Id), nil); rec.Code != http.StatusOK {
+		t.Fatalf("incomplete: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(t, mux, "DELETE", v1+task, nil); rec.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", rec.Code, rec.Body)
+	}
+
+	// A missing task is a 404 everywhere
+	for _, req := range []struct{ method, path string }{
+		{"GET", v1 + task},
+		{"PATCH", v1 + "/todo/completed/" + fmt.Sprint(added.Id)},
+		{"DELETE", v1 + task},
+		{"GET", v2 + "/tasks/" + fmt.Sprint(added.Id)},
+	} {
+		if rec := do(t, mux, req.method, req.path, nil); rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s on a deleted task: %d %s", req.method, req.path, rec.Code, rec.Body)
+		}
+	}
+}
+
+// brokenStore fails every single-task read the way a broken database would
+type brokenStore struct {
+	storage.Storage
+}
+
+func (brokenStore) GetSingleTask(ctx context.Context, userId int64, taskId int64) (*types.TaskMetaData, error) {
+	return nil, errors.New("disk I/O error")
+}
+
+func TestTaskErrorStatuses(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	userId, err := store.CreateUser(ctx, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskId, err := store.AddNewTask(ctx, userId, "Buy milk", "2 litres", "high", false, time.Now(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := func(s storage.Storage) *http.ServeMux {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /api/v2/users/{id}/tasks/{task_id}", Get(s))
+		mux.HandleFunc("PATCH /api/v2/users/{id}/tasks/{task_id}", Update(s))
+		mux.HandleFunc("PUT /api/v2/users/{id}/tasks/{task_id}", Replace(s))
+		mux.HandleFunc("DELETE /api/v2/users/{id}/tasks/{task_id}", Remove(s))
+		mux.HandleFunc("GET /api/user/{id}/todo/{task_id}", GetSingleTask(s))
+		return mux
+	}
+	replacement := map[string]interface{}{"title": "Buy oat milk", "description": "1 litre", "priority": "low", "completed": true}
+
+	// A replace is a full update, completion included
+	var replaced types.TaskMetaData
+	rec := do(t, routes(store), "PUT", fmt.Sprintf("/api/v2/users/%d/tasks/%d", userId, taskId), replacement)
+	decode(t, rec, &replaced)
+	if rec.Code != http.StatusOK || replaced.Title != "Buy oat milk" || replaced.Priority != "low" || !replaced.Completed {
+		t.Fatalf("replace: %d %s", rec.Code, rec.Body)
+	}
+	rec = do(t, routes(store), "PUT", fmt.Sprintf("/api/v2/users/%d/tasks/%d", userId, taskId), map[string]string{"title": "x"})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid replace: %d %s", rec.Code, rec.Body)
+	}
+
+	// Only a task that is not there is a 404; a failing database is a 500
+	for _, tc := range []struct {
+		store  storage.Storage
+		taskId int64
+		status int
+	}{
+		{store, taskId + 100, http.StatusNotFound},
+		{brokenStore{store}, taskId, http.StatusInternalServerError},
+	} {
+		mux := routes(tc.store)
+		for _, req := range []struct {
+			method, path string
+			body         interface{}
+		}{
+			{"GET", fmt.Sprintf("/api/v2/users/%d/tasks/%d", userId, tc.taskId), nil},
+			{"PATCH", fmt.Sprintf("/api/v2/users/%d/tasks/%d", userId, tc.taskId), map[string]string{"title": "x"}},
+			{"PUT", fmt.Sprintf("/api/v2/users/%d/tasks/%d", userId, tc.taskId), replacement},
+			{"DELETE", fmt.Sprintf("/api/v2/users/%d/tasks/%d", userId, tc.taskId), nil},
+			{"GET", fmt.Sprintf("/api/user/%d/todo/%d", userId, tc.taskId), nil},
+		} {
+			if rec := do(t, mux, req.method, req.path, req.body); rec.Code != tc.status {
+				t.Errorf("%s %s: %d %s, want %d", req.method, req.path, rec.Code, rec.Body, tc.status)
+			}
+		}
+	}
+}
+
+func TestBodyTooLarge(t *testing.T) {
+	store := newTestStore(t)
+	userId, err := store.CreateUser(context.Background(), "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taskId, err := store.AddNewTask(context.Background(), userId, "Buy milk", "2 litres", "high", false, time.Now(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v2/users/{id}/tasks", Create(store))
+	mux.HandleFunc("PATCH /api/v2/users/{id}/tasks/{task_id}", Update(store))
+	mux.HandleFunc("POST /api/user/{id}/add_task/", Add(store))
+	mux.HandleFunc("POST /api/v2/users/{id}/tasks/import", ImportCSV(store, store))
+	handler := middleware.MaxBodySize(256)(mux)
+
+	long := strings.Repeat("x", 512)
+	for _, tc := range []struct {
+		method, path string
+		body         interface{}
+		status       int
+	}{
+		{"POST", fmt.Sprintf("/api/v2/users/%d/tasks", userId), map[string]string{"title": "Buy milk", "description": long, "priority": "high"}, http.StatusRequestEntityTooLarge},
+		{"PATCH", fmt.Sprintf("/api/v2/users/%d/tasks/%d", userId, taskId), map[string]string{"description": long}, http.StatusRequestEntityTooLarge},
+		{"POST", fmt.Sprintf("/api/user/%d/add_task/", userId), map[string]string{"title": "Buy milk", "description": long, "priority": "high"}, http.StatusRequestEntityTooLarge},
+		{"POST", fmt.Sprintf("/api/v2/users/%d/tasks/import", userId), "title,description\nBuy milk," + long + "\n", http.StatusRequestEntityTooLarge},
+		// Under the limit a broken body is still a bad request
+		{"PATCH", fmt.Sprintf("/api/v2/users/%d/tasks/%d", userId, taskId), "{", http.StatusBadRequest},
+	} {
+		var body io.Reader
+		switch b := tc.body.(type) {
+		case string:
+			body = strings.NewReader(b)
+		default:
+			encoded, err := json.Marshal(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body = bytes.NewReader(encoded)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, body))
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: %d %s, want %d", tc.method, tc.path, rec.Code, rec.Body, tc.status)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/taskio"
+	"github.com/srmty09/Todo-App/internal/types"
+	"github.com/srmty09/Todo-App/internal/utils/helpers"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// DueStore sets task due dates, which the Storage interface does not cover
+type DueStore interface {
+	SetTaskDue(ctx context.Context, userId int64, taskId int64, due *time.Time, updatedAt time.Time) error
+}
+
+// requireUser answers 404 unless the user exists, and reports whether the
+// request may go on
+func requireUser(w http.ResponseWriter, r *http.Request, storage storage.Storage, userId int64) bool {
+	exists, err := storage.UserExists(r.Context(), userId)
+	if err != nil {
+		response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+		return false
+	}
+	if !exists {
+		response.WriteJson(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("user with id %d does not exist", userId)))
+		return false
+	}
+	return true
+}
+
+// parseDryRun reads the dry_run query parameter
+func parseDryRun(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("dry_run")
+	if value == "" {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("dry_run must be true or false")
+	}
+	return dryRun, nil
+}
+
+// importTasks validates every row with the same rules as a created task and
+// stores the valid ones unless dryRun is set. Invalid rows are skipped and
+// reported; they do not stop the others. A storage error does, and comes back
+// with the report of the rows before it. Rows with a UID are matched against
+// the user's tasks through uids, nil for formats without one: a row already
+// there, or seen earlier in the file, is skipped and counted.
+func importTasks(ctx context.Context, storage storage.Storage, dues DueStore, uids UIDStore, userId int64, rows []taskio.Row, dryRun bool) (*types.ImportReport, error) {
+	report := &types.ImportReport{DryRun: dryRun, Rows: len(rows), Errors: []types.ImportError{}}
+	validate := validator.New()
+	now := time.Now()
+	seen := make(map[string]bool)
+	var instance string
+	if uids != nil {
+		var err error
+		if instance, err = uids.InstanceId(ctx); err != nil {
+			return report, err
+		}
+	}
+	for _, row := range rows {
+		// A row that could not be read at all has nothing to validate
+		unreadable := len(row.Errors) > 0 && row.Task == (types.TaskMetaData{})
+		if err := validate.Struct(row.Task); err != nil && !unreadable {
+			row.Errors = append(row.Errors, strings.Split(response.ValidationError(err.(validator.ValidationErrors)).Error, ", ")...)
+		}
+		if len(row.Errors) > 0 {
+			report.Errors = append(report.Errors, types.ImportError{Row: row.Line, Errors: row.Errors})
+			continue
+		}
+		if uids != nil && row.UID != "" {
+			// Only a UID this instance gave out names a task by its id
+			var taskId int64
+			if id, ours := taskio.ParseTaskUID(row.UID, instance); ours {
+				taskId = id
+			}
+			_, found, err := uids.TaskForUID(ctx, userId, taskId, row.UID)
+			if err != nil {
+				return report, err
+			}
+			if found || seen[row.UID] {
+				report.Skipped++
+				continue
+			}
+			seen[row.UID] = true
+		}
+		report.Valid++
+		if dryRun {
+			continue
+		}
+
+		task := row.Task
+		if task.CreatedAt.IsZero() {
+			task.CreatedAt = now
+		}
+		if task.UpdatedAt.IsZero() || task.UpdatedAt.Before(task.CreatedAt) {
+			task.UpdatedAt = task.CreatedAt
+		}
+		taskId, err := storage.AddNewTask(ctx, userId, task.Title, task.Description, task.Priority, task.Completed, task.CreatedAt, task.UpdatedAt)
+		if err != nil {
+			return report, err
+		}
+		if task.DueAt != nil {
+			if err := dues.SetTaskDue(ctx, userId, taskId, task.DueAt, task.UpdatedAt); err != nil {
+				return report, err
+			}
+		}
+		if uids != nil && row.UID != "" {
+			if err := uids.SetTaskUID(ctx, userId, taskId, row.UID); err != nil {
+				return report, err
+			}
+		}
+		report.Imported++
+		report.TaskIds = append(report.TaskIds, taskId)
+	}
+	return report, nil
+}
+
+// writeImportFailure answers 500 for an import stopped by a storage error.
+// Rows are stored one by one, so the report is sent along: the tasks it
+// lists were imported and a retry would add them again.
+func writeImportFailure(w http.ResponseWriter, r *http.Request, userId int64, format string, report *types.ImportReport, err error) {
+	logger.FromContext(r.Context()).Error("task import failed", slog.Int64("userId", userId), slog.String("format", format),
+		slog.Int("imported", report.Imported), slog.Any("error", err))
+	report.Error = err.Error()
+	response.WriteJson(w, http.StatusInternalServerError, report)
+}
+
+// writeImportReport answers with the report: 200 for a dry run or when every
+// usable row was already there, 201 when tasks were stored and 422 when no
+// row was usable
+func writeImportReport(w http.ResponseWriter, r *http.Request, userId int64, format string, report *types.ImportReport) {
+	logger.FromContext(r.Context()).Info("tasks imported", slog.Int64("userId", userId), slog.String("format", format),
+		slog.Bool("dryRun", report.DryRun), slog.Int("rows", report.Rows), slog.Int("imported", report.Imported), slog.Int("skipped", report.Skipped))
+	switch {
+	case report.DryRun:
+		response.WriteJson(w, http.StatusOK, report)
+	case report.Imported > 0:
+		response.WriteJson(w, http.StatusCreated, report)
+	case report.Skipped > 0:
+		response.WriteJson(w, http.StatusOK, report)
+	default:
+		response.WriteJson(w, http.StatusUnprocessableEntity, report)
+	}
+}
+
+// ExportCSV handles GET /api/v2/users/{id}/tasks/export.csv and its v1
+// counterpart, sending every task of the user as CSV in list order
+func ExportCSV(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		if !requireUser(w, r, storage, userId) {
+			return
+		}
+		tasks, err := storage.GetTaskForId(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"tasks-%d.csv\"", userId))
+		if err := taskio.WriteCSV(w, tasks); err != nil {
+			// The status line is already out; all that is left is to log
+			logger.FromContext(r.Context()).Error("csv export failed", slog.Int64("userId", userId), slog.Any("error", err))
+		}
+	}
+}
+
+// ImportCSV handles POST /api/v2/users/{id}/tasks/import and its v1
+// counterpart. The body is CSV with a header row; columns are matched to
+// task fields by name, and map=column:field parameters name the rest (a
+// field of "-" skips the column). With dry_run=true rows are only checked.
+func ImportCSV(storage storage.Storage, dues DueStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		dryRun, err := parseDryRun(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		mapping := make(map[string]string)
+		for _, pair := range r.URL.Query()["map"] {
+			column, field, ok := strings.Cut(pair, ":")
+			if !ok || column == "" {
+				response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("map must look like column:field, got %q", pair)))
+				return
+			}
+			mapping[column] = field
+		}
+		if !requireUser(w, r, storage, userId) {
+			return
+		}
+
+		rows, ignored, err := taskio.ReadCSV(r.Body, mapping)
+		if err != nil {
+			response.WriteJson(w, helpers.BodyErrorStatus(err), response.GeneralError(fmt.Errorf("csv: %w", err)))
+			return
+		}
+		report, err := importTasks(r.Context(), storage, dues, nil, userId, rows, dryRun)
+		report.IgnoredColumns = ignored
+		if err != nil {
+			writeImportFailure(w, r, userId, "csv", report, err)
+			return
+		}
+		writeImportReport(w, r, userId, "csv", report)
+	}
+}
+
+// ExportTodoTxt handles GET /api/v2/users/{id}/tasks/export.txt, sending
+// every task of the user in todo.txt format
+func ExportTodoTxt(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		if !requireUser(w, r, storage, userId) {
+			return
+		}
+		tasks, err := storage.GetTaskForId(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"todo-%d.txt\"", userId))
+		if err := taskio.WriteTodoTxt(w, tasks); err != nil {
+			// The status line is already out; all that is left is to log
+			logger.FromContext(r.Context()).Error("todo.txt export failed", slog.Int64("userId", userId), slog.Any("error", err))
+		}
+	}
+}
+
+// ImportTodoTxt handles POST /api/v2/users/{id}/tasks/import.txt. Every
+// line of the todo.txt body becomes a task, validated like a created one.
+// With dry_run=true lines are only checked.
+func ImportTodoTxt(storage storage.Storage, dues DueStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		dryRun, err := parseDryRun(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		if !requireUser(w, r, storage, userId) {
+			return
+		}
+
+		rows, err := taskio.ReadTodoTxt(r.Body)
+		if err != nil {
+			response.WriteJson(w, helpers.BodyErrorStatus(err), response.GeneralError(fmt.Errorf("todo.txt: %w", err)))
+			return
+		}
+		report, err := importTasks(r.Context(), storage, dues, nil, userId, rows, dryRun)
+		if err != nil {
+			writeImportFailure(w, r, userId, "todo.txt", report, err)
+			return
+		}
+		writeImportReport(w, r, userId, "todo.txt", report)
+	}
+}
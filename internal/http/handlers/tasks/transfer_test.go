@@ -0,0 +1,148 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+func TestImportCSV(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	userId, err := store.CreateUser(ctx, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v2/users/{id}/tasks/import", ImportCSV(store, store))
+	path := fmt.Sprintf("/api/v2/users/%d/tasks/import", userId)
+	post := func(query string, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", path+query, strings.NewReader(body)))
+		return rec
+	}
+	stored := func() []types.TaskMetaData {
+		tasks, err := store.GetTaskForId(ctx, userId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tasks
+	}
+
+	body := "Headline,Notes,Priority,Done,Due,Colour\n" +
+		"Buy milk,2 litres,high,no,2026-03-08,red\n" +
+		"Walk dog,around the park,urgent,no,,blue\n" +
+		"Pay rent,before the 1st,medium,yes,,green\n"
+
+	// A dry run checks every row and stores nothing
+	rec := post("?dry_run=true&map=Headline:title&map=Colour:-", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("dry run: %d %s", rec.Code, rec.Body)
+	}
+	var report types.ImportReport
+	decode(t, rec, &report)
+	if !report.DryRun || report.Rows != 3 || report.Valid != 2 || report.Imported != 0 || len(report.TaskIds) != 0 {
+		t.Errorf("dry run report: %+v", report)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Row != 3 {
+		t.Errorf("dry run errors: %+v", report.Errors)
+	}
+	if len(report.IgnoredColumns) != 1 || report.IgnoredColumns[0] != "Colour" {
+		t.Errorf("ignored columns: %v", report.IgnoredColumns)
+	}
+	if tasks := stored(); len(tasks) != 0 {
+		t.Fatalf("a dry run stored %d tasks", len(tasks))
+	}
+
+	// The same file for real stores the valid rows, with their due dates
+	rec = post("?map=Headline:title", body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("import: %d %s", rec.Code, rec.Body)
+	}
+	report = types.ImportReport{}
+	decode(t, rec, &report)
+	if report.DryRun || report.Valid != 2 || report.Imported != 2 || len(report.TaskIds) != 2 {
+		t.Errorf("import report: %+v", report)
+	}
+	if !slices.Equal(report.IgnoredColumns, []string{"Colour"}) {
+		t.Errorf("ignored columns without skipping Colour: %v", report.IgnoredColumns)
+	}
+	tasks := stored()
+	if len(tasks) != 2 {
+		t.Fatalf("stored %d tasks, want 2", len(tasks))
+	}
+	byTitle := make(map[string]types.TaskMetaData)
+	for _, task := range tasks {
+		byTitle[task.Title] = task
+	}
+	if milk := byTitle["Buy milk"]; milk.DueAt == nil || milk.DueAt.Format("2006-01-02") != "2026-03-08" || milk.Priority != "high" {
+		t.Errorf("Buy milk stored as %+v", milk)
+	}
+	if rent := byTitle["Pay rent"]; !rent.Completed {
+		t.Errorf("Pay rent stored as %+v", rent)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		query string
+		body  string
+		want  int
+	}{
+		{"no usable row", "", "title,priority\nx,urgent\n", http.StatusUnprocessableEntity},
+		{"no title column", "", "headline\nx\n", http.StatusBadRequest},
+		{"malformed mapping", "?map=title", body, http.StatusBadRequest},
+		{"malformed dry_run", "?dry_run=maybe", body, http.StatusBadRequest},
+	} {
+		if rec := post(tc.query, tc.body); rec.Code != tc.want {
+			t.Errorf("%s: %d %s, want %d", tc.name, rec.Code, rec.Body, tc.want)
+		}
+	}
+	if tasks := stored(); len(tasks) != 2 {
+		t.Errorf("failed imports left %d tasks, want 2", len(tasks))
+	}
+}
+
+// fullStore stores the first tasks it is given, then fails like a full disk
+type fullStore struct {
+	storage.Storage
+	room int
+}
+
+func (s *fullStore) AddNewTask(ctx context.Context, userId int64, title string, description string, priority string, completed bool, createdAt time.Time, updatedAt time.Time) (int64, error) {
+	if s.room == 0 {
+		return 0, errors.New("database or disk is full")
+	}
+	s.room--
+	return s.Storage.AddNewTask(ctx, userId, title, description, priority, completed, createdAt, updatedAt)
+}
+
+func TestImportStoppedByStorage(t *testing.T) {
+	store := newTestStore(t)
+	userId, err := store.CreateUser(context.Background(), "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v2/users/{id}/tasks/import.txt", ImportTodoTxt(&fullStore{Storage: store, room: 1}, store))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", fmt.Sprintf("/api/v2/users/%d/tasks/import.txt", userId), strings.NewReader("Buy milk\nWalk dog\nPay rent\n")))
+
+	// The client learns which task made it in before the failure
+	var report types.ImportReport
+	decode(t, rec, &report)
+	if rec.Code != http.StatusInternalServerError || report.Error != "database or disk is full" || report.Imported != 1 || len(report.TaskIds) != 1 {
+		t.Fatalf("%d %+v", rec.Code, report)
+	}
+	if _, err := store.GetSingleTask(context.Background(), userId, report.TaskIds[0]); err != nil {
+		t.Errorf("reported task: %v", err)
+	}
+}
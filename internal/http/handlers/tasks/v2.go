@@ -0,0 +1,370 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/types"
+	"github.com/srmty09/Todo-App/internal/utils/helpers"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// taskPatch holds the fields of a partial update; nil fields are left untouched
+type taskPatch struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Priority    *string `json:"priority"`
+	Completed   *bool   `json:"completed"`
+}
+
+// decodeBody decodes a JSON request body, reporting an empty body explicitly.
+// Its errors carry the status to answer with, for writeError.
+func decodeBody(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if errors.Is(err, io.EOF) {
+		err = fmt.Errorf("empty body")
+	}
+	if err != nil {
+		return &statusError{status: helpers.BodyErrorStatus(err), err: err}
+	}
+	return nil
+}
+
+// taskLocation is the canonical v2 URL of a task
+func taskLocation(userId int64, taskId int64) string {
+	return fmt.Sprintf("/api/v2/users/%d/tasks/%d", userId, taskId)
+}
+
+// listTasks picks the storage query matching the status and search filters
+func listTasks(ctx context.Context, storage storage.Storage, userId int64, status string, keyword string) ([]types.TaskMetaData, error) {
+	switch {
+	case keyword != "" && status != "":
+		return storage.GetTaskWithFilters(ctx, userId, "%"+keyword+"%", status)
+	case keyword != "":
+		return storage.GetTaskWithTitle(ctx, userId, "%"+keyword+"%")
+	case status == "completed":
+		return storage.GetCompletedTask(ctx, userId)
+	case status == "incomplete" || status == "incompleted":
+		return storage.GetIncompletedTask(ctx, userId)
+	default:
+		return storage.GetTaskForId(ctx, userId)
+	}
+}
+
+// setCompleted moves a task to the requested completion state
+func setCompleted(ctx context.Context, storage storage.Storage, userId int64, taskId int64, completed bool) error {
+	if completed {
+		return storage.MarkComplete(ctx, userId, taskId)
+	}
+	return storage.MarkIncomplete(ctx, userId, taskId)
+}
+
+// statusError is a failed task operation together with the HTTP status it
+// maps to
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string { return e.err.Error() }
+
+func (e *statusError) Unwrap() error { return e.err }
+
+func notFound(err error) error {
+	return &statusError{status: http.StatusNotFound, err: err}
+}
+
+// taskNotFound marks a task that is not there as a not found error; any
+// other error is a storage failure and is returned as it is
+func taskNotFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound(err)
+	}
+	return err
+}
+
+// writeError answers with the status a task operation error maps to:
+// validation failures are bad requests, unclassified errors storage failures
+func writeError(w http.ResponseWriter, err error) {
+	var invalid validator.ValidationErrors
+	var withStatus *statusError
+	switch {
+	case errors.As(err, &invalid):
+		response.WriteJson(w, http.StatusBadRequest, response.ValidationError(invalid))
+	case errors.As(err, &withStatus):
+		response.WriteJson(w, withStatus.status, response.GeneralError(withStatus.err))
+	default:
+		response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+	}
+}
+
+// createTask validates a new task and stores it for an existing user,
+// returning the task as stored
+func createTask(ctx context.Context, storage storage.Storage, userId int64, task types.TaskMetaData) (*types.TaskMetaData, error) {
+	if err := validator.New().Struct(task); err != nil {
+		return nil, err
+	}
+	exists, err := storage.UserExists(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, notFound(fmt.Errorf("user with id %d does not exist", userId))
+	}
+	now := time.Now()
+	taskId, err := storage.AddNewTask(ctx, userId, task.Title, task.Description, task.Priority, task.Completed, now, now)
+	if err != nil {
+		return nil, err
+	}
+	return storage.GetSingleTask(ctx, userId, taskId)
+}
+
+// updateTask changes only the fields present in patch, including the
+// completion state, and returns the updated task
+func updateTask(ctx context.Context, storage storage.Storage, userId int64, taskId int64, patch taskPatch) (*types.TaskMetaData, error) {
+	existing, err := storage.GetSingleTask(ctx, userId, taskId)
+	if err != nil {
+		return nil, taskNotFound(err)
+	}
+
+	edited := false
+	if patch.Title != nil {
+		existing.Title = *patch.Title
+		edited = true
+	}
+	if patch.Description != nil {
+		existing.Description = *patch.Description
+		edited = true
+	}
+	if patch.Priority != nil {
+		existing.Priority = *patch.Priority
+		edited = true
+	}
+	if err := validator.New().Struct(existing); err != nil {
+		return nil, err
+	}
+
+	if edited {
+		if err := storage.EditTask(ctx, userId, taskId, existing.Title, existing.Description, existing.Priority); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Completed != nil && *patch.Completed != existing.Completed {
+		if err := setCompleted(ctx, storage, userId, taskId, *patch.Completed); err != nil {
+			return nil, err
+		}
+	}
+	return storage.GetSingleTask(ctx, userId, taskId)
+}
+
+// getUser reads a user; only a user that is not there is a not found error
+func getUser(ctx context.Context, storage storage.Storage, userId int64) (*types.User, error) {
+	user, err := storage.GetUser(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound(fmt.Errorf("user with id %d does not exist", userId))
+	}
+	return user, err
+}
+
+// getTask reads one task of the user
+func getTask(ctx context.Context, storage storage.Storage, userId int64, taskId int64) (*types.TaskMetaData, error) {
+	task, err := storage.GetSingleTask(ctx, userId, taskId)
+	if err != nil {
+		return nil, taskNotFound(err)
+	}
+	return task, nil
+}
+
+// removeTask deletes a task of the user
+func removeTask(ctx context.Context, storage storage.Storage, userId int64, taskId int64) error {
+	if _, err := storage.GetSingleTask(ctx, userId, taskId); err != nil {
+		return taskNotFound(err)
+	}
+	return storage.DeletingTask(ctx, userId, taskId)
+}
+
+// parseTaskPath extracts the user and task ids of a task resource
+func parseTaskPath(r *http.Request) (int64, int64, error) {
+	userId, err := helpers.ParsePathInt64(r, "id")
+	if err != nil {
+		return 0, 0, err
+	}
+	taskId, err := helpers.ParsePathInt64(r, "task_id")
+	if err != nil {
+		return 0, 0, err
+	}
+	return userId, taskId, nil
+}
+
+// Create handles POST /api/v2/users/{id}/tasks and returns the created task
+func Create(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		var task types.TaskMetaData
+		if err := decodeBody(r, &task); err != nil {
+			writeError(w, err)
+			return
+		}
+		created, err := createTask(r.Context(), storage, userId, task)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		logger.FromContext(r.Context()).Info("task created", slog.Int64("userId", userId), slog.Int64("taskId", created.Id))
+		w.Header().Set("Location", taskLocation(userId, created.Id))
+		response.WriteJson(w, http.StatusCreated, created)
+	}
+}
+
+// List handles GET /api/v2/users/{id}/tasks. It always answers with an array,
+// empty when nothing matches the filters, unless a Markdown checklist or HTML
+// page is asked for with the format parameter or the Accept header.
+func List(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		status := r.URL.Query().Get("status")
+		keyword := r.URL.Query().Get("search")
+		if status != "" && status != "completed" && status != "incomplete" {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("status must be one of completed, incomplete")))
+			return
+		}
+		w.Header().Add("Vary", "Accept")
+		view, err := parseListView(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		exists, err := storage.UserExists(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if !exists {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("user with id %d does not exist", userId)))
+			return
+		}
+		if view.Format != "json" {
+			writeTaskReport(w, r, storage, userId, view, status, keyword)
+			return
+		}
+		tasks, err := listTasks(r.Context(), storage, userId, status, keyword)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if tasks == nil {
+			tasks = []types.TaskMetaData{}
+		}
+		response.WriteJson(w, http.StatusOK, tasks)
+	}
+}
+
+// Get handles GET /api/v2/users/{id}/tasks/{task_id}
+func Get(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, taskId, err := parseTaskPath(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		task, err := getTask(r.Context(), storage, userId, taskId)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		response.WriteJson(w, http.StatusOK, task)
+	}
+}
+
+// Update handles PATCH /api/v2/users/{id}/tasks/{task_id}. Only the fields
+// present in the body are changed, including the completion state.
+func Update(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, taskId, err := parseTaskPath(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		var patch taskPatch
+		if err := decodeBody(r, &patch); err != nil {
+			writeError(w, err)
+			return
+		}
+		updated, err := updateTask(r.Context(), storage, userId, taskId, patch)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		logger.FromContext(r.Context()).Info("task updated", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
+		response.WriteJson(w, http.StatusOK, updated)
+	}
+}
+
+// Replace handles PUT /api/v2/users/{id}/tasks/{task_id}. The body is the
+// complete task; an omitted completed field means the task is not completed.
+func Replace(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, taskId, err := parseTaskPath(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		var task types.TaskMetaData
+		if err := decodeBody(r, &task); err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := validator.New().Struct(task); err != nil {
+			writeError(w, err)
+			return
+		}
+		// A replace is a patch of every field
+		replaced, err := updateTask(r.Context(), storage, userId, taskId, taskPatch{
+			Title:       &task.Title,
+			Description: &task.Description,
+			Priority:    &task.Priority,
+			Completed:   &task.Completed,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		logger.FromContext(r.Context()).Info("task replaced", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
+		response.WriteJson(w, http.StatusOK, replaced)
+	}
+}
+
+// Remove handles DELETE /api/v2/users/{id}/tasks/{task_id}
+func Remove(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, taskId, err := parseTaskPath(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		if err := removeTask(r.Context(), storage, userId, taskId); err != nil {
+			writeError(w, err)
+			return
+		}
+		logger.FromContext(r.Context()).Info("task removed", slog.Int64("userId", userId), slog.Int64("taskId", taskId))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,219 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/srmty09/Todo-App/internal/events"
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/types"
+	"github.com/srmty09/Todo-App/internal/utils/helpers"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// ArchiveStore reads and restores complete account archives
+type ArchiveStore interface {
+	ExportArchive(ctx context.Context, userId int64) (*types.Archive, error)
+	ImportArchive(ctx context.Context, userId int64, archive *types.Archive, conflict string, dryRun bool) (*types.ArchiveImport, error)
+}
+
+const (
+	archiveFormat  = "todo-app/archive"
+	archiveVersion = 1
+
+	// maxArchiveErrors caps how many invalid tasks a rejected import lists
+	maxArchiveErrors = 20
+)
+
+// archiveConflicts are the ways an import can treat a task that is already there
+var archiveConflicts = []string{"skip", "replace", "duplicate"}
+
+// emailTaken reports whether an import failed on a user's email address
+func emailTaken(err error) bool {
+	return errors.Is(err, storage.ErrEmailTaken)
+}
+
+// checkArchive makes sure an archive can be read by this version and that
+// everything in it would be accepted if created through the API
+func checkArchive(archive *types.Archive, newUser bool) error {
+	if archive.Format != archiveFormat {
+		return fmt.Errorf("not an account archive, format must be %q", archiveFormat)
+	}
+	if archive.Version < 1 || archive.Version > archiveVersion {
+		return fmt.Errorf("archive version %d is not supported, expected at most %d", archive.Version, archiveVersion)
+	}
+	validate := validator.New()
+	if newUser {
+		if err := validate.Struct(archive.User); err != nil {
+			return fmt.Errorf("user: %s", response.ValidationError(err.(validator.ValidationErrors)).Error)
+		}
+	}
+
+	var problems []string
+	seen := make(map[int64]bool, len(archive.Tasks))
+	for i, task := range archive.Tasks {
+		if seen[task.Id] {
+			problems = append(problems, fmt.Sprintf("tasks[%d]: id %d appears twice", i, task.Id))
+		}
+		seen[task.Id] = true
+		if err := validate.Struct(task.TaskMetaData); err != nil {
+			problems = append(problems, fmt.Sprintf("tasks[%d]: %s", i, response.ValidationError(err.(validator.ValidationErrors)).Error))
+		}
+	}
+	if len(problems) > maxArchiveErrors {
+		problems = append(problems[:maxArchiveErrors], fmt.Sprintf("and %d more", len(problems)-maxArchiveErrors))
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Export handles GET /api/v2/users/{id}/export and its v1 counterpart,
+// answering with everything stored for the user as a versioned archive
+func Export(storage storage.Storage, archives ArchiveStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		exists, err := storage.UserExists(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if !exists {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("user with id %d does not exist", userId)))
+			return
+		}
+		archive, err := archives.ExportArchive(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		archive.Format, archive.Version, archive.ExportedAt = archiveFormat, archiveVersion, time.Now().UTC()
+
+		logger.FromContext(r.Context()).Info("user exported", slog.Int64("userId", userId), slog.Int("tasks", len(archive.Tasks)))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"user-%d.json\"", userId))
+		response.WriteJson(w, http.StatusOK, archive)
+	}
+}
+
+// Import handles POST /api/v2/users/import, which recreates the archived
+// user as a new user, and POST /api/v2/users/{id}/import, which adds the
+// archived tasks to an existing user; the v1 counterparts do the same. The
+// conflict parameter (skip, replace or duplicate) says what to do with a
+// task that is already there, and dry_run=true only reports what would
+// happen. The archive is restored completely or not at all; once it is,
+// every task created or replaced is announced on bus like one created or
+// edited through the API.
+func Import(storage storage.Storage, archives ArchiveStore, bus *events.Bus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var userId int64
+		newUser := r.PathValue("id") == ""
+		if !newUser {
+			var err error
+			if userId, err = helpers.ParsePathInt64(r, "id"); err != nil {
+				response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+				return
+			}
+		}
+		conflict := r.URL.Query().Get("conflict")
+		if conflict == "" {
+			conflict = "skip"
+		}
+		if !slices.Contains(archiveConflicts, conflict) {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("conflict must be one of %s", strings.Join(archiveConflicts, ", "))))
+			return
+		}
+		dryRun := false
+		if value := r.URL.Query().Get("dry_run"); value != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(value); err != nil {
+				response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("dry_run must be true or false")))
+				return
+			}
+		}
+
+		var archive types.Archive
+		err := json.NewDecoder(r.Body).Decode(&archive)
+		if errors.Is(err, io.EOF) {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("empty body")))
+			return
+		}
+		if err != nil {
+			response.WriteJson(w, helpers.BodyErrorStatus(err), response.GeneralError(err))
+			return
+		}
+		if err := checkArchive(&archive, newUser); err != nil {
+			response.WriteJson(w, http.StatusUnprocessableEntity, response.GeneralError(err))
+			return
+		}
+		if !newUser {
+			exists, err := storage.UserExists(r.Context(), userId)
+			if err != nil {
+				response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+				return
+			}
+			if !exists {
+				response.WriteJson(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("user with id %d does not exist", userId)))
+				return
+			}
+		}
+
+		result, err := archives.ImportArchive(r.Context(), userId, &archive, conflict, dryRun)
+		if emailTaken(err) {
+			response.WriteJson(w, http.StatusConflict, response.GeneralError(fmt.Errorf("%w; import into that user with POST /api/v2/users/{id}/import", err)))
+			return
+		}
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+
+		if !dryRun {
+			publishArchiveChanges(bus, result.UserId, result.Changes)
+		}
+		logger.FromContext(r.Context()).Info("user imported", slog.Int64("userId", result.UserId), slog.Bool("dryRun", dryRun),
+			slog.Int("created", result.Created), slog.Int("replaced", result.Replaced), slog.Int("skipped", result.Skipped))
+		if result.UserCreated && !dryRun {
+			w.Header().Set("Location", fmt.Sprintf("/api/v2/users/%d", result.UserId))
+			response.WriteJson(w, http.StatusCreated, result)
+			return
+		}
+		response.WriteJson(w, http.StatusOK, result)
+	}
+}
+
+// publishArchiveChanges announces restored tasks: a created event for a new
+// task, and for a replaced one an update event plus a completion event when
+// its completion state changed
+func publishArchiveChanges(bus *events.Bus, userId int64, changes []types.ArchiveChange) {
+	for _, change := range changes {
+		task := change.Task
+		if !change.Replaced {
+			bus.Publish(events.Event{Type: events.TaskCreated, UserId: userId, TaskId: task.Id, Task: &task})
+			continue
+		}
+		bus.Publish(events.Event{Type: events.TaskUpdated, UserId: userId, TaskId: task.Id, Task: &task})
+		if task.Completed != change.WasCompleted {
+			eventType := events.TaskIncomplete
+			if task.Completed {
+				eventType = events.TaskCompleted
+			}
+			bus.Publish(events.Event{Type: eventType, UserId: userId, TaskId: task.Id, Task: &task})
+		}
+	}
+}
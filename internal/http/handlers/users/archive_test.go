@@ -0,0 +1,105 @@
+package users
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/events"
+	"github.com/srmty09/Todo-App/internal/storage/sqlite"
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+func TestImportPublishesRestoredTasks(t *testing.T) {
+	cfg, err := config.Load([]string{"-storage_path", filepath.Join(t.TempDir(), "todo.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := sqlite.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	userId, err := store.CreateUser(ctx, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	existing, err := store.AddNewTask(ctx, userId, "Pay rent", "by the 1st", "high", false, created, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus := events.NewBus(16, 16)
+	defer bus.Close()
+	// Listeners run in the publisher, so the events are in once a request returns
+	var published []events.Event
+	bus.Listen(func(ev events.Event) { published = append(published, ev) })
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v2/users/{id}/import", Import(store, store, bus))
+	mux.HandleFunc("GET /api/v2/users/{id}/export", Export(store, store))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", fmt.Sprintf("/api/v2/users/%d/export", userId), nil))
+	var archive types.Archive
+	if err := json.Unmarshal(rec.Body.Bytes(), &archive); err != nil {
+		t.Fatalf("%s: %v", rec.Body, err)
+	}
+	// The existing task comes back done, next to two identical new ones
+	archive.Tasks[0].Completed = true
+	for _, id := range []int64{100, 101} {
+		task := archive.Tasks[0]
+		task.Id, task.Title, task.Completed = id, "Water plants", false
+		archive.Tasks = append(archive.Tasks, task)
+	}
+	body, err := json.Marshal(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importArchive := func(query string) types.ArchiveImport {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("POST", fmt.Sprintf("/api/v2/users/%d/import%s", userId, query), bytes.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("import%s: %d %s", query, rec.Code, rec.Body)
+		}
+		var result types.ArchiveImport
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// A dry run announces nothing
+	importArchive("?conflict=replace&dry_run=true")
+	if len(published) != 0 {
+		t.Fatalf("a dry run published %+v", published)
+	}
+	result := importArchive("?conflict=replace")
+	if result.Created != 2 || result.Replaced != 1 {
+		t.Fatalf("import: %+v", result)
+	}
+	var got []string
+	for _, ev := range published {
+		if ev.UserId != userId || ev.Task == nil || ev.Task.Id != ev.TaskId {
+			t.Errorf("event %+v", ev)
+		}
+		got = append(got, fmt.Sprintf("%s %d", ev.Type, ev.TaskId))
+	}
+	want := []string{
+		fmt.Sprintf("%s %d", events.TaskUpdated, existing),
+		fmt.Sprintf("%s %d", events.TaskCompleted, existing),
+		fmt.Sprintf("%s %d", events.TaskCreated, result.TaskIds[100]),
+		fmt.Sprintf("%s %d", events.TaskCreated, result.TaskIds[101]),
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("published %v, want %v", got, want)
+	}
+}
@@ -12,13 +12,14 @@ import (
 	"github.com/srmty09/Todo-App/internal/storage"
 	"github.com/srmty09/Todo-App/internal/types"
 	"github.com/srmty09/Todo-App/internal/utils/helpers"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
 	"github.com/srmty09/Todo-App/internal/utils/response"
 )
 
 //create new user
 func New(storage storage.Storage) http.HandlerFunc{
 	return func(w http.ResponseWriter, r *http.Request) {
-		slog.Info("creating a user")
+		logger.FromContext(r.Context()).Info("creating a user")
 		var user types.User
 		err := json.NewDecoder(r.Body).Decode(&user)
 		if errors.Is(err,io.EOF){
@@ -26,7 +27,7 @@ func New(storage storage.Storage) http.HandlerFunc{
 			return
 		}
 		if err!=nil{
-			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
+			response.WriteJson(w,helpers.BodyErrorStatus(err),response.GeneralError(err))
 			return
 		}
 		validate := validator.New()
@@ -35,12 +36,12 @@ func New(storage storage.Storage) http.HandlerFunc{
 			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(validateErrs))
 			return
 		}
-		lastId,err:=storage.CreateUser(user.Name,user.Email)
+		lastId,err:=storage.CreateUser(r.Context(), user.Name,user.Email)
 		if err!=nil{
 			response.WriteJson(w,http.StatusInternalServerError,response.GeneralError(err))
 			return
 		}
-		slog.Info("user created successfully",slog.String("userId",fmt.Sprint(lastId)))
+		logger.FromContext(r.Context()).Info("user created successfully",slog.String("userId",fmt.Sprint(lastId)))
 		response.WriteJson(w,http.StatusCreated,map[string]interface{}{
 			"status": "OK",
 			"id": lastId,
@@ -55,8 +56,8 @@ func GetUserInfo(storage storage.Storage) http.HandlerFunc{
 			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
 			return 
 		}
-	slog.Info("getting user info for", slog.Int64("userId", intId))
-	exist,err := storage.UserExists(intId)
+	logger.FromContext(r.Context()).Info("getting user info for", slog.Int64("userId", intId))
+	exist,err := storage.UserExists(r.Context(), intId)
 	if err!=nil{
 		response.WriteJson(w,http.StatusInternalServerError,response.GeneralError(err))
 		return
@@ -65,7 +66,7 @@ func GetUserInfo(storage storage.Storage) http.HandlerFunc{
 		response.WriteJson(w,http.StatusNotFound,response.GeneralError(fmt.Errorf("user with id %d does not exist", intId)))
 		return 
 	}
-		user,err := storage.GetUser(intId)
+		user,err := storage.GetUser(r.Context(), intId)
 		if err!=nil{
 			response.WriteJson(w,http.StatusInternalServerError,response.GeneralError(err))
 			return 
@@ -81,8 +82,8 @@ func DeleteUserInfo(storage storage.Storage)http.HandlerFunc{
 			response.WriteJson(w,http.StatusBadRequest,response.GeneralError(err))
 			return  // ← Missing return fixed!
 		}
-		slog.Info("deleting user with", slog.Int64("userId", userId))
-		exist,err := storage.UserExists(userId)
+		logger.FromContext(r.Context()).Info("deleting user with", slog.Int64("userId", userId))
+		exist,err := storage.UserExists(r.Context(), userId)
 		if err!= nil{
 			response.WriteJson(w,http.StatusInternalServerError,response.GeneralError(err))
 			return 
@@ -91,7 +92,7 @@ func DeleteUserInfo(storage storage.Storage)http.HandlerFunc{
 			response.WriteJson(w,http.StatusNotFound,response.GeneralError(fmt.Errorf("user with id %d does not exist",userId)))
 			return 
 		}
-		err = storage.DeleteUser(userId)
+		err = storage.DeleteUser(r.Context(), userId)
 		if err!= nil{
 			response.WriteJson(w,http.StatusInternalServerError,response.GeneralError(err))
 			return 
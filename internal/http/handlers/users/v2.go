@@ -0,0 +1,72 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/types"
+	"github.com/srmty09/Todo-App/internal/utils/helpers"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// Create handles POST /api/v2/users and returns the created user
+func Create(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var user types.User
+		err := json.NewDecoder(r.Body).Decode(&user)
+		if errors.Is(err, io.EOF) {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("empty body")))
+			return
+		}
+		if err != nil {
+			response.WriteJson(w, helpers.BodyErrorStatus(err), response.GeneralError(err))
+			return
+		}
+		if err := validator.New().Struct(user); err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(err.(validator.ValidationErrors)))
+			return
+		}
+		userId, err := storage.CreateUser(r.Context(), user.Name, user.Email)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		user.Id = userId
+		logger.FromContext(r.Context()).Info("user created", slog.Int64("userId", userId))
+		w.Header().Set("Location", fmt.Sprintf("/api/v2/users/%d", userId))
+		response.WriteJson(w, http.StatusCreated, user)
+	}
+}
+
+// Remove handles DELETE /api/v2/users/{id}, deleting the user's tasks with it
+func Remove(storage storage.Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		exists, err := storage.UserExists(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if !exists {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("user with id %d does not exist", userId)))
+			return
+		}
+		if err := storage.DeleteUser(r.Context(), userId); err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		logger.FromContext(r.Context()).Info("user removed", slog.Int64("userId", userId))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
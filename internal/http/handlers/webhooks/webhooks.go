@@ -0,0 +1,307 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/types"
+	"github.com/srmty09/Todo-App/internal/utils/helpers"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+	"github.com/srmty09/Todo-App/internal/webhooks"
+)
+
+// Store keeps webhooks and their deliveries
+type Store interface {
+	CreateWebhook(ctx context.Context, hook types.Webhook) (int64, error)
+	ListWebhooks(ctx context.Context, userId int64) ([]types.Webhook, error)
+	GetWebhook(ctx context.Context, userId int64, webhookId int64) (*types.Webhook, error)
+	DeleteWebhook(ctx context.Context, userId int64, webhookId int64) error
+	ListDeliveries(ctx context.Context, userId int64, webhookId int64, limit int) ([]types.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, userId int64, webhookId int64, deliveryId int64) (*types.WebhookDelivery, error)
+	Redeliver(ctx context.Context, userId int64, webhookId int64, deliveryId int64, at time.Time) (*types.WebhookDelivery, error)
+}
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// webhookInput is the body of a create request; active defaults to true
+type webhookInput struct {
+	types.Webhook
+	Active *bool `json:"active"`
+}
+
+// webhookLocation is the canonical URL of a webhook
+func webhookLocation(userId int64, webhookId int64) string {
+	return fmt.Sprintf("/api/v2/users/%d/webhooks/%d", userId, webhookId)
+}
+
+// parseWebhookPath extracts the user and webhook ids of a webhook resource
+func parseWebhookPath(r *http.Request) (int64, int64, error) {
+	userId, err := helpers.ParsePathInt64(r, "id")
+	if err != nil {
+		return 0, 0, err
+	}
+	webhookId, err := helpers.ParsePathInt64(r, "webhook_id")
+	if err != nil {
+		return 0, 0, err
+	}
+	return userId, webhookId, nil
+}
+
+// checkEvents makes sure every event type is known or "*"
+func checkEvents(list []string) error {
+	for _, e := range list {
+		if e != "*" && !slices.Contains(webhooks.EventTypes, e) {
+			return fmt.Errorf("unknown event type %q, expected \"*\" or one of %v", e, webhooks.EventTypes)
+		}
+	}
+	return nil
+}
+
+// newSecret generates a signing secret for webhooks created without one
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Create handles POST /api/v2/users/{id}/webhooks. The response is the only
+// place the secret is shown; one is generated when the body has none.
+func Create(storage storage.Storage, hooks Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		var input webhookInput
+		err = json.NewDecoder(r.Body).Decode(&input)
+		if errors.Is(err, io.EOF) {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("empty body")))
+			return
+		}
+		if err != nil {
+			response.WriteJson(w, helpers.BodyErrorStatus(err), response.GeneralError(err))
+			return
+		}
+		hook := input.Webhook
+		if err := validator.New().Struct(hook); err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.ValidationError(err.(validator.ValidationErrors)))
+			return
+		}
+		if err := checkEvents(hook.Events); err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+
+		exists, err := storage.UserExists(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if !exists {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("user with id %d does not exist", userId)))
+			return
+		}
+
+		if hook.Secret == "" {
+			if hook.Secret, err = newSecret(); err != nil {
+				response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+				return
+			}
+		}
+		hook.UserId = userId
+		hook.Active = input.Active == nil || *input.Active
+		hook.CreatedAt = time.Now().UTC()
+		hook.Id, err = hooks.CreateWebhook(r.Context(), hook)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		logger.FromContext(r.Context()).Info("webhook created", slog.Int64("userId", userId), slog.Int64("webhookId", hook.Id))
+		w.Header().Set("Location", webhookLocation(userId, hook.Id))
+		response.WriteJson(w, http.StatusCreated, hook)
+	}
+}
+
+// List handles GET /api/v2/users/{id}/webhooks
+func List(storage storage.Storage, hooks Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, err := helpers.ParsePathInt64(r, "id")
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		exists, err := storage.UserExists(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if !exists {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(fmt.Errorf("user with id %d does not exist", userId)))
+			return
+		}
+		list, err := hooks.ListWebhooks(r.Context(), userId)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if list == nil {
+			list = []types.Webhook{}
+		}
+		for i := range list {
+			list[i].Secret = ""
+		}
+		response.WriteJson(w, http.StatusOK, list)
+	}
+}
+
+// Get handles GET /api/v2/users/{id}/webhooks/{webhook_id}
+func Get(hooks Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, webhookId, err := parseWebhookPath(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		hook, err := hooks.GetWebhook(r.Context(), userId, webhookId)
+		if err != nil {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(err))
+			return
+		}
+		hook.Secret = ""
+		response.WriteJson(w, http.StatusOK, hook)
+	}
+}
+
+// Remove handles DELETE /api/v2/users/{id}/webhooks/{webhook_id}, dropping
+// its pending deliveries with it
+func Remove(hooks Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, webhookId, err := parseWebhookPath(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		if _, err := hooks.GetWebhook(r.Context(), userId, webhookId); err != nil {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(err))
+			return
+		}
+		if err := hooks.DeleteWebhook(r.Context(), userId, webhookId); err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		logger.FromContext(r.Context()).Info("webhook removed", slog.Int64("userId", userId), slog.Int64("webhookId", webhookId))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Deliveries handles GET /api/v2/users/{id}/webhooks/{webhook_id}/deliveries,
+// newest first. limit caps how many are returned.
+func Deliveries(hooks Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, webhookId, err := parseWebhookPath(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		limit := defaultDeliveryLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit < 1 || limit > maxDeliveryLimit {
+				response.WriteJson(w, http.StatusBadRequest, response.GeneralError(fmt.Errorf("limit must be a number between 1 and %d", maxDeliveryLimit)))
+				return
+			}
+		}
+		if _, err := hooks.GetWebhook(r.Context(), userId, webhookId); err != nil {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(err))
+			return
+		}
+		list, err := hooks.ListDeliveries(r.Context(), userId, webhookId, limit)
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		if list == nil {
+			list = []types.WebhookDelivery{}
+		}
+		response.WriteJson(w, http.StatusOK, list)
+	}
+}
+
+// parseDeliveryPath extracts the ids of a delivery resource
+func parseDeliveryPath(r *http.Request) (int64, int64, int64, error) {
+	userId, webhookId, err := parseWebhookPath(r)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	deliveryId, err := helpers.ParsePathInt64(r, "delivery_id")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return userId, webhookId, deliveryId, nil
+}
+
+// Delivery handles
+// GET /api/v2/users/{id}/webhooks/{webhook_id}/deliveries/{delivery_id},
+// including every attempt made
+func Delivery(hooks Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, webhookId, deliveryId, err := parseDeliveryPath(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		delivery, err := hooks.GetDelivery(r.Context(), userId, webhookId, deliveryId)
+		if err != nil {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(err))
+			return
+		}
+		response.WriteJson(w, http.StatusOK, delivery)
+	}
+}
+
+// Redeliver handles
+// POST /api/v2/users/{id}/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver,
+// queueing the same payload again as a new delivery. wake tells the
+// dispatcher to send it right away.
+func Redeliver(hooks Store, wake func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId, webhookId, deliveryId, err := parseDeliveryPath(r)
+		if err != nil {
+			response.WriteJson(w, http.StatusBadRequest, response.GeneralError(err))
+			return
+		}
+		if _, err := hooks.GetDelivery(r.Context(), userId, webhookId, deliveryId); err != nil {
+			response.WriteJson(w, http.StatusNotFound, response.GeneralError(err))
+			return
+		}
+		delivery, err := hooks.Redeliver(r.Context(), userId, webhookId, deliveryId, time.Now().UTC())
+		if err != nil {
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(err))
+			return
+		}
+		wake()
+		logger.FromContext(r.Context()).Info("webhook delivery queued again", slog.Int64("userId", userId),
+			slog.Int64("webhookId", webhookId), slog.Int64("deliveryId", deliveryId), slog.Int64("newDeliveryId", delivery.Id))
+		w.Header().Set("Location", fmt.Sprintf("%s/deliveries/%d", webhookLocation(userId, webhookId), delivery.Id))
+		response.WriteJson(w, http.StatusAccepted, delivery)
+	}
+}
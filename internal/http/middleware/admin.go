@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// AdminToken guards operator endpoints with a static bearer token. With no
+// token configured every request is refused.
+func AdminToken(token string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				response.WriteJson(w, http.StatusForbidden, response.GeneralError(fmt.Errorf("admin endpoints are disabled")))
+				return
+			}
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				response.WriteJson(w, http.StatusUnauthorized, response.GeneralError(fmt.Errorf("invalid admin token")))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
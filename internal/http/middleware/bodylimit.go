@@ -0,0 +1,16 @@
+package middleware
+
+import "net/http"
+
+// MaxBodySize caps the size of request bodies. Reading past the limit fails
+// with an *http.MaxBytesError, which the handlers answer with 413.
+func MaxBodySize(limit int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit > 0 && r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/srmty09/Todo-App/internal/config"
+)
+
+// corsPolicy is an immutable, preprocessed config.CORS
+type corsPolicy struct {
+	anyOrigin        bool
+	origins          map[string]bool
+	wildcards        []string // suffixes such as ".example.com" with their scheme prefix
+	methods          []string
+	allowHeaders     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+// CORS answers preflight requests and adds the CORS headers to responses for
+// allowed origins. The policy can be replaced at runtime with Update.
+type CORS struct {
+	policy atomic.Pointer[corsPolicy]
+	// route resolves the pattern serving a request, "" when none does
+	route func(*http.Request) string
+}
+
+func NewCORS(cfg config.CORS, route func(*http.Request) string) *CORS {
+	c := &CORS{route: route}
+	c.Update(cfg)
+	return c
+}
+
+// Update swaps in a new policy
+func (c *CORS) Update(cfg config.CORS) {
+	p := &corsPolicy{
+		origins:          make(map[string]bool),
+		allowHeaders:     strings.Join(cfg.AllowedHeaders, ", "),
+		exposeHeaders:    strings.Join(cfg.ExposedHeaders, ", "),
+		allowCredentials: cfg.AllowCredentials,
+		maxAge:           strconv.Itoa(int(cfg.MaxAge.Seconds())),
+	}
+	for _, m := range cfg.AllowedMethods {
+		p.methods = append(p.methods, strings.ToUpper(strings.TrimSpace(m)))
+	}
+	for _, o := range cfg.AllowedOrigins {
+		o = strings.TrimRight(strings.TrimSpace(o), "/")
+		switch {
+		case o == "*":
+			p.anyOrigin = true
+		case strings.Contains(o, "://*."):
+			scheme, host, _ := strings.Cut(o, "://*")
+			p.wildcards = append(p.wildcards, scheme+"://|"+host)
+		case o != "":
+			p.origins[o] = true
+		}
+	}
+	c.policy.Store(p)
+}
+
+func (p *corsPolicy) allows(origin string) bool {
+	if p.anyOrigin || p.origins[origin] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		scheme, suffix, _ := strings.Cut(w, "|")
+		if strings.HasPrefix(origin, scheme) && strings.HasSuffix(origin, suffix) && len(origin) > len(scheme)+len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOrigin reports whether browsers on origin may use the API, for
+// endpoints like WebSockets that browsers do not guard with CORS
+func (c *CORS) AllowsOrigin(origin string) bool {
+	return c.policy.Load().allows(strings.TrimRight(origin, "/"))
+}
+
+func (c *CORS) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		p := c.policy.Load()
+		h := w.Header()
+		h.Add("Vary", "Origin")
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+		if !p.allows(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if p.anyOrigin && !p.allowCredentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			// credentials cannot be combined with a literal "*"
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if p.allowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if p.exposeHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", p.exposeHeaders)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Only advertise the methods actually registered for this path, and
+		// let the mux answer preflights for paths or methods we do not serve
+		methods := c.methodsFor(p, r)
+		requested := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+		if !contains(methods, requested) {
+			h.Del("Access-Control-Allow-Origin")
+			h.Del("Access-Control-Allow-Credentials")
+			next.ServeHTTP(w, r)
+			return
+		}
+		h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		if p.allowHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", p.allowHeaders)
+		}
+		h.Set("Access-Control-Max-Age", p.maxAge)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// methodsFor returns the allowed methods that have a route for r's path
+func (c *CORS) methodsFor(p *corsPolicy, r *http.Request) []string {
+	var out []string
+	for _, m := range p.methods {
+		probe := r.Clone(r.Context())
+		probe.Method = m
+		if pattern := c.route(probe); pattern != "" && strings.HasPrefix(pattern, m+" ") {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
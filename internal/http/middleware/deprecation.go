@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Deprecated marks every response of the wrapped handler as deprecated using
+// the Deprecation (RFC 9745) and Sunset (RFC 8594) headers, and points clients
+// at the successor API through a Link header.
+func Deprecated(deprecatedAt time.Time, sunset time.Time, successor string) Middleware {
+	deprecation := fmt.Sprintf("@%d", deprecatedAt.Unix())
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+	link := fmt.Sprintf("<%s>; rel=\"successor-version\"", successor)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", deprecation)
+			w.Header().Set("Sunset", sunsetDate)
+			w.Header().Add("Link", link)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
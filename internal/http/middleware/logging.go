@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// statusRecorder captures the status code and body size written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+// Flush lets streaming handlers flush through the recorder
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Hijack hands the connection to protocols like WebSocket, which answer the
+// handshake themselves; the request is recorded as switching protocols
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(s.ResponseWriter).Hijack()
+	if err == nil && s.status == 0 {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Logger attaches a request-scoped logger carrying the request id (and trace
+// id when the request is traced) to the context and writes one access log line per request.
+// Secret segments of the route pattern that route resolves are masked in the
+// logged path.
+func Logger(base *slog.Logger, route func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			l := base.With(slog.String("request_id", GetRequestID(r.Context())))
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				l = l.With(slog.String("trace_id", sc.TraceID().String()))
+			}
+			rec := &statusRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(rec, r.WithContext(logger.WithContext(r.Context(), l)))
+
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			l.Info("request completed",
+				slog.String("method", r.Method),
+				slog.String("path", loggedPath(r, route(r))),
+				slog.Int("status", rec.status),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int("bytes", rec.bytes),
+				slog.String("remote_addr", r.RemoteAddr),
+			)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/srmty09/Todo-App/internal/metrics"
+)
+
+// Metrics records request counts, latencies and in-flight requests labelled
+// by the route pattern that route resolves for the request. Requests matching
+// no route share the "unmatched" label to keep cardinality bounded.
+func Metrics(m *metrics.Metrics, route func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern := route(r)
+			if pattern == "" {
+				pattern = "unmatched"
+			}
+			done := m.RequestStarted(r.Method, pattern)
+			rec := &statusRecorder{ResponseWriter: w}
+			defer func() {
+				if rec.status == 0 {
+					rec.status = http.StatusOK
+				}
+				done(rec.status)
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// Recover turns a panicking handler into a 500 JSON response and logs the
+// stack trace with the request-scoped logger.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				// deliberate abort, let net/http handle it
+				panic(rec)
+			}
+			logger.FromContext(r.Context()).Error("panic while handling request",
+				slog.Any("panic", rec),
+				slog.String("stack", string(debug.Stack())),
+			)
+			response.WriteJson(w, http.StatusInternalServerError, response.GeneralError(fmt.Errorf("internal server error")))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// secretWildcards name the path wildcards whose values are credentials, like
+// the token of a calendar feed. Their values never reach logs or traces.
+var secretWildcards = map[string]bool{"token": true}
+
+// redacted stands in for a secret path segment
+const redacted = "REDACTED"
+
+// redactedPath returns the escaped path of r with every segment that pattern
+// matches to a secret wildcard replaced, and whether anything was replaced.
+// Segments are counted the way http.ServeMux counts them when matching.
+func redactedPath(r *http.Request, pattern string) (string, bool) {
+	if _, rest, ok := strings.Cut(pattern, " "); ok {
+		pattern = rest
+	}
+	// A pattern may start with a host
+	start := strings.Index(pattern, "/")
+	if start < 0 {
+		return "", false
+	}
+	wildcards := strings.Split(pattern[start:], "/")
+	segments := strings.Split(r.URL.EscapedPath(), "/")
+	changed := false
+	for i, wildcard := range wildcards {
+		if i >= len(segments) {
+			break
+		}
+		name, ok := strings.CutPrefix(wildcard, "{")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSuffix(name, "}")
+		name, rest := strings.CutSuffix(name, "...")
+		if !secretWildcards[name] {
+			continue
+		}
+		segments[i] = redacted
+		changed = true
+		if rest {
+			segments = segments[:i+1]
+			break
+		}
+	}
+	if !changed {
+		return "", false
+	}
+	return strings.Join(segments, "/"), true
+}
+
+// loggedPath is the path of r as it may be written to a log
+func loggedPath(r *http.Request, pattern string) string {
+	if path, ok := redactedPath(r, pattern); ok {
+		return path
+	}
+	return r.URL.Path
+}
+
+// withRedactedURL returns a shallow copy of r whose URL has the secret
+// segments of pattern replaced, or r itself when there are none
+func withRedactedURL(r *http.Request, pattern string) *http.Request {
+	path, ok := redactedPath(r, pattern)
+	if !ok {
+		return r
+	}
+	u := *r.URL
+	u.Path, _ = url.PathUnescape(path)
+	u.RawPath = ""
+	redactedReq := r.WithContext(r.Context())
+	redactedReq.URL = &u
+	redactedReq.RequestURI = u.RequestURI()
+	return redactedReq
+}
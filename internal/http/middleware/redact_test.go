@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const secret = "c2VjcmV0LWZlZWQtdG9rZW4"
+
+func TestRedactedPath(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		path    string
+		want    string
+	}{
+		{"GET /api/v2/calendars/{token}/tasks.ics", "/api/v2/calendars/" + secret + "/tasks.ics", "/api/v2/calendars/REDACTED/tasks.ics"},
+		{"example.com/feeds/{token}", "/feeds/" + secret, "/feeds/REDACTED"},
+		{"/files/{token...}", "/files/" + secret + "/more/of/it", "/files/REDACTED"},
+		{"GET /api/v2/users/{id}/tasks", "/api/v2/users/7/tasks", ""},
+		{"", "/nothing", ""},
+	} {
+		r := httptest.NewRequest("GET", tc.path, nil)
+		got, ok := redactedPath(r, tc.pattern)
+		if ok != (tc.want != "") || got != tc.want {
+			t.Errorf("%s on %s: %q %v, want %q", tc.pattern, tc.path, got, ok, tc.want)
+		}
+	}
+}
+
+// calendarMux serves a feed route and reports the token the handler saw
+func calendarMux(seen *string) (*http.ServeMux, func(*http.Request) string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v2/calendars/{token}/tasks.ics", func(w http.ResponseWriter, r *http.Request) {
+		*seen = r.PathValue("token")
+		if !strings.Contains(r.URL.Path, secret) {
+			*seen = "handler got a masked URL: " + r.URL.Path
+		}
+	})
+	route := func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	}
+	return mux, route
+}
+
+func TestLoggerMasksSecrets(t *testing.T) {
+	var seen string
+	mux, route := calendarMux(&seen)
+	var out bytes.Buffer
+	handler := Logger(slog.New(slog.NewTextHandler(&out, nil)), route)(mux)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v2/calendars/"+secret+"/tasks.ics", nil))
+
+	if seen != secret {
+		t.Errorf("handler saw %q", seen)
+	}
+	if strings.Contains(out.String(), secret) {
+		t.Errorf("token logged: %s", out.String())
+	}
+	if !strings.Contains(out.String(), "path=/api/v2/calendars/REDACTED/tasks.ics") {
+		t.Errorf("no masked path in: %s", out.String())
+	}
+}
+
+func TestTracingMasksSecrets(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	var seen string
+	mux, route := calendarMux(&seen)
+	Tracing(route)(mux).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v2/calendars/"+secret+"/tasks.ics", nil))
+
+	if seen != secret {
+		t.Errorf("handler saw %q", seen)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans ended, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/v2/calendars/{token}/tasks.ics" {
+		t.Errorf("span named %q", span.Name())
+	}
+	masked := false
+	for _, attr := range span.Attributes() {
+		if strings.Contains(attr.Value.Emit(), secret) {
+			t.Errorf("token in span attribute %s=%s", attr.Key, attr.Value.Emit())
+		}
+		if attr.Key == "url.path" && attr.Value.AsString() == "/api/v2/calendars/REDACTED/tasks.ics" {
+			masked = true
+		}
+	}
+	if !masked {
+		t.Errorf("no masked url.path among %v", span.Attributes())
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+type realRequestKey struct{}
+
+// Tracing starts a server span for every request, continuing the trace of an
+// incoming W3C traceparent header. Spans are named after the route pattern
+// that route resolves for the request. Secret path segments, like a calendar
+// feed token, are masked in the span's attributes; the handlers still see
+// the real URL.
+func Tracing(route func(*http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		traced := otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if real, ok := r.Context().Value(realRequestKey{}).(*http.Request); ok {
+				r = r.WithContext(r.Context())
+				r.URL, r.RequestURI = real.URL, real.RequestURI
+			}
+			next.ServeHTTP(w, r)
+		}), "http.server",
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				if pattern := route(r); pattern != "" {
+					return pattern
+				}
+				return r.Method + " unmatched"
+			}),
+		)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			masked := withRedactedURL(r, route(r))
+			if masked != r {
+				masked = masked.WithContext(context.WithValue(r.Context(), realRequestKey{}, r))
+			}
+			traced.ServeHTTP(w, masked)
+		})
+	}
+}
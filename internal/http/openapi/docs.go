@@ -0,0 +1,267 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+)
+
+// The docs page is rendered on the server from the embedded document, so it
+// needs no script or stylesheet from elsewhere and works offline.
+
+//go:embed docs.html
+var docsTemplate string
+
+// methodOrder is the order operations on the same path are listed in
+var methodOrder = []string{"get", "post", "put", "patch", "delete", "options", "head", "trace"}
+
+type docPage struct {
+	Title       string
+	Version     string
+	Description string
+	Tags        []docTag
+	Schemas     []docSchema
+}
+
+type docTag struct {
+	Name       string
+	Operations []docOperation
+}
+
+type docOperation struct {
+	Id          string
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Deprecated  bool
+	Admin       bool
+	Parameters  []docParameter
+	Body        []docContent
+	Responses   []docResponse
+}
+
+type docParameter struct {
+	Name        string
+	In          string
+	Type        string
+	Required    bool
+	Description string
+}
+
+type docContent struct {
+	Type   string
+	Schema string
+}
+
+type docResponse struct {
+	Status      string
+	Description string
+	Content     []docContent
+}
+
+type docSchema struct {
+	Name string
+	JSON string
+}
+
+// document is the part of the OpenAPI document the docs page shows
+type document struct {
+	Info struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description"`
+	} `json:"info"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Parameters map[string]parameter       `json:"parameters"`
+		Responses  map[string]response        `json:"responses"`
+		Schemas    map[string]json.RawMessage `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	OperationId string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description"`
+	Tags        []string              `json:"tags"`
+	Deprecated  bool                  `json:"deprecated"`
+	Security    []map[string][]string `json:"security"`
+	Parameters  []parameter           `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]media `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]response `json:"responses"`
+}
+
+type parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description"`
+	Schema      *schema `json:"schema"`
+}
+
+type response struct {
+	Ref         string           `json:"$ref"`
+	Description string           `json:"description"`
+	Content     map[string]media `json:"content"`
+}
+
+type media struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref    string        `json:"$ref"`
+	Type   interface{}   `json:"type"`
+	Format string        `json:"format"`
+	Enum   []interface{} `json:"enum"`
+	Items  *schema       `json:"items"`
+	OneOf  []*schema     `json:"oneOf"`
+}
+
+// refName is the last segment of a local reference
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// String describes a schema in a few words, naming referenced schemas
+func (s *schema) String() string {
+	switch {
+	case s == nil:
+		return ""
+	case s.Ref != "":
+		return refName(s.Ref)
+	case len(s.OneOf) > 0:
+		names := make([]string, len(s.OneOf))
+		for i, one := range s.OneOf {
+			names[i] = one.String()
+		}
+		return "one of " + strings.Join(names, ", ")
+	case s.Items != nil:
+		return "array of " + s.Items.String()
+	}
+	out := fmt.Sprint(s.Type)
+	if types, ok := s.Type.([]interface{}); ok {
+		names := make([]string, len(types))
+		for i, t := range types {
+			names[i] = fmt.Sprint(t)
+		}
+		out = strings.Join(names, " or ")
+	}
+	if s.Format != "" {
+		out += " (" + s.Format + ")"
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = fmt.Sprint(v)
+		}
+		out += ": " + strings.Join(values, ", ")
+	}
+	return out
+}
+
+func contents(content map[string]media) []docContent {
+	var out []docContent
+	for mediaType, m := range content {
+		out = append(out, docContent{Type: mediaType, Schema: m.Schema.String()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	return out
+}
+
+// renderDocs renders the docs page for the embedded document
+func renderDocs() ([]byte, error) {
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid openapi document: %w", err)
+	}
+	page := docPage{Title: doc.Info.Title, Version: doc.Info.Version, Description: doc.Info.Description}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	tags := make(map[string]*docTag)
+	var tagNames []string
+	for _, path := range paths {
+		for _, method := range methodOrder {
+			raw, ok := doc.Paths[path][method]
+			if !ok {
+				continue
+			}
+			var op operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+			out := docOperation{
+				Id:          op.OperationId,
+				Method:      strings.ToUpper(method),
+				Path:        path,
+				Summary:     op.Summary,
+				Description: op.Description,
+				Deprecated:  op.Deprecated,
+				Admin:       len(op.Security) > 0,
+			}
+			for _, p := range op.Parameters {
+				if p.Ref != "" {
+					p = doc.Components.Parameters[refName(p.Ref)]
+				}
+				out.Parameters = append(out.Parameters, docParameter{
+					Name: p.Name, In: p.In, Type: p.Schema.String(), Required: p.Required, Description: p.Description,
+				})
+			}
+			if op.RequestBody != nil {
+				out.Body = contents(op.RequestBody.Content)
+			}
+			for status, res := range op.Responses {
+				if res.Ref != "" {
+					res = doc.Components.Responses[refName(res.Ref)]
+				}
+				out.Responses = append(out.Responses, docResponse{Status: status, Description: res.Description, Content: contents(res.Content)})
+			}
+			sort.Slice(out.Responses, func(i, j int) bool { return out.Responses[i].Status < out.Responses[j].Status })
+
+			tag := "other"
+			if len(op.Tags) > 0 {
+				tag = op.Tags[0]
+			}
+			if tags[tag] == nil {
+				tags[tag] = &docTag{Name: tag}
+				tagNames = append(tagNames, tag)
+			}
+			tags[tag].Operations = append(tags[tag].Operations, out)
+		}
+	}
+	sort.Strings(tagNames)
+	for _, name := range tagNames {
+		page.Tags = append(page.Tags, *tags[name])
+	}
+
+	for name, raw := range doc.Components.Schemas {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, raw, "", "  "); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+		page.Schemas = append(page.Schemas, docSchema{Name: name, JSON: indented.String()})
+	}
+	sort.Slice(page.Schemas, func(i, j int) bool { return page.Schemas[i].Name < page.Schemas[j].Name })
+
+	tmpl, err := template.New("docs").Funcs(template.FuncMap{"lower": strings.ToLower}).Parse(docsTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, page); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,112 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// httpMethods are the operation keys allowed on an OpenAPI path item
+var httpMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// Spec serves the embedded OpenAPI document
+func Spec() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(spec)
+	}
+}
+
+// Docs serves an HTML page describing the API, rendered from the OpenAPI
+// document
+func Docs() http.HandlerFunc {
+	docs, err := renderDocs()
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write(docs)
+	}
+}
+
+// Operations returns every operation described in the document as
+// "METHOD /path", sorted.
+func Operations() ([]string, error) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid openapi document: %w", err)
+	}
+	var ops []string
+	for path, item := range doc.Paths {
+		for method := range item {
+			if httpMethods[method] {
+				ops = append(ops, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(ops)
+	return ops, nil
+}
+
+// Verify checks that the registered mux patterns and the operations in the
+// OpenAPI document describe the same set of routes. Patterns are expected in
+// the "METHOD /path" form used by the router.
+func Verify(patterns []string) error {
+	ops, err := Operations()
+	if err != nil {
+		return err
+	}
+	documented := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		documented[op] = true
+	}
+
+	registered := make(map[string]bool, len(patterns))
+	var missing []string
+	for _, p := range patterns {
+		method, path, ok := strings.Cut(p, " ")
+		if !ok {
+			return fmt.Errorf("route %q has no method", p)
+		}
+		op := strings.ToUpper(method) + " " + strings.TrimSpace(path)
+		registered[op] = true
+		if !documented[op] {
+			missing = append(missing, op)
+		}
+	}
+
+	var stale []string
+	for _, op := range ops {
+		if !registered[op] {
+			stale = append(stale, op)
+		}
+	}
+
+	var problems []string
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		problems = append(problems, "routes missing from openapi spec: "+strings.Join(missing, ", "))
+	}
+	if len(stale) > 0 {
+		problems = append(problems, "openapi operations without a route: "+strings.Join(stale, ", "))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	ops, err := Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(ops); err != nil {
+		t.Fatalf("the document's own operations: %v", err)
+	}
+
+	err = Verify(append(ops[1:], "GET /undocumented"))
+	if err == nil {
+		t.Fatal("drift was not reported")
+	}
+	for _, want := range []string{"routes missing from openapi spec: GET /undocumented", "openapi operations without a route: " + ops[0]} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestDocs(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Docs()(rec, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	page := rec.Body.String()
+
+	// Everything the page needs is in it
+	for _, external := range []string{"<script", "<link", `src="http`, `href="http`} {
+		if strings.Contains(page, external) {
+			t.Errorf("page loads something from elsewhere: found %q", external)
+		}
+	}
+	ops, err := Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range ops {
+		method, path, _ := strings.Cut(op, " ")
+		if !strings.Contains(page, ">"+method+"</span> <code>"+path+"</code>") {
+			t.Errorf("%s is not on the page", op)
+		}
+	}
+	for _, want := range []string{`id="schema-Task"`, "one of array of Task, EmptyTaskList", "admin token"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %q", want)
+		}
+	}
+}
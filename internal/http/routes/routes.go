@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Router is a thin wrapper around http.ServeMux that remembers every pattern
+// registered on it, so the API description can be checked against the routes
+// the server actually serves.
+type Router struct {
+	mux *http.ServeMux
+
+	mu          sync.Mutex
+	patterns    []string
+	middlewares []func(http.Handler) http.Handler
+}
+
+func New() *Router {
+	return &Router{
+		mux: http.NewServeMux(),
+	}
+}
+
+// HandleFunc registers the handler for the given pattern on the underlying mux
+func (r *Router) HandleFunc(pattern string, handler http.HandlerFunc) {
+	r.Handle(pattern, handler)
+}
+
+// Use adds middleware wrapping every handler registered after the call.
+// Unlike middleware around the router itself, these run after routing, so
+// r.Pattern and r.PathValue are available to them.
+func (r *Router) Use(middlewares ...func(http.Handler) http.Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// Handle registers the handler for the given pattern on the underlying mux
+func (r *Router) Handle(pattern string, handler http.Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		handler = r.middlewares[i](handler)
+	}
+	r.mux.Handle(pattern, handler)
+	r.patterns = append(r.patterns, pattern)
+}
+
+// Patterns returns the registered patterns in registration order
+func (r *Router) Patterns() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]string, len(r.patterns))
+	copy(out, r.patterns)
+	return out
+}
+
+// Route returns the pattern that will serve req, or "" when nothing matches.
+// It is meant for labelling metrics with the route rather than the raw path.
+func (r *Router) Route(req *http.Request) string {
+	_, pattern := r.mux.Handler(req)
+	return pattern
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics owns the Prometheus registry and the collectors shared by the HTTP
+// middleware and the storage wrapper
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	httpInFlight *prometheus.GaugeVec
+
+	storageDuration *prometheus.HistogramVec
+	storageErrors   *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "todo_http_requests_total",
+			Help: "HTTP requests handled, by route pattern and status code.",
+		}, []string{"method", "route", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "todo_http_request_duration_seconds",
+			Help:    "HTTP request latency, by route pattern.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		httpInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "todo_http_requests_in_flight",
+			Help: "HTTP requests currently being served, by route pattern.",
+		}, []string{"method", "route"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "todo_storage_query_duration_seconds",
+			Help:    "Storage call latency, by storage method.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"method"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "todo_storage_errors_total",
+			Help: "Storage calls that returned an error, by storage method.",
+		}, []string{"method"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.httpInFlight,
+		m.storageDuration,
+		m.storageErrors,
+	)
+	return m
+}
+
+// RegisterDB exports the connection pool statistics of db
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the registry in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RequestStarted marks a request as in flight and returns the function that
+// records its outcome
+func (m *Metrics) RequestStarted(method string, route string) func(status int) {
+	start := time.Now()
+	inFlight := m.httpInFlight.WithLabelValues(method, route)
+	inFlight.Inc()
+	return func(status int) {
+		inFlight.Dec()
+		m.httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	}
+}
+
+// observeStorage records the latency and outcome of one storage call
+func (m *Metrics) observeStorage(method string, start time.Time, err error) {
+	m.storageDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.storageErrors.WithLabelValues(method).Inc()
+	}
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// Storage decorates a storage.Storage with per-method latency and error metrics
+type Storage struct {
+	next    storage.Storage
+	metrics *Metrics
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+// WrapStorage returns next instrumented with m
+func WrapStorage(next storage.Storage, m *Metrics) *Storage {
+	return &Storage{next: next, metrics: m}
+}
+
+func (s *Storage) CreateUser(ctx context.Context, name string, email string) (int64, error) {
+	start := time.Now()
+	res, err := s.next.CreateUser(ctx, name, email)
+	s.metrics.observeStorage("CreateUser", start, err)
+	return res, err
+}
+
+func (s *Storage) UserExists(ctx context.Context, userId int64) (bool, error) {
+	start := time.Now()
+	res, err := s.next.UserExists(ctx, userId)
+	s.metrics.observeStorage("UserExists", start, err)
+	return res, err
+}
+
+func (s *Storage) AddNewTask(ctx context.Context, userId int64, title string, description string, priority string, completed bool, createdAt time.Time, updatedAt time.Time) (int64, error) {
+	start := time.Now()
+	res, err := s.next.AddNewTask(ctx, userId, title, description, priority, completed, createdAt, updatedAt)
+	s.metrics.observeStorage("AddNewTask", start, err)
+	return res, err
+}
+
+func (s *Storage) GetTaskForId(ctx context.Context, userId int64) ([]types.TaskMetaData, error) {
+	start := time.Now()
+	res, err := s.next.GetTaskForId(ctx, userId)
+	s.metrics.observeStorage("GetTaskForId", start, err)
+	return res, err
+}
+
+func (s *Storage) GetSingleTask(ctx context.Context, userId int64, taskId int64) (*types.TaskMetaData, error) {
+	start := time.Now()
+	res, err := s.next.GetSingleTask(ctx, userId, taskId)
+	s.metrics.observeStorage("GetSingleTask", start, err)
+	return res, err
+}
+
+func (s *Storage) MarkComplete(ctx context.Context, userId int64, taskId int64) error {
+	start := time.Now()
+	err := s.next.MarkComplete(ctx, userId, taskId)
+	s.metrics.observeStorage("MarkComplete", start, err)
+	return err
+}
+
+func (s *Storage) MarkIncomplete(ctx context.Context, userId int64, taskId int64) error {
+	start := time.Now()
+	err := s.next.MarkIncomplete(ctx, userId, taskId)
+	s.metrics.observeStorage("MarkIncomplete", start, err)
+	return err
+}
+
+func (s *Storage) DeletingTask(ctx context.Context, userId int64, taskId int64) error {
+	start := time.Now()
+	err := s.next.DeletingTask(ctx, userId, taskId)
+	s.metrics.observeStorage("DeletingTask", start, err)
+	return err
+}
+
+func (s *Storage) EditTask(ctx context.Context, userId int64, taskId int64, title string, description string, priority string) error {
+	start := time.Now()
+	err := s.next.EditTask(ctx, userId, taskId, title, description, priority)
+	s.metrics.observeStorage("EditTask", start, err)
+	return err
+}
+
+func (s *Storage) GetUser(ctx context.Context, userId int64) (*types.User, error) {
+	start := time.Now()
+	res, err := s.next.GetUser(ctx, userId)
+	s.metrics.observeStorage("GetUser", start, err)
+	return res, err
+}
+
+func (s *Storage) DeleteUser(ctx context.Context, userId int64) error {
+	start := time.Now()
+	err := s.next.DeleteUser(ctx, userId)
+	s.metrics.observeStorage("DeleteUser", start, err)
+	return err
+}
+
+func (s *Storage) GetCompletedTask(ctx context.Context, userId int64) ([]types.TaskMetaData, error) {
+	start := time.Now()
+	res, err := s.next.GetCompletedTask(ctx, userId)
+	s.metrics.observeStorage("GetCompletedTask", start, err)
+	return res, err
+}
+
+func (s *Storage) GetIncompletedTask(ctx context.Context, userId int64) ([]types.TaskMetaData, error) {
+	start := time.Now()
+	res, err := s.next.GetIncompletedTask(ctx, userId)
+	s.metrics.observeStorage("GetIncompletedTask", start, err)
+	return res, err
+}
+
+func (s *Storage) GetTaskWithTitle(ctx context.Context, userId int64, keyword string) ([]types.TaskMetaData, error) {
+	start := time.Now()
+	res, err := s.next.GetTaskWithTitle(ctx, userId, keyword)
+	s.metrics.observeStorage("GetTaskWithTitle", start, err)
+	return res, err
+}
+
+func (s *Storage) GetTaskWithFilters(ctx context.Context, userId int64, keyword string, status string) ([]types.TaskMetaData, error) {
+	start := time.Now()
+	res, err := s.next.GetTaskWithFilters(ctx, userId, keyword, status)
+	s.metrics.observeStorage("GetTaskWithFilters", start, err)
+	return res, err
+}
+
+func (s *Storage) Close() error {
+	return s.next.Close()
+}
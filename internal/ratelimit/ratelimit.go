@@ -0,0 +1,187 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/utils/logger"
+	"github.com/srmty09/Todo-App/internal/utils/response"
+)
+
+// policy is a validated config.RateLimitPolicy
+type policy struct {
+	name   string
+	key    string
+	limit  Limit
+	quota  int
+	window time.Duration
+}
+
+// rules is an immutable snapshot of the configuration, swapped atomically
+type rules struct {
+	enabled           bool
+	trustForwardedFor bool
+	byRoute           map[string]*policy
+	fallback          *policy
+}
+
+// Limiter enforces the configured policies against a Store
+type Limiter struct {
+	store Store
+	rules atomic.Pointer[rules]
+}
+
+// New builds a limiter from the config; store is usually a MemoryStore
+func New(cfg config.RateLimit, store Store) (*Limiter, error) {
+	l := &Limiter{store: store}
+	if err := l.Update(cfg); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Update replaces the policies. Existing buckets are kept, so clients do not
+// get a fresh allowance just because the config was reloaded.
+func (l *Limiter) Update(cfg config.RateLimit) error {
+	next := &rules{
+		enabled:           cfg.Enabled,
+		trustForwardedFor: cfg.TrustForwardedFor,
+		byRoute:           make(map[string]*policy),
+	}
+	if cfg.Default.Requests > 0 {
+		p, err := newPolicy(cfg.Default, "default")
+		if err != nil {
+			return err
+		}
+		next.fallback = p
+	}
+	for i, pc := range cfg.Policies {
+		p, err := newPolicy(pc, fmt.Sprintf("policy-%d", i))
+		if err != nil {
+			return err
+		}
+		for _, route := range pc.Routes {
+			if other, ok := next.byRoute[route]; ok {
+				return fmt.Errorf("rate limit: route %q is in both %q and %q", route, other.name, p.name)
+			}
+			next.byRoute[route] = p
+		}
+	}
+	l.rules.Store(next)
+	return nil
+}
+
+func newPolicy(pc config.RateLimitPolicy, fallbackName string) (*policy, error) {
+	name := pc.Name
+	if name == "" {
+		name = fallbackName
+	}
+	if pc.Requests <= 0 {
+		return nil, fmt.Errorf("rate limit %q: requests must be positive", name)
+	}
+	if pc.Period <= 0 {
+		return nil, fmt.Errorf("rate limit %q: period must be positive", name)
+	}
+	key := pc.Key
+	if key == "" {
+		key = "ip"
+	}
+	if key != "ip" && key != "user" {
+		return nil, fmt.Errorf("rate limit %q: key must be \"ip\" or \"user\"", name)
+	}
+	burst := pc.Burst
+	if burst <= 0 {
+		burst = pc.Requests
+	}
+	return &policy{
+		name:   name,
+		key:    key,
+		limit:  Limit{Rate: float64(pc.Requests) / pc.Period.Seconds(), Burst: burst},
+		quota:  pc.Requests,
+		window: pc.Period,
+	}, nil
+}
+
+// Middleware must run inside the mux (see routes.Router.Use) so that the
+// matched pattern and path values are available on the request.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rs := l.rules.Load()
+		if !rs.enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		p, ok := rs.byRoute[r.Pattern]
+		if !ok {
+			p = rs.fallback
+		}
+		if p == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := p.name + ":" + l.clientKey(rs, p, r)
+		res, err := l.store.Take(r.Context(), key, p.limit)
+		if err != nil {
+			// A broken shared store should not take the API down with it
+			logger.FromContext(r.Context()).Error("rate limit store failed", slog.Any("error", err))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", p.quota, int(p.window.Seconds()), p.limit.Burst))
+		h.Set("RateLimit-Limit", strconv.Itoa(p.limit.Burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			logger.FromContext(r.Context()).Warn("rate limit exceeded", slog.String("policy", p.name), slog.String("key", key))
+			response.WriteJson(w, http.StatusTooManyRequests, response.GeneralError(fmt.Errorf("rate limit exceeded, retry in %d seconds", ceilSeconds(res.RetryAfter))))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientKey identifies who the request is counted against. The {id} path
+// value is not authenticated, so "user" keys also carry the client IP: a
+// client can neither dodge its limit by changing the id nor use up another
+// user's allowance by sending that user's id.
+func (l *Limiter) clientKey(rs *rules, p *policy, r *http.Request) string {
+	ip := "ip:" + clientIP(r, rs.trustForwardedFor)
+	if p.key == "user" {
+		if id := r.PathValue("id"); id != "" {
+			return "user:" + id + ":" + ip
+		}
+	}
+	return ip
+}
+
+// clientIP returns the address of the client. X-Forwarded-For is only
+// honoured when the server sits behind a proxy that sets it.
+func clientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,220 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/config"
+)
+
+// fakeClock drives a MemoryStore's time by hand
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = clock.now
+	return store, clock
+}
+
+func TestMemoryStoreBurstAndRefill(t *testing.T) {
+	store, clock := newTestStore()
+	ctx := context.Background()
+	// One token a second, up to three at once
+	limit := Limit{Rate: 1, Burst: 3}
+
+	for i := 2; i >= 0; i-- {
+		res, _ := store.Take(ctx, "a", limit)
+		if !res.Allowed || res.Remaining != i {
+			t.Fatalf("burst request with %d left: %+v", i, res)
+		}
+	}
+	res, _ := store.Take(ctx, "a", limit)
+	if res.Allowed || res.RetryAfter != time.Second || res.ResetAfter != 3*time.Second {
+		t.Fatalf("over the burst: %+v", res)
+	}
+	// Other keys have their own bucket
+	if res, _ := store.Take(ctx, "b", limit); !res.Allowed {
+		t.Errorf("another key was limited: %+v", res)
+	}
+
+	clock.advance(1500 * time.Millisecond)
+	if res, _ := store.Take(ctx, "a", limit); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("after refilling a token and a half: %+v", res)
+	}
+	res, _ = store.Take(ctx, "a", limit)
+	if res.Allowed || res.RetryAfter != 500*time.Millisecond {
+		t.Errorf("half a token left: %+v", res)
+	}
+
+	// A bucket never holds more than its burst
+	clock.advance(time.Hour)
+	if res, _ := store.Take(ctx, "a", limit); res.Remaining != 2 {
+		t.Errorf("after an hour: %+v", res)
+	}
+}
+
+func TestMemoryStoreSweepsIdleBuckets(t *testing.T) {
+	store, clock := newTestStore()
+	ctx := context.Background()
+	store.Take(ctx, "a", Limit{Rate: 1, Burst: 3})
+	store.Take(ctx, "b", Limit{Rate: 0.001, Burst: 3})
+	clock.advance(2 * sweepInterval)
+	store.Take(ctx, "c", Limit{Rate: 1, Burst: 3})
+	if _, ok := store.buckets["a"]; ok {
+		t.Error("a full bucket was kept")
+	}
+	if _, ok := store.buckets["b"]; !ok {
+		t.Error("a bucket still refilling was dropped")
+	}
+}
+
+// newTestMux serves ok on a few routes behind a limiter built from cfg
+func newTestMux(t *testing.T, cfg config.RateLimit) (*http.ServeMux, *fakeClock) {
+	t.Helper()
+	store, clock := newTestStore()
+	l, err := New(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	mux := http.NewServeMux()
+	for _, pattern := range []string{"POST /users", "POST /users/{id}/tasks", "GET /users/{id}/tasks"} {
+		mux.Handle(pattern, l.Middleware(ok))
+	}
+	return mux, clock
+}
+
+func serve(mux *http.ServeMux, method string, target string, remoteAddr string, forwardedFor string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	r.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, r)
+	return rec
+}
+
+func TestMiddlewarePolicies(t *testing.T) {
+	mux, clock := newTestMux(t, config.RateLimit{
+		Enabled: true,
+		Default: config.RateLimitPolicy{Requests: 100, Period: time.Minute},
+		Policies: []config.RateLimitPolicy{
+			{Name: "signup", Routes: []string{"POST /users"}, Requests: 2, Period: time.Minute},
+			{Name: "writes", Routes: []string{"POST /users/{id}/tasks"}, Key: "user", Requests: 60, Period: time.Minute, Burst: 1},
+		},
+	})
+	const ann, bob = "10.0.0.1:5000", "10.0.0.2:5000"
+
+	// Signups are limited per IP by their own policy
+	for i := 0; i < 2; i++ {
+		if rec := serve(mux, "POST", "/users", ann, ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("signup %d: %d", i, rec.Code)
+		}
+	}
+	rec := serve(mux, "POST", "/users", ann, "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" {
+		t.Errorf("third signup: %d %v", rec.Code, rec.Header())
+	}
+	if got := rec.Header().Get("RateLimit-Policy"); got != "2;w=60;burst=2" {
+		t.Errorf("RateLimit-Policy %q", got)
+	}
+	if rec := serve(mux, "POST", "/users", bob, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("another IP was limited: %d", rec.Code)
+	}
+	// Routes without a policy fall back to the default
+	rec = serve(mux, "GET", "/users/1/tasks", ann, "")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "100" || rec.Header().Get("RateLimit-Remaining") != "99" {
+		t.Errorf("default policy: %d %v", rec.Code, rec.Header())
+	}
+
+	// User keys count the id and the IP together
+	if rec := serve(mux, "POST", "/users/1/tasks", ann, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("first write: %d", rec.Code)
+	}
+	rec = serve(mux, "POST", "/users/1/tasks", ann, "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("second write: %d %v", rec.Code, rec.Header())
+	}
+	if rec := serve(mux, "POST", "/users/2/tasks", ann, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("another user from the same IP: %d", rec.Code)
+	}
+	// Someone else sending user 1's id does not use up their allowance
+	if rec := serve(mux, "POST", "/users/1/tasks", bob, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("user 1's id from another IP: %d", rec.Code)
+	}
+	clock.advance(time.Second)
+	if rec := serve(mux, "POST", "/users/1/tasks", ann, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("write after the refill: %d", rec.Code)
+	}
+}
+
+func TestMiddlewareDisabledAndUnlimited(t *testing.T) {
+	mux, _ := newTestMux(t, config.RateLimit{
+		Enabled:  false,
+		Policies: []config.RateLimitPolicy{{Routes: []string{"POST /users"}, Requests: 1, Period: time.Minute}},
+	})
+	for i := 0; i < 3; i++ {
+		if rec := serve(mux, "POST", "/users", "10.0.0.1:5000", ""); rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("disabled limiter: %d %v", rec.Code, rec.Header())
+		}
+	}
+
+	// Without a default, routes outside every policy are not limited
+	mux, _ = newTestMux(t, config.RateLimit{
+		Enabled:  true,
+		Policies: []config.RateLimitPolicy{{Routes: []string{"POST /users"}, Requests: 1, Period: time.Minute}},
+	})
+	if rec := serve(mux, "GET", "/users/1/tasks", "10.0.0.1:5000", ""); rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("route without a policy: %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestForwardedFor(t *testing.T) {
+	for _, tc := range []struct {
+		trust bool
+		// the second request comes from another proxy hop or client
+		first, second string
+		limited       bool
+	}{
+		// Untrusted, the header is ignored and both come from the proxy
+		{false, "203.0.113.7", "198.51.100.1", true},
+		// Trusted, the first address is the client
+		{true, "203.0.113.7", "198.51.100.1", false},
+		{true, "203.0.113.7, 10.0.0.1", " 203.0.113.7 ,10.0.0.9", true},
+	} {
+		mux, _ := newTestMux(t, config.RateLimit{
+			Enabled:           true,
+			TrustForwardedFor: tc.trust,
+			Default:           config.RateLimitPolicy{Requests: 1, Period: time.Minute},
+		})
+		serve(mux, "GET", "/users/1/tasks", "10.0.0.1:5000", tc.first)
+		rec := serve(mux, "GET", "/users/1/tasks", "10.0.0.1:5000", tc.second)
+		if limited := rec.Code == http.StatusTooManyRequests; limited != tc.limited {
+			t.Errorf("trust %v, %q then %q: %d", tc.trust, tc.first, tc.second, rec.Code)
+		}
+	}
+}
+
+func TestUpdateRejectsBadPolicies(t *testing.T) {
+	for name, cfg := range map[string]config.RateLimit{
+		"no requests": {Policies: []config.RateLimitPolicy{{Routes: []string{"GET /"}, Period: time.Minute}}},
+		"no period":   {Policies: []config.RateLimitPolicy{{Routes: []string{"GET /"}, Requests: 1}}},
+		"bad key":     {Policies: []config.RateLimitPolicy{{Routes: []string{"GET /"}, Requests: 1, Period: time.Minute, Key: "email"}}},
+		"route twice": {Policies: []config.RateLimitPolicy{
+			{Routes: []string{"GET /"}, Requests: 1, Period: time.Minute},
+			{Routes: []string{"GET /"}, Requests: 1, Period: time.Minute},
+		}},
+	} {
+		if _, err := New(cfg, NewMemoryStore()); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
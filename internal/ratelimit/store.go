@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit describes a token bucket: Rate tokens are added per second up to Burst
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// ResetAfter is how long until the bucket is full again
+	ResetAfter time.Duration
+	// RetryAfter is how long until a token is available; zero when allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets. The in-process MemoryStore suits a single
+// instance; implement Store on top of a shared backend (Redis, a database)
+// to enforce limits across several instances.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// refill is how long an empty bucket takes to fill up under its limit
+	refill time.Duration
+}
+
+// MemoryStore is a Store keeping buckets in a map. Idle buckets are swept
+// periodically so the map does not grow with every client ever seen.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// sweepInterval is how often idle buckets are looked for
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (m *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	capacity := float64(limit.Burst)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		m.buckets[key] = b
+	}
+
+	// Refill for the time elapsed since the last request
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	b.refill = secondsToDuration(capacity / limit.Rate)
+
+	res := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - b.tokens) / limit.Rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.ResetAfter = secondsToDuration((capacity - b.tokens) / limit.Rate)
+	return res, nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again, as
+// they are indistinguishable from new ones. Must be called with mu held.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if now.Sub(b.last) > b.refill {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,187 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+)
+
+// Maintenance operations used by the admin command. They are not part of
+// storage.Storage since the HTTP API never needs them.
+
+// UserSummary is a user together with their task counts
+type UserSummary struct {
+	Id             int64  `json:"id"`
+	Name           string `json:"name"`
+	Email          string `json:"email"`
+	Tasks          int64  `json:"tasks"`
+	CompletedTasks int64  `json:"completed_tasks"`
+}
+
+// TableStats describes one table of the database
+type TableStats struct {
+	Name    string `json:"name"`
+	Rows    int64  `json:"rows"`
+	Indexes int64  `json:"indexes"`
+}
+
+// DatabaseStats describes the database file as a whole
+type DatabaseStats struct {
+	SchemaVersion int          `json:"schema_version"`
+	PageSize      int64        `json:"page_size"`
+	PageCount     int64        `json:"page_count"`
+	FreePages     int64        `json:"free_pages"`
+	Tables        []TableStats `json:"tables"`
+}
+
+// ListUsers returns every user with their task counts, ordered by id
+func (s *Sqlite) ListUsers(ctx context.Context) ([]UserSummary, error) {
+	rows, err := s.Reader.QueryContext(ctx, `SELECT u.id, u.name, u.email,
+	COUNT(t.id), COALESCE(SUM(CASE WHEN t.completed THEN 1 ELSE 0 END), 0)
+	FROM user u LEFT JOIN todo t ON t.user_id = u.id
+	GROUP BY u.id ORDER BY u.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []UserSummary
+	for rows.Next() {
+		var u UserSummary
+		if err := rows.Scan(&u.Id, &u.Name, &u.Email, &u.Tasks, &u.CompletedTasks); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// ReassignTasks moves every task of one user to another and returns how many
+// were moved
+func (s *Sqlite) ReassignTasks(ctx context.Context, fromUser int64, toUser int64) (int64, error) {
+	if fromUser == toUser {
+		return 0, fmt.Errorf("source and target user are the same")
+	}
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, id := range []int64{fromUser, toUser} {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM user WHERE id = ?)", id).Scan(&exists); err != nil {
+			return 0, err
+		}
+		if !exists {
+			return 0, fmt.Errorf("user with id %d does not exist", id)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE todo SET user_id = ? WHERE user_id = ?", toUser, fromUser)
+	if err != nil {
+		return 0, err
+	}
+	moved, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return moved, tx.Commit()
+}
+
+// Vacuum rebuilds the database file, reclaiming free pages
+func (s *Sqlite) Vacuum(ctx context.Context) error {
+	_, err := s.Db.ExecContext(ctx, "VACUUM")
+	return err
+}
+
+// Analyze refreshes the statistics used by the query planner
+func (s *Sqlite) Analyze(ctx context.Context) error {
+	_, err := s.Db.ExecContext(ctx, "ANALYZE")
+	return err
+}
+
+// IntegrityCheck runs SQLite's integrity and foreign key checks and returns
+// the problems found; an empty result means the database is healthy
+func (s *Sqlite) IntegrityCheck(ctx context.Context) ([]string, error) {
+	var problems []string
+
+	rows, err := s.Db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	fkRows, err := s.Db.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return nil, err
+	}
+	defer fkRows.Close()
+	for fkRows.Next() {
+		var table, parent string
+		var rowid, fkid interface{}
+		if err := fkRows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return nil, err
+		}
+		problems = append(problems, fmt.Sprintf("row %v of %s references a missing %s row", rowid, table, parent))
+	}
+	return problems, fkRows.Err()
+}
+
+// Stats reports the size of the database and the row count of every table
+func (s *Sqlite) Stats(ctx context.Context) (*DatabaseStats, error) {
+	stats := &DatabaseStats{}
+	var err error
+	if stats.SchemaVersion, err = s.SchemaVersion(ctx); err != nil {
+		return nil, err
+	}
+	for pragma, dest := range map[string]*int64{
+		"page_size":      &stats.PageSize,
+		"page_count":     &stats.PageCount,
+		"freelist_count": &stats.FreePages,
+	} {
+		if err := s.Db.QueryRowContext(ctx, "PRAGMA "+pragma).Scan(dest); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := s.Db.QueryContext(ctx, `SELECT t.name,
+	(SELECT COUNT(*) FROM sqlite_master i WHERE i.type = 'index' AND i.tbl_name = t.name)
+	FROM sqlite_master t WHERE t.type = 'table' AND t.name NOT LIKE 'sqlite_%' ORDER BY t.name`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var t TableStats
+		if err := rows.Scan(&t.Name, &t.Indexes); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stats.Tables = append(stats.Tables, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range stats.Tables {
+		// Table names come from sqlite_master, quoting guards odd names
+		query := fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, stats.Tables[i].Name)
+		if err := s.Db.QueryRowContext(ctx, query).Scan(&stats.Tables[i].Rows); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,203 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// Account archives: everything stored for a user, read in one snapshot and
+// restored in one transaction.
+
+// ExportArchive reads the user and every task of the user, oldest first.
+// Format, Version and ExportedAt are left to the caller.
+func (s *Sqlite) ExportArchive(ctx context.Context, userId int64) (*types.Archive, error) {
+	tx, err := s.Reader.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	archive := &types.Archive{Tasks: []types.ArchiveTask{}}
+	u := &archive.User
+	err = tx.QueryRowContext(ctx, "SELECT id, name, email FROM user WHERE id = ?", userId).Scan(&u.Id, &u.Name, &u.Email)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT "+taskColumns+", client_id FROM todo WHERE user_id = ? ORDER BY id", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		task, err := scanArchiveTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		archive.Tasks = append(archive.Tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return archive, tx.Commit()
+}
+
+func scanArchiveTask(row rowScanner) (types.ArchiveTask, error) {
+	var task types.ArchiveTask
+	var due sql.NullTime
+	var clientId sql.NullString
+	t := &task.TaskMetaData
+	err := row.Scan(&t.Id, &t.Title, &t.Description, &t.Priority, &t.Completed, &t.CreatedAt, &t.UpdatedAt, &due, &clientId)
+	if due.Valid {
+		t.DueAt = &due.Time
+	}
+	task.ClientId = clientId.String
+	return task, err
+}
+
+// archiveKey identifies a task without a client id: an archived task
+// matches an existing one with the same title created at the same time
+func archiveKey(title string, createdAt time.Time) string {
+	return fmt.Sprintf("%s\x00%d", title, createdAt.UnixNano())
+}
+
+// ImportArchive restores the tasks of an archive, already validated, in one
+// transaction. With userId 0 the archive's user is created first, or
+// ErrEmailTaken returned if its email is in use; otherwise the tasks go to
+// that user and the archive's user record is ignored. Tasks always get new
+// ids. An archived task matching an existing one, by client id or else by
+// title and creation time, is handled as conflict says: "skip" keeps the
+// existing task, "replace" overwrites it and "duplicate" adds the archived
+// one anyway, without its client id if that is taken. Only tasks there
+// before the import are matched; identical tasks within the archive are
+// all restored. With dryRun the
+// transaction is rolled back, so the ids reported are only what they would
+// have been.
+func (s *Sqlite) ImportArchive(ctx context.Context, userId int64, archive *types.Archive, conflict string, dryRun bool) (*types.ArchiveImport, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &types.ArchiveImport{DryRun: dryRun, UserId: userId, TaskIds: make(map[int64]int64, len(archive.Tasks))}
+	if userId == 0 {
+		var taken bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM user WHERE email = ?)", archive.User.Email).Scan(&taken); err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, storage.ErrEmailTaken
+		}
+		res, err := tx.ExecContext(ctx, "INSERT INTO user (name, email) VALUES (?, ?)", archive.User.Name, archive.User.Email)
+		if err != nil {
+			return nil, err
+		}
+		if result.UserId, err = res.LastInsertId(); err != nil {
+			return nil, err
+		}
+		result.UserCreated = true
+	}
+
+	// Both maps hold the tasks there before the import; takenClientIds also
+	// those the import adds, which the unique index keeps from repeating
+	byClientId := make(map[string]int64)
+	byKey := make(map[string]int64)
+	takenClientIds := make(map[string]bool)
+	rows, err := tx.QueryContext(ctx, "SELECT id, title, created_at, client_id FROM todo WHERE user_id = ?", result.UserId)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int64
+		var title string
+		var createdAt time.Time
+		var clientId sql.NullString
+		if err := rows.Scan(&id, &title, &createdAt, &clientId); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if clientId.Valid {
+			byClientId[clientId.String] = id
+			takenClientIds[clientId.String] = true
+		}
+		byKey[archiveKey(title, createdAt)] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, task := range archive.Tasks {
+		existing, found := byClientId[task.ClientId]
+		if task.ClientId == "" || !found {
+			existing, found = byKey[archiveKey(task.Title, task.CreatedAt)]
+		}
+
+		switch {
+		case found && conflict == "skip":
+			result.Skipped++
+			result.TaskIds[task.Id] = existing
+			continue
+		case found && conflict == "replace":
+			var wasCompleted bool
+			if err := tx.QueryRowContext(ctx, "SELECT completed FROM todo WHERE id = ?", existing).Scan(&wasCompleted); err != nil {
+				return nil, err
+			}
+			_, err := tx.ExecContext(ctx, `UPDATE todo SET title = ?, description = ?, priority = ?, completed = ?, created_at = ?, updated_at = ?, due_at = ?
+			WHERE id = ?`, task.Title, task.Description, task.Priority, task.Completed, task.CreatedAt, task.UpdatedAt, nullableTime(task.DueAt), existing)
+			if err != nil {
+				return nil, err
+			}
+			result.Replaced++
+			result.TaskIds[task.Id] = existing
+			restored := task.TaskMetaData
+			restored.Id = existing
+			result.Changes = append(result.Changes, types.ArchiveChange{Task: restored, Replaced: true, WasCompleted: wasCompleted})
+			continue
+		}
+
+		var clientId interface{}
+		if task.ClientId != "" && !takenClientIds[task.ClientId] {
+			clientId = task.ClientId
+		}
+		// Which fields changed last is not archived; all date from the last update
+		at := task.UpdatedAt
+		res, err := tx.ExecContext(ctx, `INSERT INTO todo (user_id, client_id, title, description, priority, completed, created_at, updated_at, due_at,
+		title_updated_at, description_updated_at, priority_updated_at, completed_updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			result.UserId, clientId, task.Title, task.Description, task.Priority, task.Completed, task.CreatedAt, task.UpdatedAt, nullableTime(task.DueAt),
+			at, at, at, at)
+		if err != nil {
+			return nil, err
+		}
+		taskId, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		if clientId != nil {
+			takenClientIds[task.ClientId] = true
+		}
+		result.Created++
+		result.TaskIds[task.Id] = taskId
+		restored := task.TaskMetaData
+		restored.Id = taskId
+		result.Changes = append(result.Changes, types.ArchiveChange{Task: restored})
+	}
+
+	if dryRun {
+		return result, nil
+	}
+	return result, tx.Commit()
+}
+
+// nullableTime stores a missing time as NULL
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
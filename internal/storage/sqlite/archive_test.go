@@ -0,0 +1,140 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// archiveTask builds an archived task created at the given minute of a day
+func archiveTask(id int64, title string, minute int, clientId string) types.ArchiveTask {
+	at := time.Date(2026, 3, 1, 9, minute, 0, 0, time.UTC)
+	return types.ArchiveTask{
+		TaskMetaData: types.TaskMetaData{Id: id, Title: title, Description: title + " notes", Priority: "low", CreatedAt: at, UpdatedAt: at},
+		ClientId:     clientId,
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	userId := newTestUser(t, s)
+	now := time.Now().UTC().Truncate(time.Second)
+	due := now.Add(48 * time.Hour)
+
+	for _, m := range []types.SyncMutation{
+		{Op: "upsert", ClientId: "phone-1", Title: ptr("Buy milk"), Description: ptr("2 litres"), Priority: ptr("high"), UpdatedAt: now},
+		{Op: "upsert", ClientId: "phone-2", Title: ptr("Pay rent"), Description: ptr("by the 1st"), Priority: ptr("medium"), Completed: ptr(true), UpdatedAt: now},
+	} {
+		applyOne(t, s, userId, m)
+	}
+	taskId, err := s.AddNewTask(ctx, userId, "Walk dog", "twice", "low", false, now, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetTaskDue(ctx, userId, taskId, &due, now); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := s.ExportArchive(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archive.Tasks) != 3 || archive.User.Id != userId || archive.Tasks[0].ClientId != "phone-1" {
+		t.Fatalf("exported %+v", archive)
+	}
+	archive.User.Email = "restored@example.com"
+
+	result, err := s.ImportArchive(ctx, 0, archive, "skip", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.UserCreated || result.UserId == userId || result.Created != 3 || len(result.Changes) != 3 {
+		t.Fatalf("import into a new user: %+v", result)
+	}
+	restored, err := s.ExportArchive(ctx, result.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, task := range restored.Tasks {
+		want := archive.Tasks[i]
+		if task.Id != result.TaskIds[want.Id] || task.ClientId != want.ClientId || task.Title != want.Title || task.Description != want.Description ||
+			task.Priority != want.Priority || task.Completed != want.Completed || !task.CreatedAt.Equal(want.CreatedAt) || !task.UpdatedAt.Equal(want.UpdatedAt) ||
+			(task.DueAt == nil) != (want.DueAt == nil) || (task.DueAt != nil && !task.DueAt.Equal(*want.DueAt)) {
+			t.Errorf("task %d restored as %+v, want %+v", want.Id, task, want)
+		}
+	}
+
+	// The same email again is refused, whole
+	if _, err := s.ImportArchive(ctx, 0, archive, "skip", false); !errors.Is(err, storage.ErrEmailTaken) {
+		t.Errorf("second import of the user: %v, want %v", err, storage.ErrEmailTaken)
+	}
+}
+
+func TestArchiveImportDuplicates(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	userId := newTestUser(t, s)
+
+	// Identical tasks within one archive are all restored
+	archive := &types.Archive{Tasks: []types.ArchiveTask{
+		archiveTask(7, "Water plants", 0, ""),
+		archiveTask(8, "Water plants", 0, ""),
+		archiveTask(9, "Call mum", 1, "c-1"),
+		archiveTask(10, "Call mum", 1, "c-1"),
+	}}
+	result, err := s.ImportArchive(ctx, userId, archive, "skip", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 4 || result.Skipped != 0 || result.TaskIds[7] == result.TaskIds[8] || result.TaskIds[9] == result.TaskIds[10] {
+		t.Fatalf("import with duplicates: %+v", result)
+	}
+	stored, err := s.ExportArchive(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Tasks) != 4 || stored.Tasks[2].ClientId != "c-1" || stored.Tasks[3].ClientId != "" {
+		t.Fatalf("stored %+v", stored.Tasks)
+	}
+
+	// Against what is there now, each conflict mode does its thing
+	for _, tc := range []struct {
+		conflict string
+		created  int
+		replaced int
+		skipped  int
+	}{
+		{"skip", 0, 0, 4},
+		{"replace", 0, 4, 0},
+		{"duplicate", 4, 0, 0},
+	} {
+		result, err := s.ImportArchive(ctx, userId, archive, tc.conflict, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Created != tc.created || result.Replaced != tc.replaced || result.Skipped != tc.skipped {
+			t.Errorf("%s: %+v", tc.conflict, result)
+		}
+	}
+	// Dry runs store nothing
+	if stored, _ := s.ExportArchive(ctx, userId); len(stored.Tasks) != 4 {
+		t.Errorf("%d tasks after dry runs, want 4", len(stored.Tasks))
+	}
+
+	// A replace reports what the task was
+	edited := &types.Archive{Tasks: []types.ArchiveTask{archiveTask(1, "Call mum", 1, "c-1")}}
+	edited.Tasks[0].Completed = true
+	result, err = s.ImportArchive(ctx, userId, edited, "replace", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changes) != 1 || !result.Changes[0].Replaced || result.Changes[0].WasCompleted || !result.Changes[0].Task.Completed ||
+		result.Changes[0].Task.Id != stored.Tasks[2].Id {
+		t.Errorf("replace changes: %+v", result.Changes)
+	}
+}
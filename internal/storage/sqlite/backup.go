@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// BackupTo copies the live database into a new file at path using the SQLite
+// online backup API. All pages are copied in one step from a read
+// transaction: in WAL mode writers carry on meanwhile, and the copy is a
+// consistent snapshot that a busy database can never force to restart.
+func (s *Sqlite) BackupTo(ctx context.Context, path string) error {
+	dest, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+	// Reading from the read pool leaves the writer free during the copy
+	srcConn, err := s.Reader.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriver interface{}) error {
+		return srcConn.Raw(func(srcDriver interface{}) error {
+			backup, err := destDriver.(*sqlite3.SQLiteConn).Backup("main", srcDriver.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			done, err := backup.Step(-1)
+			if err != nil {
+				backup.Finish()
+				return err
+			}
+			if !done {
+				backup.Finish()
+				return fmt.Errorf("backup stopped before the last page")
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// VerifyBackup opens the database file at path read-only and checks it can
+// replace the live database: it must pass the integrity and foreign key
+// checks, hold the expected tables and not be newer than this build. It
+// returns the schema version of the file.
+func VerifyBackup(ctx context.Context, path string) (int, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	s := &Sqlite{Db: db}
+
+	problems, err := s.IntegrityCheck(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("not a readable database: %w", err)
+	}
+	if len(problems) > 0 {
+		return 0, fmt.Errorf("integrity check failed: %s", problems[0])
+	}
+
+	version, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if version > LatestSchemaVersion() {
+		return 0, fmt.Errorf("schema version %d is newer than this build supports (%d)", version, LatestSchemaVersion())
+	}
+	for _, table := range []string{"user", "todo"} {
+		var exists bool
+		err := db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)", table).Scan(&exists)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			return 0, fmt.Errorf("table %s is missing", table)
+		}
+	}
+	return version, nil
+}
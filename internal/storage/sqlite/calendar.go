@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Due dates, calendar feed tokens and the UIDs of imported calendar tasks.
+// They came after the Storage interface and are kept off it like the other
+// feature stores.
+
+// SetTaskDue sets when a task is due, or clears it when due is nil, and
+// stamps the task as updated at updatedAt
+func (s *Sqlite) SetTaskDue(ctx context.Context, userId int64, taskId int64, due *time.Time, updatedAt time.Time) error {
+	result, err := s.Db.ExecContext(ctx, "UPDATE todo SET due_at = ?, updated_at = ? WHERE id = ? AND user_id = ?", nullableTime(due), updatedAt, taskId, userId)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return taskNotFound(taskId, userId)
+	}
+	return nil
+}
+
+// SetCalendarToken replaces the hash of the user's calendar feed token, so
+// any earlier token stops working. An empty hash turns the feed off.
+func (s *Sqlite) SetCalendarToken(ctx context.Context, userId int64, hash string) error {
+	var value interface{}
+	if hash != "" {
+		value = hash
+	}
+	result, err := s.Db.ExecContext(ctx, "UPDATE user SET calendar_token_hash = ? WHERE id = ?", value, userId)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with id %d does not exist", userId)
+	}
+	return nil
+}
+
+// CalendarUser returns the user whose calendar feed token has the hash;
+// found is false when no user has it
+func (s *Sqlite) CalendarUser(ctx context.Context, hash string) (userId int64, found bool, err error) {
+	err = s.Reader.QueryRowContext(ctx, "SELECT id FROM user WHERE calendar_token_hash = ?", hash).Scan(&userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return userId, err == nil, err
+}
+
+// InstanceId is the random id of the database, set when it was created,
+// that tells its calendar UIDs apart from another server's
+func (s *Sqlite) InstanceId(ctx context.Context) (string, error) {
+	var id string
+	err := s.Reader.QueryRowContext(ctx, "SELECT id FROM instance").Scan(&id)
+	return id, err
+}
+
+// TaskForUID finds the user's task a calendar UID stands for: the task with
+// taskId when the UID is one this instance gave out, taskId 0 otherwise, or
+// the task imported under the UID. found is false when there is none.
+func (s *Sqlite) TaskForUID(ctx context.Context, userId int64, taskId int64, uid string) (id int64, found bool, err error) {
+	err = s.Reader.QueryRowContext(ctx, "SELECT id FROM todo WHERE user_id = ? AND (id = ? OR client_id = ?) ORDER BY id = ? DESC LIMIT 1",
+		userId, taskId, uid, taskId).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return id, err == nil, err
+}
+
+// SetTaskUID records the calendar UID a task was imported under, as its
+// client id, so importing the calendar again finds it
+func (s *Sqlite) SetTaskUID(ctx context.Context, userId int64, taskId int64, uid string) error {
+	result, err := s.Db.ExecContext(ctx, "UPDATE todo SET client_id = ? WHERE id = ? AND user_id = ?", uid, taskId, userId)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return taskNotFound(taskId, userId)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// migration is one step of the schema history. Steps are applied in order
+// and the number applied is tracked in PRAGMA user_version, so a step must
+// never be edited or removed once released; add a new one instead.
+type migration struct {
+	name string
+	up   func(ctx context.Context, tx *sql.Tx) error
+}
+
+var migrations = []migration{
+	{
+		// Databases created before versioning already have these tables,
+		// hence IF NOT EXISTS and the priority column check.
+		name: "create user and todo tables",
+		up: func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS user(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	email TEXT NOT NULL UNIQUE
+	)`)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS todo(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL,
+	priority TEXT NOT NULL DEFAULT 'medium',
+	completed BOOL DEFAULT FALSE,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+	)`)
+			if err != nil {
+				return err
+			}
+			return addColumnIfMissing(ctx, tx, "todo", "priority", "TEXT NOT NULL DEFAULT 'medium'")
+		},
+	},
+	{
+		// Every task query filters on the owner, most also on completion
+		name: "index tasks by user and completion",
+		up: func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS todo_user_completed ON todo(user_id, completed)")
+			return err
+		},
+	},
+	{
+		// Webhook subscriptions and the outbox of deliveries, with every
+		// attempt made for them
+		name: "create webhook tables",
+		up: func(ctx context.Context, tx *sql.Tx) error {
+			for _, stmt := range []string{`CREATE TABLE webhook(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	url TEXT NOT NULL,
+	events TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	active BOOL NOT NULL DEFAULT TRUE,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+	)`, `CREATE INDEX webhook_user ON webhook(user_id)`,
+				`CREATE TABLE webhook_delivery(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	webhook_id INTEGER NOT NULL,
+	event_type TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME,
+	created_at DATETIME NOT NULL,
+	delivered_at DATETIME,
+	FOREIGN KEY (webhook_id) REFERENCES webhook(id) ON DELETE CASCADE
+	)`, `CREATE INDEX webhook_delivery_due ON webhook_delivery(status, next_attempt_at)`,
+				`CREATE INDEX webhook_delivery_webhook ON webhook_delivery(webhook_id, id)`,
+				`CREATE TABLE webhook_attempt(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	delivery_id INTEGER NOT NULL,
+	attempted_at DATETIME NOT NULL,
+	status_code INTEGER NOT NULL,
+	error TEXT NOT NULL DEFAULT '',
+	duration_ms INTEGER NOT NULL,
+	FOREIGN KEY (delivery_id) REFERENCES webhook_delivery(id) ON DELETE CASCADE
+	)`, `CREATE INDEX webhook_attempt_delivery ON webhook_attempt(delivery_id)`,
+			} {
+				if _, err := tx.ExecContext(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// Delta sync: tasks can carry an id chosen by an offline client,
+		// remember when each field last changed for per-field
+		// last-writer-wins, and every change is logged for change tokens.
+		// Triggers keep the clocks and the log current whichever path
+		// writes a task. The log keeps only the latest change per task, so
+		// a deleted task stays behind as its tombstone.
+		name: "track task changes for sync",
+		up: func(ctx context.Context, tx *sql.Tx) error {
+			for _, column := range []string{"client_id TEXT", "title_updated_at DATETIME", "description_updated_at DATETIME",
+				"priority_updated_at DATETIME", "completed_updated_at DATETIME"} {
+				name, definition, _ := strings.Cut(column, " ")
+				if err := addColumnIfMissing(ctx, tx, "todo", name, definition); err != nil {
+					return err
+				}
+			}
+			stmts := []string{
+				`CREATE UNIQUE INDEX todo_user_client_id ON todo(user_id, client_id) WHERE client_id IS NOT NULL`,
+				`CREATE TABLE task_change(
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	task_id INTEGER NOT NULL,
+	client_id TEXT,
+	deleted BOOL NOT NULL DEFAULT FALSE,
+	changed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+				`CREATE INDEX task_change_user ON task_change(user_id, seq)`,
+				`CREATE INDEX task_change_task ON task_change(task_id)`,
+				`INSERT INTO task_change (user_id, task_id, client_id) SELECT user_id, id, client_id FROM todo ORDER BY id`,
+				// Which fields of existing tasks changed last is unknown
+				`UPDATE todo SET title_updated_at = updated_at, description_updated_at = updated_at,
+	priority_updated_at = updated_at, completed_updated_at = updated_at`,
+				`CREATE TRIGGER todo_change_insert AFTER INSERT ON todo BEGIN
+	DELETE FROM task_change WHERE task_id = NEW.id;
+	INSERT INTO task_change (user_id, task_id, client_id) VALUES (NEW.user_id, NEW.id, NEW.client_id);
+	END`,
+				// A task moved to another user is a deletion for its old owner
+				`CREATE TRIGGER todo_change_update AFTER UPDATE ON todo BEGIN
+	DELETE FROM task_change WHERE task_id = NEW.id;
+	INSERT INTO task_change (user_id, task_id, client_id) VALUES (NEW.user_id, NEW.id, NEW.client_id);
+	INSERT INTO task_change (user_id, task_id, client_id, deleted) SELECT OLD.user_id, OLD.id, OLD.client_id, TRUE WHERE OLD.user_id != NEW.user_id;
+	END`,
+				`CREATE TRIGGER todo_change_delete AFTER DELETE ON todo BEGIN
+	DELETE FROM task_change WHERE task_id = OLD.id;
+	INSERT INTO task_change (user_id, task_id, client_id, deleted) VALUES (OLD.user_id, OLD.id, OLD.client_id, TRUE);
+	END`,
+				`CREATE TRIGGER user_change_delete AFTER DELETE ON user BEGIN
+	DELETE FROM task_change WHERE user_id = OLD.id;
+	END`,
+			}
+			// Writes that change a field without setting its clock, like
+			// EditTask and MarkComplete, stamp it with the task's updated_at
+			for _, field := range []string{"title", "description", "priority", "completed"} {
+				stmts = append(stmts, fmt.Sprintf(`CREATE TRIGGER todo_%[1]s_clock AFTER UPDATE OF %[1]s ON todo
+	WHEN NEW.%[1]s IS NOT OLD.%[1]s AND NEW.%[1]s_updated_at IS OLD.%[1]s_updated_at BEGIN
+	UPDATE todo SET %[1]s_updated_at = NEW.updated_at WHERE id = NEW.id;
+	END`, field))
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.ExecContext(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// Tasks may be due at a time, shown in calendar feeds. A user's
+		// feed is reached with a secret token of which only the SHA-256
+		// hash is kept.
+		name: "add due dates and calendar feed tokens",
+		up: func(ctx context.Context, tx *sql.Tx) error {
+			if err := addColumnIfMissing(ctx, tx, "todo", "due_at", "DATETIME"); err != nil {
+				return err
+			}
+			if err := addColumnIfMissing(ctx, tx, "user", "calendar_token_hash", "TEXT"); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, "CREATE UNIQUE INDEX user_calendar_token ON user(calendar_token_hash) WHERE calendar_token_hash IS NOT NULL")
+			return err
+		},
+	},
+	{
+		// Calendar UIDs name the database they come from, so a task exported
+		// by another server is not taken for the task with the same id here.
+		// Feeds change their UIDs once, after which they stay put.
+		name: "give the instance an id for calendar UIDs",
+		up: func(ctx context.Context, tx *sql.Tx) error {
+			for _, stmt := range []string{
+				`CREATE TABLE instance(id TEXT NOT NULL)`,
+				`INSERT INTO instance (id) VALUES (lower(hex(randomblob(8))))`,
+			} {
+				if _, err := tx.ExecContext(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// LatestSchemaVersion is the schema version this build migrates to
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+// SchemaVersion returns the number of migrations applied to the database
+func (s *Sqlite) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := s.Db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+// CheckMigrations fails unless every migration of this build is applied
+func (s *Sqlite) CheckMigrations(ctx context.Context) error {
+	version, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if version != LatestSchemaVersion() {
+		return fmt.Errorf("schema version %d, expected %d", version, LatestSchemaVersion())
+	}
+	return nil
+}
+
+// Migrate applies every pending migration, each in its own transaction
+func (s *Sqlite) Migrate(ctx context.Context) error {
+	current, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d)", current, len(migrations))
+	}
+	for i := current; i < len(migrations); i++ {
+		if err := s.applyMigration(ctx, i+1, migrations[i]); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", i+1, migrations[i].name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Sqlite) applyMigration(ctx context.Context, version int, m migration) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.up(ctx, tx); err != nil {
+		return err
+	}
+	// PRAGMA does not accept bound parameters
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// addColumnIfMissing adds a column unless the table already has it
+func addColumnIfMissing(ctx context.Context, tx *sql.Tx, table string, column string, definition string) error {
+	var count int
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
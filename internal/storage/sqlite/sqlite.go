@@ -1,8 +1,12 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -11,439 +15,255 @@ import (
 )
 
 
+// Sqlite keeps two pools on the same file: Db holds the single connection
+// every write goes through, Reader the read-only connections for queries.
 type Sqlite struct{
 	Db *sql.DB
+	Reader *sql.DB
+
+	stmts statements
 }
 
 func New(cfg *config.Config) (*Sqlite, error) {
-	db, err := sql.Open("sqlite3", cfg.Storage_path)
+	db, err := sql.Open("sqlite3", dsn(cfg.Storage_path, cfg.SQLite, false))
 	if err != nil {
 		return nil, err
 	}
-	
-	// Create user table
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS user(
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	name TEXT NOT NULL,
-	email TEXT NOT NULL UNIQUE
-	)`)
-	if err != nil {
-		return nil, err 
+	// One writer at a time is all SQLite allows; queueing here instead of in
+	// the busy handler keeps concurrent writes from failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxIdleTime(0)
+
+	s := &Sqlite{
+		Db: db,
 	}
-	
-	// Enable foreign key constraints
-	_, err = db.Exec("PRAGMA foreign_keys = ON")
-	if err != nil {
+
+	// Bring the schema up to date
+	if err := s.Migrate(context.Background()); err != nil {
+		db.Close()
 		return nil, err
 	}
-	
-	// Create todo table
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS todo(
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	user_id INTEGER NOT NULL,
-	title TEXT NOT NULL,
-	description TEXT NOT NULL,
-	priority TEXT NOT NULL DEFAULT 'medium',
-	completed BOOL DEFAULT FALSE,
-	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
-	)`)
-	if err != nil {
-		return nil, err 
-	}
-	
-	// Add priority column if it doesn't exist (for existing databases)
-	_, err = db.Exec(`ALTER TABLE todo ADD COLUMN priority TEXT NOT NULL DEFAULT 'medium'`)
-	if err != nil {
-		// Ignore error if column already exists
-		// SQLite will return an error if the column already exists
-	}
-	
-	return &Sqlite{
-		Db: db,
-	}, nil
-}
-
-
 
-func (s *Sqlite) CreateUser(name string,email string)(int64, error){
-	stmt,err:= s.Db.Prepare(
-		"INSERT INTO user (name,email) VALUES(?,?)")
-	if err!=nil{
-		return 0,err 
+	// The read pool is opened once the file and its journal mode exist
+	reader, err := sql.Open("sqlite3", dsn(cfg.Storage_path, cfg.SQLite, true))
+	if err != nil {
+		db.Close()
+		return nil, err
 	}
-	defer stmt.Close()
+	reader.SetMaxOpenConns(cfg.SQLite.MaxReadConns)
+	reader.SetMaxIdleConns(cfg.SQLite.MaxReadConns)
+	reader.SetConnMaxIdleTime(cfg.SQLite.ConnMaxIdleTime)
+	s.Reader = reader
 
-	res,err := stmt.Exec(name,email)
-	if err!=nil{
-		return 0,err 
-	}
-	
-	id,err:=res.LastInsertId()
-	
-	if err!=nil{
-		return 0,err 
+	if err := s.prepare(context.Background()); err != nil {
+		reader.Close()
+		db.Close()
+		return nil, err
 	}
 
-	return id,nil
+	return s, nil
 }
 
-func (s *Sqlite) UserExists(userid int64)(bool,error){
-	var exists bool
-	query := "SELECT EXISTS(SELECT 1 FROM user WHERE id = ?)"
-	err := s.Db.QueryRow(query, userid).Scan(&exists)
-	if err != nil {
-		return false, err
+// dsn builds the connection string for the database at path. The driver runs
+// the pragmas given as parameters on every new connection.
+func dsn(path string, opts config.SQLite, readOnly bool) string {
+	params := url.Values{}
+	params.Set("_busy_timeout", strconv.FormatInt(opts.BusyTimeout.Milliseconds(), 10))
+	params.Set("_foreign_keys", strconv.FormatBool(opts.ForeignKeys))
+	if readOnly {
+		params.Set("mode", "ro")
+		params.Set("_query_only", "true")
+	} else {
+		params.Set("_journal_mode", strings.ToUpper(opts.JournalMode))
+		params.Set("_synchronous", strings.ToUpper(opts.Synchronous))
+		// Take the write lock when a transaction begins, not at its first
+		// write, so a transaction never has to be retried halfway through
+		params.Set("_txlock", "immediate")
 	}
-	return exists, nil
+	return "file:" + path + "?" + params.Encode()
 }
 
-func (s *Sqlite) AddNewTask(userid int64,title string,description string,priority string,completed bool,created_at time.Time,updated_at time.Time)(int64, error){
-	var completedInt int
-	if completed {
-		completedInt = 1
-	} else {
-		completedInt = 0
-	}
-	
-	stmt,err:= s.Db.Prepare(
-		"INSERT INTO todo (user_id,title,description,priority,completed,created_at,updated_at) VALUES(?,?,?,?,?,?,?)")
-	if err!=nil{
-		return 0,err 
-	}
-	defer stmt.Close()
 
-	res,err := stmt.Exec(userid,title,description,priority,completedInt,created_at,updated_at)
-	if err!=nil{
-		return 0,err 
-	}
-	
-	id,err:=res.LastInsertId()
-	
-	if err!=nil{
-		return 0,err 
-	}
 
-	return id,nil
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-
-func (s *Sqlite)GetTaskForId(userid int64) ([]types.TaskMetaData,error){
-	stmt,err:= s.Db.Prepare("SELECT title,description,priority,completed,created_at,updated_at FROM todo WHERE user_id = ? ORDER BY CASE priority WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 END, created_at DESC")
-	if err!= nil{
-		return []types.TaskMetaData{},err
+// scanTask maps one row selected with taskColumns
+func scanTask(row rowScanner) (types.TaskMetaData, error) {
+	var task types.TaskMetaData
+	var due sql.NullTime
+	err := row.Scan(&task.Id, &task.Title, &task.Description, &task.Priority, &task.Completed, &task.CreatedAt, &task.UpdatedAt, &due)
+	if due.Valid {
+		task.DueAt = &due.Time
 	}
-	defer stmt.Close()
-	rows,err := stmt.Query(userid)
-	if err!= nil{
-		return []types.TaskMetaData{},err
+	return task, err
+}
+
+// queryTasks runs a task query and maps every row; no rows gives nil
+func queryTasks(ctx context.Context, stmt *sql.Stmt, args ...interface{}) ([]types.TaskMetaData, error) {
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
-	var tasks []types.TaskMetaData
 
-	for rows.Next(){
-		var task types.TaskMetaData
-		var completedInt int
-		err:= rows.Scan(&task.Title,&task.Description,&task.Priority,&completedInt,&task.CreatedAt,&task.UpdatedAt)
-		if err!= nil{
-			return nil,err
+	var tasks []types.TaskMetaData
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
 		}
-		task.Completed = completedInt == 1
 		tasks = append(tasks, task)
 	}
-	return tasks,nil
+	return tasks, rows.Err()
+}
+
+// execTaskChange runs a statement changing one task, reporting a task that is
+// missing or owned by someone else
+// notFoundError reports a row that does not exist or is not the user's. It
+// unwraps to sql.ErrNoRows so callers can tell it from a failing query.
+type notFoundError struct {
+	msg string
 }
 
+func (e *notFoundError) Error() string { return e.msg }
 
-func (s *Sqlite) MarkComplete(userid int64, taskid int64) error {
-	stmt, err := s.Db.Prepare("UPDATE todo SET completed = TRUE, updated_at = ? WHERE id = ? AND user_id = ?")
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-	
-	result, err := stmt.Exec(time.Now(), taskid, userid)
+func (e *notFoundError) Unwrap() error { return sql.ErrNoRows }
+
+func taskNotFound(taskid int64, userid int64) error {
+	return &notFoundError{msg: fmt.Sprintf("task with id %d does not belong to user with id %d or does not exist", taskid, userid)}
+}
+
+func execTaskChange(ctx context.Context, stmt *sql.Stmt, userid int64, taskid int64, args ...interface{}) error {
+	result, err := stmt.ExecContext(ctx, append(args, taskid, userid)...)
 	if err != nil {
 		return err
 	}
-	
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	
 	if rowsAffected == 0 {
-		return fmt.Errorf("task with id %d does not belong to user with id %d or does not exist", taskid, userid)
+		return taskNotFound(taskid, userid)
 	}
-	
 	return nil
 }
 
-func (s *Sqlite) MarkIncomplete(userid int64, taskid int64) error {
-	stmt, err := s.Db.Prepare("UPDATE todo SET completed = FALSE, updated_at = ? WHERE id = ? AND user_id = ?")
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-	
-	result, err := stmt.Exec(time.Now(), taskid, userid)
+func (s *Sqlite) CreateUser(ctx context.Context, name string, email string) (int64, error) {
+	res, err := s.stmts.createUser.ExecContext(ctx, name, email)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	
-	if rowsAffected == 0 {
-		return fmt.Errorf("task with id %d does not belong to user with id %d or does not exist", taskid, userid)
-	}
-	
-	return nil
+	return res.LastInsertId()
 }
 
+func (s *Sqlite) UserExists(ctx context.Context, userid int64) (bool, error) {
+	var exists bool
+	err := s.stmts.userExists.QueryRowContext(ctx, userid).Scan(&exists)
+	return exists, err
+}
 
-func (s *Sqlite) DeletingTask(userid int64, taskid int64) error {
-	stmt, err := s.Db.Prepare("DELETE FROM todo WHERE id = ? AND user_id = ?")
+func (s *Sqlite) AddNewTask(ctx context.Context, userid int64, title string, description string, priority string, completed bool, created_at time.Time, updated_at time.Time) (int64, error) {
+	res, err := s.stmts.addTask.ExecContext(ctx, userid, title, description, priority, completed, created_at, updated_at)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer stmt.Close()
-	
-	result, err := stmt.Exec(taskid, userid)
-	if err != nil {
-		return err
-	}
-	
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	
-	if rowsAffected == 0 {
-		return fmt.Errorf("task with id %d does not belong to user with id %d or does not exist", taskid, userid)
-	}
-	
-	return nil
+	return res.LastInsertId()
 }
 
-func (s *Sqlite) GetSingleTask(userid int64, taskid int64) (*types.TaskMetaData, error) {
-	stmt, err := s.Db.Prepare("SELECT title, description, priority, completed, created_at, updated_at FROM todo WHERE id = ? AND user_id = ?")
-	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
-	
-	var task types.TaskMetaData
-	var completedInt int
-	err = stmt.QueryRow(taskid, userid).Scan(&task.Title, &task.Description, &task.Priority, &completedInt, &task.CreatedAt, &task.UpdatedAt)
+func (s *Sqlite) GetTaskForId(ctx context.Context, userid int64) ([]types.TaskMetaData, error) {
+	return queryTasks(ctx, s.stmts.listTasks, userid)
+}
+
+func (s *Sqlite) MarkComplete(ctx context.Context, userid int64, taskid int64) error {
+	return execTaskChange(ctx, s.stmts.setCompleted, userid, taskid, true, time.Now())
+}
+
+func (s *Sqlite) MarkIncomplete(ctx context.Context, userid int64, taskid int64) error {
+	return execTaskChange(ctx, s.stmts.setCompleted, userid, taskid, false, time.Now())
+}
+
+func (s *Sqlite) DeletingTask(ctx context.Context, userid int64, taskid int64) error {
+	return execTaskChange(ctx, s.stmts.deleteTask, userid, taskid)
+}
+
+func (s *Sqlite) GetSingleTask(ctx context.Context, userid int64, taskid int64) (*types.TaskMetaData, error) {
+	task, err := scanTask(s.stmts.getTask.QueryRowContext(ctx, taskid, userid))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("task with id %d does not belong to user with id %d or does not exist", taskid, userid)
+		return nil, taskNotFound(taskid, userid)
 	}
 	if err != nil {
 		return nil, err
 	}
-	
-	task.Completed = completedInt == 1
 	return &task, nil
 }
 
-func (s *Sqlite) EditTask(userid int64, taskid int64, title string, description string, priority string) error {
-	stmt, err := s.Db.Prepare("UPDATE todo SET title = ?, description = ?, priority = ?, updated_at = ? WHERE id = ? AND user_id = ?")
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-	
-	result, err := stmt.Exec(title, description, priority, time.Now(), taskid, userid)
-	if err != nil {
-		return err
-	}
-	
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	
-	if rowsAffected == 0 {
-		return fmt.Errorf("task with id %d does not belong to user with id %d or does not exist", taskid, userid)
-	}
-	
-	return nil
+func (s *Sqlite) EditTask(ctx context.Context, userid int64, taskid int64, title string, description string, priority string) error {
+	return execTaskChange(ctx, s.stmts.editTask, userid, taskid, title, description, priority, time.Now())
 }
 
-
-func (s *Sqlite) GetUser(userId int64)(*types.User,error){
-	stmt,err := s.Db.Prepare("SELECT name, email FROM user WHERE id = ?")
-	if err!= nil{
-		return nil,err
-	}
-	defer stmt.Close()
+func (s *Sqlite) GetUser(ctx context.Context, userId int64) (*types.User, error) {
 	var user types.User
-	err = stmt.QueryRow(userId).Scan(&user.Name,&user.Email)
-	if err!=nil{
-		return nil,err
+	err := s.stmts.getUser.QueryRowContext(ctx, userId).Scan(&user.Id, &user.Name, &user.Email)
+	if err != nil {
+		return nil, err
 	}
-	return &user,nil
+	return &user, nil
 }
 
-func (s *Sqlite) DeleteUser(userid int64)(error){
-	stmt, err := s.Db.Prepare("DELETE FROM user WHERE id = ?")
+func (s *Sqlite) DeleteUser(ctx context.Context, userid int64) error {
+	result, err := s.stmts.deleteUser.ExecContext(ctx, userid)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
-	
-	result, err := stmt.Exec(userid)
-	if err != nil {
-		return err
-	}
-	
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	
 	if rowsAffected == 0 {
 		return fmt.Errorf("user with id %d does not exist", userid)
 	}
-	
 	return nil
 }
 
-
-func (s *Sqlite)GetCompletedTask(userid int64) ([]types.TaskMetaData,error){
-	stmt,err:= s.Db.Prepare("SELECT title,description,priority,created_at,updated_at FROM todo WHERE user_id = ? AND completed = 1 ORDER BY CASE priority WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 END, created_at DESC")
-	if err!= nil{
-		return nil,err
-	}
-	defer stmt.Close()
-	rows,err := stmt.Query(userid)
-	if err!= nil{
-		return nil,err
-	}
-	defer rows.Close()
-	var tasks []types.TaskMetaData
-
-	for rows.Next(){
-		var task types.TaskMetaData
-		err:= rows.Scan(&task.Title,&task.Description,&task.Priority,&task.CreatedAt,&task.UpdatedAt)
-		if err!= nil{
-			return nil,err
-		}
-		task.Completed = true 
-		tasks = append(tasks, task)
-	}
-	if len(tasks) == 0 {
-		return nil, nil
-	}
-	
-	return tasks,nil
+func (s *Sqlite) GetCompletedTask(ctx context.Context, userid int64) ([]types.TaskMetaData, error) {
+	return queryTasks(ctx, s.stmts.listTasksByStatus, userid, true)
 }
 
-func (s *Sqlite)GetIncompletedTask(userid int64) ([]types.TaskMetaData,error){
-	stmt,err:= s.Db.Prepare("SELECT title,description,priority,created_at,updated_at FROM todo WHERE user_id = ? AND completed = 0 ORDER BY CASE priority WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 END, created_at DESC")
-	if err!= nil{
-		return nil,err
-	}
-	defer stmt.Close()
-	rows,err := stmt.Query(userid)
-	if err!= nil{
-		return nil,err
-	}
-	defer rows.Close()
-	var tasks []types.TaskMetaData
-
-	for rows.Next(){
-		var task types.TaskMetaData
-		err:= rows.Scan(&task.Title,&task.Description,&task.Priority,&task.CreatedAt,&task.UpdatedAt)
-		if err!= nil{
-			return nil,err
-		}
-		task.Completed = false 
-		tasks = append(tasks, task)
-	}
-	
-	if len(tasks) == 0 {
-		return nil, nil
-	}
-	
-	return tasks,nil
+func (s *Sqlite) GetIncompletedTask(ctx context.Context, userid int64) ([]types.TaskMetaData, error) {
+	return queryTasks(ctx, s.stmts.listTasksByStatus, userid, false)
 }
 
-func (s *Sqlite)GetTaskWithTitle(userid int64,keyword string) ([]types.TaskMetaData,error){
-	// Search in both title AND description for better results
-	stmt,err:= s.Db.Prepare("SELECT title, description, priority, completed, created_at, updated_at FROM todo WHERE user_id = ? AND (title LIKE ? OR description LIKE ?) ORDER BY CASE priority WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 END, created_at DESC")
-	if err!= nil{
-		return nil,err
-	}
-	defer stmt.Close()
-	rows,err := stmt.Query(userid, keyword, keyword)
-	if err!= nil{
-		return nil,err
-	}
-	defer rows.Close()
-	var tasks []types.TaskMetaData
+// GetTaskWithTitle searches both title and description; keyword is a LIKE
+// pattern
+func (s *Sqlite) GetTaskWithTitle(ctx context.Context, userid int64, keyword string) ([]types.TaskMetaData, error) {
+	return queryTasks(ctx, s.stmts.searchTasks, userid, keyword, keyword)
+}
 
-	for rows.Next(){
-		var task types.TaskMetaData
-		var completedInt int
-		err:= rows.Scan(&task.Title, &task.Description, &task.Priority, &completedInt, &task.CreatedAt, &task.UpdatedAt)
-		if err!= nil{
-			return nil,err
-		}
-		task.Completed = completedInt == 1
-		tasks = append(tasks, task)
-	}
-	
-	if len(tasks) == 0 {
-		return nil, nil
-	}
-	
-	return tasks,nil
+// GetTaskWithFilters is GetTaskWithTitle limited to completed tasks when
+// status is "completed" and to incomplete tasks otherwise
+func (s *Sqlite) GetTaskWithFilters(ctx context.Context, userid int64, keyword string, status string) ([]types.TaskMetaData, error) {
+	return queryTasks(ctx, s.stmts.searchByStatus, userid, status == "completed", keyword, keyword)
 }
 
-func (s *Sqlite)GetTaskWithFilters(userid int64, keyword string, status string)([]types.TaskMetaData,error){
-	var completedFilter int
-	if status == "completed" {
-		completedFilter = 1
-	} else {
-		completedFilter = 0
-	}
-	
-	stmt,err:= s.Db.Prepare("SELECT title, description, priority, completed, created_at, updated_at FROM todo WHERE user_id = ? AND completed = ? AND (title LIKE ? OR description LIKE ?) ORDER BY CASE priority WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 END, created_at DESC")
-	if err!= nil{
-		return nil,err
+// Close releases the prepared statements and closes both connection pools
+func (s *Sqlite) Close() error {
+	stmtErr := s.stmts.close()
+	readErr := s.Reader.Close()
+	if err := s.Db.Close(); err != nil {
+		return err
 	}
-	defer stmt.Close()
-	rows,err := stmt.Query(userid, completedFilter, keyword, keyword)
-	if err!= nil{
-		return nil,err
+	if readErr != nil {
+		return readErr
 	}
-	defer rows.Close()
-	var tasks []types.TaskMetaData
-
-	for rows.Next(){
-		var task types.TaskMetaData
-		var completedInt int
-		err:= rows.Scan(&task.Title, &task.Description, &task.Priority, &completedInt, &task.CreatedAt, &task.UpdatedAt)
-		if err!= nil{
-			return nil,err
-		}
-		task.Completed = completedInt == 1
-		tasks = append(tasks, task)
-	}
-	
-	if len(tasks) == 0 {
-		return nil, nil
+	return stmtErr
+}
+// Ping checks that the database can be reached through both pools
+func (s *Sqlite) Ping(ctx context.Context) error {
+	if err := s.Db.PingContext(ctx); err != nil {
+		return err
 	}
-	
-	return tasks,nil
+	return s.Reader.PingContext(ctx)
 }
-
-// Close closes the database connection
-func (s *Sqlite) Close() error {
-	return s.Db.Close()
-}
\ No newline at end of file
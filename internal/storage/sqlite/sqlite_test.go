@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/config"
+)
+
+// newTestStore opens a migrated database in a temporary directory; args are
+// extra config flags
+func newTestStore(tb testing.TB, args ...string) *Sqlite {
+	tb.Helper()
+	cfg, err := config.Load(append([]string{"-storage_path", filepath.Join(tb.TempDir(), "todo.db")}, args...))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	s, err := New(cfg)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { s.Close() })
+	return s
+}
+
+// newTestUser creates a user with a unique email
+func newTestUser(tb testing.TB, s *Sqlite) int64 {
+	tb.Helper()
+	userId, err := s.CreateUser(context.Background(), "Ann", fmt.Sprintf("ann-%d@example.com", time.Now().UnixNano()))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return userId
+}
+
+func TestConcurrentWritesAndReads(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	userId := newTestUser(t, s)
+
+	const writers, perWriter = 20, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*writers*perWriter)
+	for i := 0; i < writers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWriter; j++ {
+				now := time.Now()
+				taskId, err := s.AddNewTask(ctx, userId, fmt.Sprintf("task %d", j), "d", "low", false, now, now)
+				if err == nil {
+					err = s.MarkComplete(ctx, userId, taskId)
+				}
+				if err != nil {
+					errs <- err
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWriter; j++ {
+				if _, err := s.GetTaskForId(ctx, userId); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		// SQLITE_BUSY would show up here
+		t.Error(err)
+	}
+
+	tasks, err := s.GetCompletedTask(ctx, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != writers*perWriter {
+		t.Errorf("%d completed tasks, want %d", len(tasks), writers*perWriter)
+	}
+}
+
+// holdConns takes n distinct connections from the pool at once
+func holdConns(t *testing.T, db *sql.DB, n int) []*sql.Conn {
+	t.Helper()
+	conns := make([]*sql.Conn, n)
+	for i := range conns {
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conns[i] = conn
+	}
+	return conns
+}
+
+func pragma(t *testing.T, conn *sql.Conn, name string) string {
+	t.Helper()
+	var value string
+	if err := conn.QueryRowContext(context.Background(), "PRAGMA "+name).Scan(&value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestPragmasOnEveryConnection(t *testing.T) {
+	s := newTestStore(t, "-sqlite.max_read_conns", "3", "-sqlite.busy_timeout", "2s")
+
+	// Every read connection, not just the first, gets the pragmas
+	for i, conn := range holdConns(t, s.Reader, 3) {
+		if got := pragma(t, conn, "foreign_keys"); got != "1" {
+			t.Errorf("reader %d: foreign_keys = %s", i, got)
+		}
+		if got := pragma(t, conn, "query_only"); got != "1" {
+			t.Errorf("reader %d: query_only = %s", i, got)
+		}
+		if got := pragma(t, conn, "busy_timeout"); got != "2000" {
+			t.Errorf("reader %d: busy_timeout = %s", i, got)
+		}
+	}
+
+	writer := holdConns(t, s.Db, 1)[0]
+	for name, want := range map[string]string{"foreign_keys": "1", "journal_mode": "wal", "busy_timeout": "2000"} {
+		if got := pragma(t, writer, name); got != want {
+			t.Errorf("writer: %s = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestWriterReconnectKeepsForeignKeys(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	// Force the writer onto a new connection; it must come up with the
+	// pragmas too, or deleting a user would leave its tasks behind
+	s.Db.SetConnMaxLifetime(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	defer s.Db.SetConnMaxLifetime(0)
+
+	userId := newTestUser(t, s)
+	now := time.Now()
+	if _, err := s.AddNewTask(ctx, userId, "t", "d", "low", false, now, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteUser(ctx, userId); err != nil {
+		t.Fatal(err)
+	}
+	var left int
+	if err := s.Reader.QueryRowContext(ctx, "SELECT COUNT(*) FROM todo WHERE user_id = ?", userId).Scan(&left); err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d tasks left after deleting their user", left)
+	}
+
+	if _, err := s.Db.ExecContext(ctx, "INSERT INTO todo (user_id, title, description, priority, completed, created_at, updated_at) VALUES (?, 't', 'd', 'low', 0, ?, ?)", userId, now, now); err == nil {
+		t.Error("a task for a missing user was accepted")
+	}
+}
+
+func TestReaderIsReadOnly(t *testing.T) {
+	s := newTestStore(t)
+	if _, err := s.Reader.Exec("INSERT INTO user (name, email) VALUES ('x', 'x@example.com')"); err == nil {
+		t.Error("the read pool accepted a write")
+	}
+}
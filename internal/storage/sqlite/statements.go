@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// taskColumns is the column list every task query selects, in the order
+// scanTask expects
+const taskColumns = "id, title, description, priority, completed, created_at, updated_at, due_at"
+
+// taskOrder lists tasks by priority, newest first within a priority
+const taskOrder = "ORDER BY CASE priority WHEN 'high' THEN 1 WHEN 'medium' THEN 2 WHEN 'low' THEN 3 END, created_at DESC"
+
+// statements holds every query of the Storage interface, prepared once when
+// the database is opened. Writes are prepared on the write pool and reads on
+// the read pool; database/sql re-prepares them per connection as needed.
+type statements struct {
+	createUser *sql.Stmt
+	userExists *sql.Stmt
+	getUser    *sql.Stmt
+	deleteUser *sql.Stmt
+
+	addTask           *sql.Stmt
+	getTask           *sql.Stmt
+	listTasks         *sql.Stmt
+	listTasksByStatus *sql.Stmt
+	searchTasks       *sql.Stmt
+	searchByStatus    *sql.Stmt
+	setCompleted      *sql.Stmt
+	editTask          *sql.Stmt
+	deleteTask        *sql.Stmt
+
+	all []*sql.Stmt
+}
+
+// prepare compiles every statement, closing the ones already prepared if
+// any of them fails
+func (s *Sqlite) prepare(ctx context.Context) error {
+	var prepared []*sql.Stmt
+	var failed error
+	on := func(db *sql.DB, dest **sql.Stmt, query string) {
+		if failed != nil {
+			return
+		}
+		stmt, err := db.PrepareContext(ctx, query)
+		if err != nil {
+			failed = fmt.Errorf("prepare %q: %w", query, err)
+			return
+		}
+		*dest = stmt
+		prepared = append(prepared, stmt)
+	}
+
+	st := &s.stmts
+	on(s.Db, &st.createUser, "INSERT INTO user (name, email) VALUES (?, ?)")
+	on(s.Reader, &st.userExists, "SELECT EXISTS(SELECT 1 FROM user WHERE id = ?)")
+	on(s.Reader, &st.getUser, "SELECT id, name, email FROM user WHERE id = ?")
+	on(s.Db, &st.deleteUser, "DELETE FROM user WHERE id = ?")
+
+	on(s.Db, &st.addTask, "INSERT INTO todo (user_id, title, description, priority, completed, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	on(s.Reader, &st.getTask, "SELECT "+taskColumns+" FROM todo WHERE id = ? AND user_id = ?")
+	on(s.Reader, &st.listTasks, "SELECT "+taskColumns+" FROM todo WHERE user_id = ? "+taskOrder)
+	on(s.Reader, &st.listTasksByStatus, "SELECT "+taskColumns+" FROM todo WHERE user_id = ? AND completed = ? "+taskOrder)
+	on(s.Reader, &st.searchTasks, "SELECT "+taskColumns+" FROM todo WHERE user_id = ? AND (title LIKE ? OR description LIKE ?) "+taskOrder)
+	on(s.Reader, &st.searchByStatus, "SELECT "+taskColumns+" FROM todo WHERE user_id = ? AND completed = ? AND (title LIKE ? OR description LIKE ?) "+taskOrder)
+	on(s.Db, &st.setCompleted, "UPDATE todo SET completed = ?, updated_at = ? WHERE id = ? AND user_id = ?")
+	on(s.Db, &st.editTask, "UPDATE todo SET title = ?, description = ?, priority = ?, updated_at = ? WHERE id = ? AND user_id = ?")
+	on(s.Db, &st.deleteTask, "DELETE FROM todo WHERE id = ? AND user_id = ?")
+
+	if failed != nil {
+		for _, stmt := range prepared {
+			stmt.Close()
+		}
+		return failed
+	}
+	st.all = prepared
+	return nil
+}
+
+// close releases every prepared statement
+func (st *statements) close() error {
+	var errs []error
+	for _, stmt := range st.all {
+		errs = append(errs, stmt.Close())
+	}
+	return errors.Join(errs...)
+}
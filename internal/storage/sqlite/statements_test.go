@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// The benchmarks compare the prepared statements with the same queries
+// compiled on every call, as they were before statements.go:
+//
+//	go test -run x -bench . -benchmem ./internal/storage/sqlite
+
+// seedTasks gives a new user n tasks and returns the user and a task id
+func seedTasks(b *testing.B, s *Sqlite, n int) (int64, int64) {
+	b.Helper()
+	ctx := context.Background()
+	userId := newTestUser(b, s)
+	priorities := []string{"high", "medium", "low"}
+	var taskId int64
+	for i := 0; i < n; i++ {
+		now := time.Now()
+		id, err := s.AddNewTask(ctx, userId, fmt.Sprintf("task %d", i), "description", priorities[i%3], i%2 == 0, now, now)
+		if err != nil {
+			b.Fatal(err)
+		}
+		taskId = id
+	}
+	return userId, taskId
+}
+
+// unpreparedTasks is queryTasks with the query compiled on every call
+func unpreparedTasks(ctx context.Context, s *Sqlite, query string, args ...interface{}) ([]types.TaskMetaData, error) {
+	rows, err := s.Reader.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tasks []types.TaskMetaData
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+func BenchmarkGetTaskForId(b *testing.B) {
+	s := newTestStore(b)
+	ctx := context.Background()
+	userId, _ := seedTasks(b, s, 50)
+
+	b.Run("prepared", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := s.GetTaskForId(ctx, userId); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("unprepared", func(b *testing.B) {
+		query := "SELECT " + taskColumns + " FROM todo WHERE user_id = ? " + taskOrder
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := unpreparedTasks(ctx, s, query, userId); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetTask(b *testing.B) {
+	s := newTestStore(b)
+	ctx := context.Background()
+	userId, taskId := seedTasks(b, s, 50)
+
+	b.Run("prepared", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := s.GetSingleTask(ctx, userId, taskId); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("unprepared", func(b *testing.B) {
+		query := "SELECT " + taskColumns + " FROM todo WHERE id = ? AND user_id = ?"
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := scanTask(s.Reader.QueryRowContext(ctx, query, taskId, userId)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,294 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// Delta sync. Every task change is logged in task_change by triggers, keyed
+// by a sequence number clients hold as their change token; see the "track
+// task changes for sync" migration.
+
+// syncRow is a task with the time each of its fields last changed
+type syncRow struct {
+	task   types.SyncTask
+	clocks map[string]time.Time
+}
+
+// syncFields are the task fields a mutation can change, in column order
+var syncFields = []string{"title", "description", "priority", "completed"}
+
+const syncColumns = taskColumns + ", client_id, title_updated_at, description_updated_at, priority_updated_at, completed_updated_at"
+
+func scanSyncRow(row rowScanner) (*syncRow, error) {
+	var r syncRow
+	var clientId sql.NullString
+	var due sql.NullTime
+	clocks := make([]sql.NullTime, len(syncFields))
+	t := &r.task
+	err := row.Scan(&t.Id, &t.Title, &t.Description, &t.Priority, &t.Completed, &t.CreatedAt, &t.UpdatedAt, &due,
+		&clientId, &clocks[0], &clocks[1], &clocks[2], &clocks[3])
+	if err != nil {
+		return nil, err
+	}
+	t.ClientId = clientId.String
+	if due.Valid {
+		t.DueAt = &due.Time
+	}
+	// Fields never changed since the task was created date from it
+	r.clocks = make(map[string]time.Time, len(syncFields))
+	for i, field := range syncFields {
+		r.clocks[field] = t.CreatedAt
+		if clocks[i].Valid {
+			r.clocks[field] = clocks[i].Time
+		}
+	}
+	return &r, nil
+}
+
+// ChangeSeq returns the sequence number of the latest change to any task.
+// A change token beyond it was not issued by this database.
+func (s *Sqlite) ChangeSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	err := s.Reader.QueryRowContext(ctx, "SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'task_change'), 0)").Scan(&seq)
+	return seq, err
+}
+
+// ChangesSince returns up to limit of the user's task changes after seq, each
+// task in its current state or as a tombstone. From seq 0 it lists every
+// task and leaves out tombstones, as the client has nothing to delete.
+func (s *Sqlite) ChangesSince(ctx context.Context, userId int64, seq int64, limit int) (*types.SyncChanges, error) {
+	// One read transaction sees a single snapshot of the log and the tasks
+	tx, err := s.Reader.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	type change struct {
+		seq      int64
+		taskId   int64
+		clientId sql.NullString
+		deleted  bool
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT seq, task_id, client_id, deleted FROM task_change
+	WHERE user_id = ? AND seq > ? AND (? > 0 OR NOT deleted) ORDER BY seq LIMIT ?`, userId, seq, seq, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	var log []change
+	for rows.Next() {
+		var c change
+		if err := rows.Scan(&c.seq, &c.taskId, &c.clientId, &c.deleted); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		log = append(log, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	changes := &types.SyncChanges{Tasks: []types.SyncTask{}, Deleted: []types.SyncTombstone{}}
+	if len(log) > limit {
+		log = log[:limit]
+		changes.More = true
+	}
+	if changes.More {
+		changes.Seq = log[len(log)-1].seq
+	} else {
+		// Nothing left for this user, so the token can move past other
+		// users' changes too
+		err := tx.QueryRowContext(ctx, "SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'task_change'), 0)").Scan(&changes.Seq)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, c := range log {
+		if c.deleted {
+			changes.Deleted = append(changes.Deleted, types.SyncTombstone{TaskId: c.taskId, ClientId: c.clientId.String})
+			continue
+		}
+		row, err := scanSyncRow(tx.QueryRowContext(ctx, "SELECT "+syncColumns+" FROM todo WHERE id = ? AND user_id = ?", c.taskId, userId))
+		if err != nil {
+			return nil, err
+		}
+		changes.Tasks = append(changes.Tasks, row.task)
+	}
+	return changes, tx.Commit()
+}
+
+// ApplySync applies a client's mutations in order, in one transaction, and
+// reports the outcome of each. Conflicts are settled per field: a field
+// takes the mutation's value only when the mutation is newer than the
+// field's last change on the server, ties going to the server. A delete
+// wins only over a task not changed after it, and a task deleted on the
+// server stays deleted. Mutations that are invalid or lose a conflict do not
+// fail the batch.
+func (s *Sqlite) ApplySync(ctx context.Context, userId int64, mutations []types.SyncMutation) ([]types.SyncResult, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	validate := validator.New()
+	results := make([]types.SyncResult, 0, len(mutations))
+	for _, m := range mutations {
+		result := types.SyncResult{Op: m.Op, TaskId: m.TaskId, ClientId: m.ClientId}
+		if err := validate.Struct(m); err != nil {
+			result.Status, result.Error = "invalid", err.Error()
+			results = append(results, result)
+			continue
+		}
+		m.UpdatedAt = m.UpdatedAt.UTC()
+
+		existing, err := loadSyncRow(ctx, tx, userId, m)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case m.Op == "delete":
+			err = syncDelete(ctx, tx, existing, m, &result)
+		case existing == nil && m.TaskId != 0:
+			result.Status, result.Error = "conflict", "task was deleted on the server"
+		case existing == nil:
+			err = syncCreate(ctx, tx, userId, m, validate, &result)
+		default:
+			err = syncUpdate(ctx, tx, existing, m, validate, &result)
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, tx.Commit()
+}
+
+// loadSyncRow finds the task a mutation is about; nil when there is none
+func loadSyncRow(ctx context.Context, tx *sql.Tx, userId int64, m types.SyncMutation) (*syncRow, error) {
+	var row *syncRow
+	var err error
+	if m.TaskId != 0 {
+		row, err = scanSyncRow(tx.QueryRowContext(ctx, "SELECT "+syncColumns+" FROM todo WHERE id = ? AND user_id = ?", m.TaskId, userId))
+	} else {
+		row, err = scanSyncRow(tx.QueryRowContext(ctx, "SELECT "+syncColumns+" FROM todo WHERE client_id = ? AND user_id = ?", m.ClientId, userId))
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return row, err
+}
+
+func syncDelete(ctx context.Context, tx *sql.Tx, existing *syncRow, m types.SyncMutation, result *types.SyncResult) error {
+	if existing == nil {
+		// Already gone; deleting again is not an error
+		result.Status = "unchanged"
+		return nil
+	}
+	result.TaskId, result.ClientId = existing.task.Id, existing.task.ClientId
+	for _, field := range syncFields {
+		if !m.UpdatedAt.After(existing.clocks[field]) {
+			result.Status, result.Error = "unchanged", "task changed on the server after the deletion"
+			result.Task = &existing.task
+			return nil
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM todo WHERE id = ?", existing.task.Id); err != nil {
+		return err
+	}
+	result.Status = "deleted"
+	return nil
+}
+
+func syncCreate(ctx context.Context, tx *sql.Tx, userId int64, m types.SyncMutation, validate *validator.Validate, result *types.SyncResult) error {
+	var task types.TaskMetaData
+	if m.Title != nil {
+		task.Title = *m.Title
+	}
+	if m.Description != nil {
+		task.Description = *m.Description
+	}
+	if m.Priority != nil {
+		task.Priority = *m.Priority
+	}
+	if m.Completed != nil {
+		task.Completed = *m.Completed
+	}
+	if err := validate.Struct(task); err != nil {
+		result.Status, result.Error = "invalid", err.Error()
+		return nil
+	}
+	at := m.UpdatedAt
+	res, err := tx.ExecContext(ctx, `INSERT INTO todo (user_id, client_id, title, description, priority, completed, created_at, updated_at,
+	title_updated_at, description_updated_at, priority_updated_at, completed_updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		userId, m.ClientId, task.Title, task.Description, task.Priority, task.Completed, at, at, at, at, at, at)
+	if err != nil {
+		return err
+	}
+	taskId, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	row, err := scanSyncRow(tx.QueryRowContext(ctx, "SELECT "+syncColumns+" FROM todo WHERE id = ?", taskId))
+	if err != nil {
+		return err
+	}
+	result.Status, result.TaskId, result.Task = "created", taskId, &row.task
+	result.AppliedFields = syncFields
+	return nil
+}
+
+func syncUpdate(ctx context.Context, tx *sql.Tx, existing *syncRow, m types.SyncMutation, validate *validator.Validate, result *types.SyncResult) error {
+	task := existing.task
+	result.TaskId, result.ClientId = task.Id, task.ClientId
+	clocks := existing.clocks
+
+	// take applies one field if the mutation is newer than its clock
+	take := func(field string, differs bool, apply func()) {
+		if !differs {
+			return
+		}
+		if !m.UpdatedAt.After(clocks[field]) {
+			result.StaleFields = append(result.StaleFields, field)
+			return
+		}
+		apply()
+		clocks[field] = m.UpdatedAt
+		result.AppliedFields = append(result.AppliedFields, field)
+	}
+	take("title", m.Title != nil && *m.Title != task.Title, func() { task.Title = *m.Title })
+	take("description", m.Description != nil && *m.Description != task.Description, func() { task.Description = *m.Description })
+	take("priority", m.Priority != nil && *m.Priority != task.Priority, func() { task.Priority = *m.Priority })
+	take("completed", m.Completed != nil && *m.Completed != task.Completed, func() { task.Completed = *m.Completed })
+
+	if len(result.AppliedFields) == 0 {
+		result.Status, result.Task = "unchanged", &existing.task
+		return nil
+	}
+	if err := validate.Struct(task.TaskMetaData); err != nil {
+		result.Status, result.Error = "invalid", err.Error()
+		result.AppliedFields, result.StaleFields = nil, nil
+		return nil
+	}
+
+	if m.UpdatedAt.After(task.UpdatedAt) {
+		task.UpdatedAt = m.UpdatedAt
+	}
+	_, err := tx.ExecContext(ctx, `UPDATE todo SET title = ?, description = ?, priority = ?, completed = ?, updated_at = ?,
+	title_updated_at = ?, description_updated_at = ?, priority_updated_at = ?, completed_updated_at = ? WHERE id = ?`,
+		task.Title, task.Description, task.Priority, task.Completed, task.UpdatedAt,
+		clocks["title"], clocks["description"], clocks["priority"], clocks["completed"], task.Id)
+	if err != nil {
+		return err
+	}
+	result.Status, result.Task = "updated", &task
+	return nil
+}
@@ -0,0 +1,195 @@
+package sqlite
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// applyOne applies a single mutation and returns its result
+func applyOne(t *testing.T, s *Sqlite, userId int64, m types.SyncMutation) types.SyncResult {
+	t.Helper()
+	results, err := s.ApplySync(context.Background(), userId, []types.SyncMutation{m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results[0]
+}
+
+func TestSyncLastWriterWinsPerField(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	userId := newTestUser(t, s)
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	res := applyOne(t, s, userId, types.SyncMutation{Op: "upsert", ClientId: "c-1", Title: ptr("Buy milk"),
+		Description: ptr("2 litres"), Priority: ptr("low"), UpdatedAt: created})
+	if res.Status != "created" || res.TaskId == 0 || res.Task.ClientId != "c-1" {
+		t.Fatalf("create: %+v", res)
+	}
+	taskId := res.TaskId
+
+	// The server changes the title now; the priority keeps its clock
+	if err := s.EditTask(ctx, userId, taskId, "Buy oat milk", "2 litres", "low"); err != nil {
+		t.Fatal(err)
+	}
+
+	// An offline edit from before that: the title lost, the priority is
+	// newer than anything the server did to it
+	res = applyOne(t, s, userId, types.SyncMutation{Op: "upsert", ClientId: "c-1", Title: ptr("Buy soy milk"),
+		Priority: ptr("high"), UpdatedAt: created.Add(time.Hour)})
+	if res.Status != "updated" || !slices.Equal(res.AppliedFields, []string{"priority"}) || !slices.Equal(res.StaleFields, []string{"title"}) {
+		t.Fatalf("offline edit: %+v", res)
+	}
+	task, err := s.GetSingleTask(ctx, userId, taskId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Title != "Buy oat milk" || task.Priority != "high" || task.Description != "2 litres" {
+		t.Errorf("after merging: %+v", task)
+	}
+
+	// A tie goes to the server
+	res = applyOne(t, s, userId, types.SyncMutation{Op: "upsert", TaskId: taskId, Priority: ptr("medium"), UpdatedAt: created.Add(time.Hour)})
+	if res.Status != "unchanged" || !slices.Equal(res.StaleFields, []string{"priority"}) {
+		t.Errorf("tie: %+v", res)
+	}
+
+	// Values that already match change nothing, stale or not
+	res = applyOne(t, s, userId, types.SyncMutation{Op: "upsert", TaskId: taskId, Priority: ptr("high"), UpdatedAt: created})
+	if res.Status != "unchanged" || len(res.StaleFields) != 0 {
+		t.Errorf("same value: %+v", res)
+	}
+
+	// A later edit wins everywhere, and an invalid one changes nothing
+	later := time.Now().Add(time.Hour)
+	res = applyOne(t, s, userId, types.SyncMutation{Op: "upsert", TaskId: taskId, Title: ptr("Buy bread"), Completed: ptr(true), UpdatedAt: later})
+	if res.Status != "updated" || !slices.Equal(res.AppliedFields, []string{"title", "completed"}) || !res.Task.Completed {
+		t.Errorf("later edit: %+v", res)
+	}
+	res = applyOne(t, s, userId, types.SyncMutation{Op: "upsert", TaskId: taskId, Priority: ptr("urgent"), UpdatedAt: later.Add(time.Hour)})
+	if res.Status != "invalid" {
+		t.Errorf("invalid priority: %+v", res)
+	}
+	if task, _ := s.GetSingleTask(ctx, userId, taskId); task.Priority != "high" {
+		t.Errorf("an invalid mutation changed the priority to %s", task.Priority)
+	}
+}
+
+func TestSyncDeletes(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	userId := newTestUser(t, s)
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	res := applyOne(t, s, userId, types.SyncMutation{Op: "upsert", ClientId: "c-1", Title: ptr("t"), Description: ptr("d"),
+		Priority: ptr("low"), UpdatedAt: created})
+	taskId := res.TaskId
+	if err := s.MarkComplete(ctx, userId, taskId); err != nil {
+		t.Fatal(err)
+	}
+
+	// A delete made before the server's change does not win
+	res = applyOne(t, s, userId, types.SyncMutation{Op: "delete", ClientId: "c-1", UpdatedAt: created.Add(time.Hour)})
+	if res.Status != "unchanged" || res.Task == nil || !res.Task.Completed {
+		t.Fatalf("stale delete: %+v", res)
+	}
+
+	res = applyOne(t, s, userId, types.SyncMutation{Op: "delete", ClientId: "c-1", UpdatedAt: time.Now().Add(time.Hour)})
+	if res.Status != "deleted" || res.TaskId != taskId {
+		t.Fatalf("delete: %+v", res)
+	}
+	// Deleted on the server stays deleted
+	res = applyOne(t, s, userId, types.SyncMutation{Op: "upsert", TaskId: taskId, Title: ptr("back"), UpdatedAt: time.Now().Add(2 * time.Hour)})
+	if res.Status != "conflict" {
+		t.Errorf("edit of a deleted task: %+v", res)
+	}
+	res = applyOne(t, s, userId, types.SyncMutation{Op: "delete", TaskId: taskId, UpdatedAt: time.Now()})
+	if res.Status != "unchanged" || res.Error != "" {
+		t.Errorf("second delete: %+v", res)
+	}
+}
+
+func TestSyncChangesAndTombstones(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	userId := newTestUser(t, s)
+	other := newTestUser(t, s)
+	now := time.Now()
+
+	var ids []int64
+	for _, title := range []string{"a", "b", "c"} {
+		id, err := s.AddNewTask(ctx, userId, title, title, "low", false, now, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if _, err := s.AddNewTask(ctx, other, "not mine", "x", "low", false, now, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// A first sync pages through every task of the user
+	first, err := s.ChangesSince(ctx, userId, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.More || len(first.Tasks) != 2 {
+		t.Fatalf("first page: more=%v, %d tasks", first.More, len(first.Tasks))
+	}
+	rest, err := s.ChangesSince(ctx, userId, first.Seq, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest.More || len(rest.Tasks) != 1 || rest.Tasks[0].Id != ids[2] {
+		t.Fatalf("second page: %+v", rest)
+	}
+	latest, err := s.ChangeSeq(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest.Seq != latest {
+		t.Errorf("a finished sync ends at %d, not the latest change %d", rest.Seq, latest)
+	}
+
+	// Since then: one task edited, one deleted
+	if err := s.EditTask(ctx, userId, ids[0], "a2", "a", "low"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeletingTask(ctx, userId, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := s.ChangesSince(ctx, userId, rest.Seq, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Tasks) != 1 || changes.Tasks[0].Title != "a2" {
+		t.Errorf("changed tasks: %+v", changes.Tasks)
+	}
+	if len(changes.Deleted) != 1 || changes.Deleted[0].TaskId != ids[1] {
+		t.Errorf("tombstones: %+v", changes.Deleted)
+	}
+
+	// Nothing changed after the returned token
+	again, err := s.ChangesSince(ctx, userId, changes.Seq, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Tasks) != 0 || len(again.Deleted) != 0 {
+		t.Errorf("changes after the latest token: %+v", again)
+	}
+
+	// Starting over lists what is left and no tombstones
+	all, err := s.ChangesSince(ctx, userId, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Tasks) != 2 || len(all.Deleted) != 0 {
+		t.Errorf("from scratch: %d tasks, %d tombstones", len(all.Tasks), len(all.Deleted))
+	}
+}
@@ -0,0 +1,264 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// Webhook subscriptions and their delivery outbox. Event types are stored
+// comma separated.
+
+const webhookColumns = "id, user_id, url, events, secret, active, created_at"
+
+const deliveryColumns = "id, webhook_id, event_type, payload, status, attempts, next_attempt_at, created_at, delivered_at"
+
+func scanWebhook(row rowScanner) (types.Webhook, error) {
+	var hook types.Webhook
+	var events string
+	err := row.Scan(&hook.Id, &hook.UserId, &hook.URL, &events, &hook.Secret, &hook.Active, &hook.CreatedAt)
+	hook.Events = strings.Split(events, ",")
+	return hook, err
+}
+
+func scanDelivery(row rowScanner) (types.WebhookDelivery, error) {
+	var d types.WebhookDelivery
+	var payload string
+	var next, delivered sql.NullTime
+	err := row.Scan(&d.Id, &d.WebhookId, &d.EventType, &payload, &d.Status, &d.Attempts, &next, &d.CreatedAt, &delivered)
+	d.Payload = []byte(payload)
+	if next.Valid {
+		d.NextAttemptAt = &next.Time
+	}
+	if delivered.Valid {
+		d.DeliveredAt = &delivered.Time
+	}
+	return d, err
+}
+
+// subscribed reports whether a webhook wants an event type
+func subscribed(hook types.Webhook, eventType string) bool {
+	for _, e := range hook.Events {
+		if e == "*" || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Sqlite) CreateWebhook(ctx context.Context, hook types.Webhook) (int64, error) {
+	res, err := s.Db.ExecContext(ctx, "INSERT INTO webhook (user_id, url, events, secret, active, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		hook.UserId, hook.URL, strings.Join(hook.Events, ","), hook.Secret, hook.Active, hook.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (s *Sqlite) ListWebhooks(ctx context.Context, userId int64) ([]types.Webhook, error) {
+	rows, err := s.Reader.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhook WHERE user_id = ? ORDER BY id", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []types.Webhook
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (s *Sqlite) GetWebhook(ctx context.Context, userId int64, webhookId int64) (*types.Webhook, error) {
+	hook, err := scanWebhook(s.Reader.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhook WHERE id = ? AND user_id = ?", webhookId, userId))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook with id %d does not belong to user with id %d or does not exist", webhookId, userId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// DeleteWebhook removes a webhook along with its deliveries
+func (s *Sqlite) DeleteWebhook(ctx context.Context, userId int64, webhookId int64) error {
+	res, err := s.Db.ExecContext(ctx, "DELETE FROM webhook WHERE id = ? AND user_id = ?", webhookId, userId)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("webhook with id %d does not belong to user with id %d or does not exist", webhookId, userId)
+	}
+	return nil
+}
+
+// EnqueueDeliveries queues the payload for every active webhook of the user
+// subscribed to the event type and returns how many were queued
+func (s *Sqlite) EnqueueDeliveries(ctx context.Context, userId int64, eventType string, payload []byte, at time.Time) (int, error) {
+	hooks, err := s.ListWebhooks(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	queued := 0
+	for _, hook := range hooks {
+		if !hook.Active || !subscribed(hook, eventType) {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO webhook_delivery (webhook_id, event_type, payload, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?)",
+			hook.Id, eventType, string(payload), at, at)
+		if err != nil {
+			return 0, err
+		}
+		queued++
+	}
+	return queued, tx.Commit()
+}
+
+// DueDeliveries returns up to limit pending deliveries whose next attempt is
+// due, oldest first
+func (s *Sqlite) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]types.PendingDelivery, error) {
+	rows, err := s.Reader.QueryContext(ctx, `SELECT d.id, d.webhook_id, d.event_type, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.created_at, d.delivered_at, w.url, w.secret
+	FROM webhook_delivery d JOIN webhook w ON w.id = d.webhook_id
+	WHERE d.status = 'pending' AND d.next_attempt_at <= ? AND w.active
+	ORDER BY d.next_attempt_at, d.id LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []types.PendingDelivery
+	for rows.Next() {
+		var p types.PendingDelivery
+		var payload string
+		var next, delivered sql.NullTime
+		d := &p.Delivery
+		err := rows.Scan(&d.Id, &d.WebhookId, &d.EventType, &payload, &d.Status, &d.Attempts, &next, &d.CreatedAt, &delivered, &p.URL, &p.Secret)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		if next.Valid {
+			d.NextAttemptAt = &next.Time
+		}
+		due = append(due, p)
+	}
+	return due, rows.Err()
+}
+
+// RecordAttempt stores the outcome of one attempt and moves the delivery to
+// status. A pending delivery is retried at nextAttemptAt.
+func (s *Sqlite) RecordAttempt(ctx context.Context, deliveryId int64, attempt types.WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO webhook_attempt (delivery_id, attempted_at, status_code, error, duration_ms) VALUES (?, ?, ?, ?, ?)",
+		deliveryId, attempt.AttemptedAt, attempt.StatusCode, attempt.Error, attempt.DurationMs)
+	if err != nil {
+		return err
+	}
+
+	var next, delivered interface{}
+	switch status {
+	case "pending":
+		next = nextAttemptAt
+	case "delivered":
+		delivered = attempt.AttemptedAt
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE webhook_delivery SET status = ?, attempts = attempts + 1, next_attempt_at = ?, delivered_at = ? WHERE id = ?",
+		status, next, delivered, deliveryId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListDeliveries returns the latest deliveries of a webhook, newest first
+func (s *Sqlite) ListDeliveries(ctx context.Context, userId int64, webhookId int64, limit int) ([]types.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, userId, webhookId); err != nil {
+		return nil, err
+	}
+	rows, err := s.Reader.QueryContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_delivery WHERE webhook_id = ? ORDER BY id DESC LIMIT ?", webhookId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []types.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDelivery returns one delivery of the user's webhook with its attempts
+func (s *Sqlite) GetDelivery(ctx context.Context, userId int64, webhookId int64, deliveryId int64) (*types.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, userId, webhookId); err != nil {
+		return nil, err
+	}
+	d, err := scanDelivery(s.Reader.QueryRowContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_delivery WHERE id = ? AND webhook_id = ?", deliveryId, webhookId))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("delivery with id %d does not belong to webhook with id %d or does not exist", deliveryId, webhookId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.Reader.QueryContext(ctx, "SELECT attempted_at, status_code, error, duration_ms FROM webhook_attempt WHERE delivery_id = ? ORDER BY id", deliveryId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a types.WebhookAttempt
+		if err := rows.Scan(&a.AttemptedAt, &a.StatusCode, &a.Error, &a.DurationMs); err != nil {
+			return nil, err
+		}
+		d.History = append(d.History, a)
+	}
+	return &d, rows.Err()
+}
+
+// Redeliver queues a fresh delivery of an earlier delivery's payload, due
+// immediately, and returns it
+func (s *Sqlite) Redeliver(ctx context.Context, userId int64, webhookId int64, deliveryId int64, at time.Time) (*types.WebhookDelivery, error) {
+	original, err := s.GetDelivery(ctx, userId, webhookId, deliveryId)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Db.ExecContext(ctx, "INSERT INTO webhook_delivery (webhook_id, event_type, payload, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?)",
+		webhookId, original.EventType, string(original.Payload), at, at)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetDelivery(ctx, userId, webhookId, id)
+}
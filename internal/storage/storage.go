@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/srmty09/Todo-App/internal/types"
@@ -8,20 +10,24 @@ import (
 
 
 type Storage interface{
-	CreateUser(name string,email string)(int64,error)
-	UserExists(userid int64)(bool,error)
-	AddNewTask(userid int64,title string,description string,priority string,completed bool,created_at time.Time,updated_at time.Time)(int64,error)
-	GetTaskForId(userid int64)([]types.TaskMetaData,error)
-	GetSingleTask(userid int64, taskid int64)(*types.TaskMetaData,error)
-	MarkComplete(userid int64, taskid int64)(error)
-	MarkIncomplete(userid int64, taskid int64)(error)
-	DeletingTask(userid int64, taskid int64)(error)
-	EditTask(userid int64, taskid int64, title string, description string, priority string)(error)
-	GetUser(userid int64)(*types.User,error)
-	DeleteUser(userid int64)(error)
-	GetCompletedTask(userid int64)([]types.TaskMetaData,error)
-	GetIncompletedTask(userid int64)([]types.TaskMetaData,error)
-	GetTaskWithTitle(userid int64,keyword string)([]types.TaskMetaData,error)
-	GetTaskWithFilters(userid int64, keyword string, status string)([]types.TaskMetaData,error)
+	CreateUser(ctx context.Context, name string,email string)(int64,error)
+	UserExists(ctx context.Context, userid int64)(bool,error)
+	AddNewTask(ctx context.Context, userid int64,title string,description string,priority string,completed bool,created_at time.Time,updated_at time.Time)(int64,error)
+	GetTaskForId(ctx context.Context, userid int64)([]types.TaskMetaData,error)
+	GetSingleTask(ctx context.Context, userid int64, taskid int64)(*types.TaskMetaData,error)
+	MarkComplete(ctx context.Context, userid int64, taskid int64)(error)
+	MarkIncomplete(ctx context.Context, userid int64, taskid int64)(error)
+	DeletingTask(ctx context.Context, userid int64, taskid int64)(error)
+	EditTask(ctx context.Context, userid int64, taskid int64, title string, description string, priority string)(error)
+	GetUser(ctx context.Context, userid int64)(*types.User,error)
+	DeleteUser(ctx context.Context, userid int64)(error)
+	GetCompletedTask(ctx context.Context, userid int64)([]types.TaskMetaData,error)
+	GetIncompletedTask(ctx context.Context, userid int64)([]types.TaskMetaData,error)
+	GetTaskWithTitle(ctx context.Context, userid int64,keyword string)([]types.TaskMetaData,error)
+	GetTaskWithFilters(ctx context.Context, userid int64, keyword string, status string)([]types.TaskMetaData,error)
 	Close() error
-}
\ No newline at end of file
+}
+
+// ErrEmailTaken is returned when a user would get an email address that
+// already belongs to another user
+var ErrEmailTaken = errors.New("email already belongs to another user")
@@ -0,0 +1,232 @@
+package taskio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// CSVColumns are the columns written on export, one per TaskMetaData field.
+// They are also the field names imports map columns to.
+var CSVColumns = []string{"id", "title", "description", "priority", "completed", "created_at", "updated_at", "due_at"}
+
+// csvAliases are other header names understood on import without a mapping
+var csvAliases = map[string]string{
+	"name":        "title",
+	"task":        "title",
+	"task_name":   "title",
+	"summary":     "title",
+	"notes":       "description",
+	"note":        "description",
+	"details":     "description",
+	"body":        "description",
+	"done":        "completed",
+	"status":      "completed",
+	"complete":    "completed",
+	"created":     "created_at",
+	"updated":     "updated_at",
+	"modified":    "updated_at",
+	"modified_at": "updated_at",
+	"due":         "due_at",
+	"due_date":    "due_at",
+	"deadline":    "due_at",
+}
+
+// WriteCSV writes the tasks with a header row. Cells a spreadsheet would
+// run as a formula are prefixed with a quote, which ReadCSV strips again.
+func WriteCSV(w io.Writer, tasks []types.TaskMetaData) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVColumns); err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		due := ""
+		if task.DueAt != nil {
+			due = task.DueAt.UTC().Format(time.RFC3339Nano)
+		}
+		err := cw.Write([]string{
+			strconv.FormatInt(task.Id, 10),
+			escapeFormula(task.Title),
+			escapeFormula(task.Description),
+			task.Priority,
+			strconv.FormatBool(task.Completed),
+			task.CreatedAt.UTC().Format(time.RFC3339Nano),
+			task.UpdatedAt.UTC().Format(time.RFC3339Nano),
+			due,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func unescapeFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// headerKey normalises a header cell for matching: case, surrounding space
+// and the separators spreadsheets put in names are ignored
+func headerKey(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// ReadCSV reads tasks from CSV with a header row. Columns are matched to
+// fields by name or a known alias; mapping overrides that, from a header
+// name to a field name or "-" to skip the column. Columns matching no field
+// are returned as ignored. The id column is read but never trusted, imported
+// tasks always get new ids. An error is returned only when the file as a
+// whole is unusable; problems with single rows are reported on the row.
+func ReadCSV(r io.Reader, mapping map[string]string) (rows []Row, ignored []string, err error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("empty file")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(header) > 0 {
+		// Spreadsheets like to start UTF-8 files with a byte order mark
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	overrides := make(map[string]string, len(mapping))
+	for column, field := range mapping {
+		if field != "-" && !isCSVField(field) {
+			return nil, nil, fmt.Errorf("cannot map column %q to unknown field %q", column, field)
+		}
+		overrides[headerKey(column)] = field
+	}
+
+	// fields[i] is the field column i fills, "" for none
+	fields := make([]string, len(header))
+	seen := make(map[string]string)
+	for i, name := range header {
+		key := headerKey(name)
+		field, ok := overrides[key]
+		switch {
+		case ok && field == "-":
+			field = ""
+		case ok:
+		case isCSVField(key):
+			field = key
+		default:
+			field = csvAliases[key]
+		}
+		if field == "" {
+			ignored = append(ignored, name)
+			continue
+		}
+		if other, dup := seen[field]; dup {
+			return nil, nil, fmt.Errorf("columns %q and %q both map to %s", other, name, field)
+		}
+		seen[field] = name
+		fields[i] = field
+	}
+	if _, ok := seen["title"]; !ok {
+		return nil, nil, fmt.Errorf("no column maps to title; name one title or pass a mapping")
+	}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, err
+			}
+			// The reader resynchronises at the next line
+			rows = append(rows, Row{Line: parseErr.StartLine, Errors: []string{parseErr.Err.Error()}})
+			continue
+		}
+		if isBlank(record) {
+			continue
+		}
+		if len(rows) == MaxRows {
+			return nil, nil, fmt.Errorf("more than %d rows", MaxRows)
+		}
+		line, _ := cr.FieldPos(0)
+		rows = append(rows, csvRow(line, fields, record))
+	}
+	return rows, ignored, nil
+}
+
+func isCSVField(name string) bool {
+	return slices.Contains(CSVColumns, name)
+}
+
+func isBlank(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// csvRow fills a task from one record
+func csvRow(line int, fields []string, record []string) Row {
+	row := Row{Line: line}
+	task := &row.Task
+	for i, cell := range record {
+		if i >= len(fields) || fields[i] == "" {
+			continue
+		}
+		value := strings.TrimSpace(cell)
+		var err error
+		switch fields[i] {
+		case "title":
+			task.Title = unescapeFormula(value)
+		case "description":
+			task.Description = unescapeFormula(value)
+		case "priority":
+			task.Priority = strings.ToLower(value)
+		case "completed":
+			task.Completed, err = parseCompleted(value)
+		case "created_at":
+			if value != "" {
+				task.CreatedAt, err = parseTime(value)
+			}
+		case "updated_at":
+			if value != "" {
+				task.UpdatedAt, err = parseTime(value)
+			}
+		case "due_at":
+			if value != "" {
+				var due time.Time
+				if due, err = parseTime(value); err == nil {
+					task.DueAt = &due
+				}
+			}
+		}
+		if err != nil {
+			row.Errors = append(row.Errors, fmt.Sprintf("%s: %s", fields[i], err))
+		}
+	}
+	return row
+}
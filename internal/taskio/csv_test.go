@@ -0,0 +1,160 @@
+package taskio
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	created := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	due := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	tasks := []types.TaskMetaData{
+		{Id: 1, Title: "Buy milk", Description: "2 litres, semi-skimmed", Priority: "high", CreatedAt: created, UpdatedAt: created, DueAt: &due},
+		{Id: 2, Title: "=SUM(A1:A9)", Description: "-5 degrees\nbring a coat", Priority: "low", Completed: true, CreatedAt: created, UpdatedAt: created.Add(time.Hour)},
+		{Id: 3, Title: `Say "hi"`, Description: "@everyone", Priority: "medium", CreatedAt: created, UpdatedAt: created},
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, tasks); err != nil {
+		t.Fatal(err)
+	}
+	// Formulas reach the spreadsheet as text
+	for _, cell := range []string{"'=SUM(A1:A9)", "\"'-5 degrees", "'@everyone"} {
+		if !strings.Contains(buf.String(), cell) {
+			t.Errorf("export does not contain %q:\n%s", cell, buf.String())
+		}
+	}
+
+	rows, ignored, err := ReadCSV(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ignored) != 0 {
+		t.Errorf("ignored %v", ignored)
+	}
+	if len(rows) != len(tasks) {
+		t.Fatalf("read %d rows, want %d", len(rows), len(tasks))
+	}
+	for i, row := range rows {
+		want := tasks[i]
+		want.Id = 0
+		got := row.Task
+		if len(row.Errors) != 0 || got.Title != want.Title || got.Description != want.Description || got.Priority != want.Priority ||
+			got.Completed != want.Completed || !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) ||
+			(got.DueAt == nil) != (want.DueAt == nil) || (got.DueAt != nil && !got.DueAt.Equal(*want.DueAt)) {
+			t.Errorf("row %d: got %+v %v, want %+v", i, got, row.Errors, want)
+		}
+	}
+}
+
+func TestCSVHeaderMapping(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		header  string
+		values  string
+		mapping map[string]string
+		want    types.TaskMetaData
+		ignored []string
+	}{
+		{
+			name:   "aliases",
+			header: "Task Name,Notes,Priority,Done,Due-Date",
+			want:   types.TaskMetaData{Title: "v1", Description: "v2", Priority: "v3", Completed: true},
+		},
+		{
+			name:   "byte order mark and case",
+			header: "\ufeffTITLE, Description ,priority,status,deadline",
+			want:   types.TaskMetaData{Title: "v1", Description: "v2", Priority: "v3", Completed: true},
+		},
+		{
+			name:    "unknown columns are ignored",
+			header:  "title,description,priority,colour,due",
+			want:    types.TaskMetaData{Title: "v1", Description: "v2", Priority: "v3"},
+			ignored: []string{"colour"},
+		},
+		{
+			name:    "explicit mapping",
+			header:  "Headline,Body,Importance,Finished,When",
+			mapping: map[string]string{"headline": "title", "Importance": "priority", "finished": "completed"},
+			want:    types.TaskMetaData{Title: "v1", Description: "v2", Priority: "v3", Completed: true},
+			ignored: []string{"When"},
+		},
+		{
+			name:    "mapping overrides an alias and skips a column",
+			header:  "Name,Summary,Notes,Priority,Done",
+			values:  "v1,v2,v3,v4,no",
+			mapping: map[string]string{"name": "-", "summary": "title"},
+			want:    types.TaskMetaData{Title: "v2", Description: "v3", Priority: "v4"},
+			ignored: []string{"Name"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			values := tc.values
+			if values == "" {
+				values = "v1,v2,v3,yes,2026-03-08"
+			}
+			rows, ignored, err := ReadCSV(strings.NewReader(tc.header+"\n"+values+"\n"), tc.mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ignored, tc.ignored) {
+				t.Errorf("ignored %v, want %v", ignored, tc.ignored)
+			}
+			if len(rows) != 1 || len(rows[0].Errors) != 0 {
+				t.Fatalf("rows: %+v", rows)
+			}
+			got := rows[0].Task
+			got.DueAt = nil
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCSVUnusableFiles(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		input   string
+		mapping map[string]string
+		want    string
+	}{
+		{"empty", "", nil, "empty file"},
+		{"no title", "description,priority\nd,low\n", nil, "no column maps to title"},
+		{"two titles", "title,name\na,b\n", nil, `columns "title" and "name" both map to title`},
+		{"unknown field", "title\na\n", map[string]string{"title": "headline"}, `unknown field "headline"`},
+	} {
+		_, _, err := ReadCSV(strings.NewReader(tc.input), tc.mapping)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestCSVRowErrors(t *testing.T) {
+	input := "title,completed,created_at,due_at\n" +
+		"ok,no,2026-03-01,\n" +
+		",,,\n" +
+		"bad,perhaps,yesterday,2026-03-08\n" +
+		"\"unterminated,no,,\n"
+	rows, _, err := ReadCSV(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("read %d rows, want 3 (blank lines are skipped): %+v", len(rows), rows)
+	}
+	if rows[0].Line != 2 || len(rows[0].Errors) != 0 {
+		t.Errorf("first row: %+v", rows[0])
+	}
+	if rows[1].Line != 4 || len(rows[1].Errors) != 2 || rows[1].Task.Title != "bad" || rows[1].Task.DueAt == nil {
+		t.Errorf("row with bad cells: %+v", rows[1])
+	}
+	if rows[2].Line != 5 || len(rows[2].Errors) != 1 {
+		t.Errorf("unparsable row: %+v", rows[2])
+	}
+}
@@ -0,0 +1,476 @@
+package taskio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// iCalendar (RFC 5545). Tasks are written as VTODOs and, for calendar apps
+// that do not show those, tasks with a due date also as VEVENTs on the day
+// or at the time they are due. Only VTODOs are read back.
+
+const (
+	icalProdId   = "-//Todo-App//Tasks//EN"
+	icalUIDHost  = "todo-app"
+	icalDateTime = "20060102T150405Z"
+	icalLocal    = "20060102T150405"
+	icalDate     = "20060102"
+	// icalLineOctets is the longest a content line may be before folding
+	icalLineOctets = 75
+)
+
+// ICalOptions chooses what WriteICal puts in a calendar
+type ICalOptions struct {
+	// Name is the calendar name shown by clients; empty leaves it out
+	Name string
+	// Todos writes a VTODO for every task
+	Todos bool
+	// Events writes a VEVENT for every task with a due date
+	Events bool
+	// Refresh, when set, tells subscribed clients how often to poll
+	Refresh time.Duration
+	// Now stamps every component
+	Now time.Time
+	// Instance is the id of the database the tasks come from, which makes
+	// their UIDs differ from those of another server's tasks
+	Instance string
+}
+
+// ICalPriority maps a task priority to the iCalendar PRIORITY scale, where
+// 1 is the highest and 9 the lowest
+func ICalPriority(priority string) int {
+	switch priority {
+	case "high":
+		return 1
+	case "medium":
+		return 5
+	case "low":
+		return 9
+	}
+	return 0
+}
+
+// priorityFromICal maps PRIORITY back; 0 means undefined and becomes medium,
+// the default of a task
+func priorityFromICal(value string) (string, error) {
+	n, err := strconv.Atoi(value)
+	switch {
+	case err != nil || n < 0 || n > 9:
+		return "", fmt.Errorf("invalid priority %q, expected 0 to 9", value)
+	case n == 0 || n == 5:
+		return "medium", nil
+	case n < 5:
+		return "high", nil
+	default:
+		return "low", nil
+	}
+}
+
+// allDay reports whether a due time stands for a whole day
+func allDay(t time.Time) bool {
+	t = t.UTC()
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+// icalWriter writes content lines, escaping and folding them
+type icalWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (iw *icalWriter) raw(line string) {
+	if iw.err != nil {
+		return
+	}
+	// Fold at 75 octets without splitting a character; continuation lines
+	// start with a space, which counts towards their length
+	limit := icalLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		if _, iw.err = iw.w.WriteString(line[:cut] + "\r\n "); iw.err != nil {
+			return
+		}
+		line = line[cut:]
+		limit = icalLineOctets - 1
+	}
+	_, iw.err = iw.w.WriteString(line + "\r\n")
+}
+
+func (iw *icalWriter) text(name string, value string) {
+	iw.raw(name + ":" + escapeICalText(value))
+}
+
+func (iw *icalWriter) time(name string, t time.Time) {
+	iw.raw(name + ":" + t.UTC().Format(icalDateTime))
+}
+
+// due writes a due time, as a date when it stands for the whole day
+func (iw *icalWriter) due(name string, t time.Time) {
+	if allDay(t) {
+		iw.raw(name + ";VALUE=DATE:" + t.UTC().Format(icalDate))
+		return
+	}
+	iw.time(name, t)
+}
+
+func escapeICalText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(value)
+}
+
+func unescapeICalText(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// TaskUID is the iCalendar UID of a task's VTODO in the database instance;
+// its VEVENT adds "-due"
+func TaskUID(taskId int64, instance string) string {
+	return fmt.Sprintf("task-%d@%s.%s", taskId, instance, icalUIDHost)
+}
+
+// ParseTaskUID returns the task id in a UID made by TaskUID for the same
+// instance. ok is false for any other UID.
+func ParseTaskUID(uid string, instance string) (taskId int64, ok bool) {
+	id, ok := strings.CutSuffix(uid, "@"+instance+"."+icalUIDHost)
+	if !ok {
+		return 0, false
+	}
+	id, ok = strings.CutPrefix(id, "task-")
+	if !ok {
+		return 0, false
+	}
+	taskId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || taskId <= 0 {
+		return 0, false
+	}
+	return taskId, true
+}
+
+// WriteICal writes the tasks as one VCALENDAR
+func WriteICal(w io.Writer, tasks []types.TaskMetaData, opts ICalOptions) error {
+	iw := &icalWriter{w: bufio.NewWriter(w)}
+	iw.raw("BEGIN:VCALENDAR")
+	iw.raw("VERSION:2.0")
+	iw.raw("PRODID:" + icalProdId)
+	iw.raw("CALSCALE:GREGORIAN")
+	if opts.Name != "" {
+		iw.text("NAME", opts.Name)
+		iw.text("X-WR-CALNAME", opts.Name)
+	}
+	if opts.Refresh > 0 {
+		minutes := fmt.Sprintf("PT%dM", int(opts.Refresh.Minutes()))
+		iw.raw("REFRESH-INTERVAL;VALUE=DURATION:" + minutes)
+		iw.raw("X-PUBLISHED-TTL:" + minutes)
+	}
+
+	for _, task := range tasks {
+		if opts.Todos {
+			iw.raw("BEGIN:VTODO")
+			iw.raw("UID:" + TaskUID(task.Id, opts.Instance))
+			iw.time("DTSTAMP", opts.Now)
+			iw.time("CREATED", task.CreatedAt)
+			iw.time("LAST-MODIFIED", task.UpdatedAt)
+			iw.text("SUMMARY", task.Title)
+			if task.Description != "" {
+				iw.text("DESCRIPTION", task.Description)
+			}
+			iw.raw("PRIORITY:" + strconv.Itoa(ICalPriority(task.Priority)))
+			if task.Completed {
+				iw.raw("STATUS:COMPLETED")
+				// The completion time is not kept; the last change is the
+				// closest there is
+				iw.time("COMPLETED", task.UpdatedAt)
+				iw.raw("PERCENT-COMPLETE:100")
+			} else {
+				iw.raw("STATUS:NEEDS-ACTION")
+			}
+			if task.DueAt != nil {
+				iw.due("DUE", *task.DueAt)
+			}
+			iw.raw("END:VTODO")
+		}
+		if opts.Events && task.DueAt != nil {
+			summary := task.Title
+			if task.Completed {
+				summary = "✓ " + summary
+			}
+			iw.raw("BEGIN:VEVENT")
+			iw.raw("UID:" + strings.Replace(TaskUID(task.Id, opts.Instance), "@", "-due@", 1))
+			iw.time("DTSTAMP", opts.Now)
+			iw.time("CREATED", task.CreatedAt)
+			iw.time("LAST-MODIFIED", task.UpdatedAt)
+			// Without DTEND an all-day event lasts the day and a timed one
+			// is a point in time
+			iw.due("DTSTART", *task.DueAt)
+			iw.text("SUMMARY", summary)
+			if task.Description != "" {
+				iw.text("DESCRIPTION", task.Description)
+			}
+			iw.raw("PRIORITY:" + strconv.Itoa(ICalPriority(task.Priority)))
+			iw.raw("TRANSP:TRANSPARENT")
+			iw.raw("END:VEVENT")
+		}
+	}
+	iw.raw("END:VCALENDAR")
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+// icalLine is one unfolded content line
+type icalLine struct {
+	number int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseICalLine splits "NAME;PARAM=value:VALUE"; colons and semicolons
+// inside quoted parameter values do not count
+func parseICalLine(number int, line string) (icalLine, error) {
+	l := icalLine{number: number, params: map[string]string{}}
+	quoted := false
+	nameEnd, valueStart := -1, -1
+	for i := 0; i < len(line) && valueStart < 0; i++ {
+		switch c := line[i]; {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == ';' && nameEnd < 0:
+			nameEnd = i
+		case c == ':':
+			if nameEnd < 0 {
+				nameEnd = i
+			}
+			valueStart = i + 1
+		}
+	}
+	if valueStart < 0 || nameEnd == 0 {
+		return l, fmt.Errorf("line %d: malformed content line", number)
+	}
+	l.name = strings.ToUpper(line[:nameEnd])
+	l.value = line[valueStart:]
+	if nameEnd < valueStart-1 {
+		for _, param := range splitICalParams(line[nameEnd+1 : valueStart-1]) {
+			key, value, _ := strings.Cut(param, "=")
+			l.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return l, nil
+}
+
+func splitICalParams(s string) []string {
+	var params []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				params = append(params, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(params, s[start:])
+}
+
+// parseICalTime reads a DATE or DATE-TIME value. Times without a zone or
+// with an unknown one are an error unless in UTC; dates become midnight UTC.
+func parseICalTime(l icalLine) (time.Time, error) {
+	value := l.value
+	if l.params["VALUE"] == "DATE" || len(value) == len(icalDate) {
+		t, err := time.Parse(icalDate, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", value)
+		}
+		return t, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icalDateTime, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q", value)
+		}
+		return t, nil
+	}
+	// A floating time has no zone at all; UTC is as good a guess as any
+	loc := time.UTC
+	if tzid := l.params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, fmt.Errorf("unknown time zone %q", tzid)
+		}
+	}
+	t, err := time.ParseInLocation(icalLocal, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+	return t.UTC(), nil
+}
+
+// ReadICal reads the VTODOs of a VCALENDAR as tasks; other components are
+// skipped. Tasks without a PRIORITY are medium and, as in todo.txt, the
+// SUMMARY stands in for a missing DESCRIPTION. The UID is kept on the row so
+// a calendar imported twice can be recognised. An error is returned only
+// when the file as a whole is unusable; problems with single VTODOs are
+// reported on their row, whose line is that of BEGIN:VTODO.
+func ReadICal(r io.Reader) ([]Row, error) {
+	lines, err := unfoldICal(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("empty file")
+	}
+
+	var rows []Row
+	var stack []string
+	var row *Row
+	for _, raw := range lines {
+		l, err := parseICalLine(raw.number, raw.text)
+		if err != nil {
+			return nil, err
+		}
+		switch l.name {
+		case "BEGIN":
+			component := strings.ToUpper(l.value)
+			if len(stack) == 0 && component != "VCALENDAR" {
+				return nil, fmt.Errorf("line %d: expected BEGIN:VCALENDAR", l.number)
+			}
+			stack = append(stack, component)
+			if component == "VTODO" && len(stack) == 2 {
+				if len(rows) == MaxRows {
+					return nil, fmt.Errorf("more than %d tasks", MaxRows)
+				}
+				row = &Row{Line: l.number, Task: types.TaskMetaData{Priority: "medium"}}
+			}
+			continue
+		case "END":
+			component := strings.ToUpper(l.value)
+			if len(stack) == 0 || stack[len(stack)-1] != component {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", l.number, l.value)
+			}
+			stack = stack[:len(stack)-1]
+			if component == "VTODO" && row != nil && len(stack) == 1 {
+				if row.Task.Description == "" {
+					row.Task.Description = row.Task.Title
+				}
+				rows = append(rows, *row)
+				row = nil
+			}
+			continue
+		}
+		if len(stack) == 0 {
+			return nil, fmt.Errorf("line %d: expected BEGIN:VCALENDAR", l.number)
+		}
+		// Properties of the task itself; those of an alarm inside it are not
+		if row != nil && len(stack) == 2 {
+			if err := applyICalProperty(row, l); err != nil {
+				row.Errors = append(row.Errors, fmt.Sprintf("%s: %s", l.name, err))
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("missing END:%s", stack[len(stack)-1])
+	}
+	return rows, nil
+}
+
+// applyICalProperty fills the task field a VTODO property maps to
+func applyICalProperty(row *Row, l icalLine) error {
+	task := &row.Task
+	var err error
+	switch l.name {
+	case "UID":
+		row.UID = strings.TrimSpace(l.value)
+	case "SUMMARY":
+		task.Title = strings.TrimSpace(unescapeICalText(l.value))
+	case "DESCRIPTION":
+		task.Description = strings.TrimSpace(unescapeICalText(l.value))
+	case "PRIORITY":
+		var priority string
+		if priority, err = priorityFromICal(strings.TrimSpace(l.value)); err == nil {
+			task.Priority = priority
+		}
+	case "STATUS":
+		switch strings.ToUpper(l.value) {
+		case "COMPLETED":
+			task.Completed = true
+		case "NEEDS-ACTION", "IN-PROCESS":
+		case "CANCELLED":
+			return fmt.Errorf("cancelled tasks are not imported")
+		default:
+			return fmt.Errorf("unknown status %q", l.value)
+		}
+	case "COMPLETED":
+		task.Completed = true
+	case "PERCENT-COMPLETE":
+		task.Completed = task.Completed || strings.TrimSpace(l.value) == "100"
+	case "CREATED":
+		task.CreatedAt, err = parseICalTime(l)
+	case "LAST-MODIFIED":
+		task.UpdatedAt, err = parseICalTime(l)
+	case "DUE":
+		var due time.Time
+		if due, err = parseICalTime(l); err == nil {
+			task.DueAt = &due
+		}
+	}
+	return err
+}
+
+type icalRawLine struct {
+	number int
+	text   string
+}
+
+// unfoldICal joins folded lines, keeping the number of the first physical
+// line of each, and drops empty ones
+func unfoldICal(r io.Reader) ([]icalRawLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lines []icalRawLine
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if number == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text != "" && (text[0] == ' ' || text[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		lines = append(lines, icalRawLine{number: number, text: text})
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,187 @@
+package taskio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+func TestICalFolding(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	long := strings.Repeat("Ünïcödé text, with commas; and semicolons ", 8)
+	tasks := []types.TaskMetaData{{Id: 1, Title: long, Description: "line one\nline two", Priority: "low", CreatedAt: now, UpdatedAt: now}}
+	var buf bytes.Buffer
+	if err := WriteICal(&buf, tasks, ICalOptions{Todos: true, Now: now}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasSuffix(out, "\r\n") || strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Fatal("content lines must end in CRLF")
+	}
+	folded := 0
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > icalLineOctets {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("fold split a character: %q", line)
+		}
+		if strings.HasPrefix(line, " ") {
+			folded++
+		}
+	}
+	if folded < 4 {
+		t.Errorf("%d continuation lines, want the summary folded", folded)
+	}
+	if !strings.Contains(out, `DESCRIPTION:line one\nline two`) || !strings.Contains(out, `commas\; and semicolons`) {
+		t.Errorf("text not escaped:\n%s", out)
+	}
+
+	rows, err := ReadICal(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Task.Title != strings.TrimSpace(long) || rows[0].Task.Description != "line one\nline two" {
+		t.Errorf("read back %+v", rows)
+	}
+}
+
+func TestICalRoundTrip(t *testing.T) {
+	created := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	dueDay := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	dueTime := time.Date(2026, 3, 9, 17, 45, 0, 0, time.UTC)
+	tasks := []types.TaskMetaData{
+		{Id: 4, Title: "Buy milk", Description: `2 litres, \ semi`, Priority: "high", CreatedAt: created, UpdatedAt: created, DueAt: &dueDay},
+		{Id: 5, Title: "Pay rent", Description: "before the 1st", Priority: "medium", Completed: true, CreatedAt: created, UpdatedAt: created.Add(time.Hour), DueAt: &dueTime},
+		{Id: 6, Title: "Walk dog", Description: "", Priority: "low", CreatedAt: created, UpdatedAt: created},
+	}
+	var buf bytes.Buffer
+	if err := WriteICal(&buf, tasks, ICalOptions{Name: "Ann's tasks", Todos: true, Events: true, Now: created, Instance: "0a1b2c3d"}); err != nil {
+		t.Fatal(err)
+	}
+	// Events are for calendar apps; only the todos come back
+	if n := strings.Count(buf.String(), "BEGIN:VEVENT"); n != 2 {
+		t.Errorf("%d events, want one per due task", n)
+	}
+	rows, err := ReadICal(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(tasks) {
+		t.Fatalf("read %d rows, want %d", len(rows), len(tasks))
+	}
+	for i, row := range rows {
+		want := tasks[i]
+		want.Id = 0
+		if want.Description == "" {
+			want.Description = want.Title
+		}
+		got := row.Task
+		if len(row.Errors) != 0 || got.Title != want.Title || got.Description != want.Description || got.Priority != want.Priority ||
+			got.Completed != want.Completed || !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) ||
+			(got.DueAt == nil) != (want.DueAt == nil) || (got.DueAt != nil && !got.DueAt.Equal(*want.DueAt)) {
+			t.Errorf("task %d: got %+v %v, want %+v", tasks[i].Id, got, row.Errors, want)
+		}
+		if id, ok := ParseTaskUID(row.UID, "0a1b2c3d"); !ok || id != tasks[i].Id {
+			t.Errorf("task %d came back with UID %q", tasks[i].Id, row.UID)
+		}
+	}
+}
+
+func TestReadICal(t *testing.T) {
+	input := "\ufeffBEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VTODO\r\n" +
+		"UID:abc-123@example.com\r\n" +
+		"SUMMARY:Call the\r\n" +
+		"  bank\r\n" +
+		"DUE;TZID=Europe/Berlin:20260310T090000\r\n" +
+		"PRIORITY:3\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"DESCRIPTION:Reminder\r\n" +
+		"END:VALARM\r\n" +
+		"END:VTODO\r\n" +
+		"BEGIN:VTODO\r\n" +
+		"SUMMARY;LANGUAGE=\"en:GB\":Renew passport\r\n" +
+		"DESCRIPTION:Photos first\r\n" +
+		"DUE;VALUE=DATE:20260401\r\n" +
+		"PERCENT-COMPLETE:100\r\n" +
+		"END:VTODO\r\n" +
+		"BEGIN:VTODO\r\n" +
+		"SUMMARY:Cancelled\r\n" +
+		"STATUS:CANCELLED\r\n" +
+		"PRIORITY:12\r\n" +
+		"END:VTODO\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Not a task\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	rows, err := ReadICal(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("read %d rows, want 3: %+v", len(rows), rows)
+	}
+
+	bank := rows[0]
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	if bank.Line != 3 || bank.UID != "abc-123@example.com" || bank.Task.Title != "Call the bank" || bank.Task.Priority != "high" {
+		t.Errorf("first todo: %+v", bank)
+	}
+	// The alarm's DESCRIPTION is not the task's; the summary stands in
+	if bank.Task.Description != "Call the bank" {
+		t.Errorf("description %q, want the summary", bank.Task.Description)
+	}
+	if want := time.Date(2026, 3, 10, 9, 0, 0, 0, berlin); bank.Task.DueAt == nil || !bank.Task.DueAt.Equal(want) {
+		t.Errorf("due %v, want %v", bank.Task.DueAt, want)
+	}
+
+	passport := rows[1]
+	if passport.UID != "" || passport.Task.Title != "Renew passport" || passport.Task.Description != "Photos first" ||
+		!passport.Task.Completed || passport.Task.Priority != "medium" || passport.Task.DueAt == nil || !passport.Task.DueAt.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("second todo: %+v", passport)
+	}
+	if len(rows[2].Errors) != 2 {
+		t.Errorf("third todo errors: %v", rows[2].Errors)
+	}
+}
+
+func TestReadICalUnusableFiles(t *testing.T) {
+	for name, input := range map[string]string{
+		"empty":         "",
+		"no calendar":   "BEGIN:VTODO\r\nEND:VTODO\r\n",
+		"unclosed":      "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:x\r\n",
+		"mismatched":    "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		"no colon":      "BEGIN:VCALENDAR\r\nSUMMARY\r\nEND:VCALENDAR\r\n",
+		"after the end": "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\nSUMMARY:x\r\n",
+	} {
+		if _, err := ReadICal(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestParseTaskUID(t *testing.T) {
+	for uid, want := range map[string]int64{
+		TaskUID(42, "0a1b2c3d"):             42,
+		"task-42-due@0a1b2c3d.todo-app":     0,
+		"task-0@0a1b2c3d.todo-app":          0,
+		"task-x@0a1b2c3d.todo-app":          0,
+		"task-9999999999@0a1b2c3d.todo-app": 9999999999,
+		// Another server's tasks, and UIDs from before instances had ids
+		TaskUID(42, "ffff0000"): 0,
+		"task-42@todo-app":      0,
+		"task-42@example.com":   0,
+		"abc-123@example.com":   0,
+	} {
+		id, ok := ParseTaskUID(uid, "0a1b2c3d")
+		if id != want || ok != (want != 0) {
+			t.Errorf("ParseTaskUID(%q) = %d, %v, want %d", uid, id, ok, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package taskio
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// Reports are task lists meant for people rather than other tools: a
+// Markdown checklist to paste into notes and issues, and an HTML page to
+// print. Neither can be imported back.
+
+// ReportGroups are the ways a report can section its tasks
+var ReportGroups = []string{"priority", "status"}
+
+// ReportOptions says how a report is titled and sectioned. Filter describes
+// what the tasks were selected by, if anything, and is shown under the
+// title. Group is one of ReportGroups; tasks keep their order within a
+// group.
+type ReportOptions struct {
+	Title  string
+	Filter string
+	Group  string
+	Now    time.Time
+}
+
+// ReportSection is one group of a report
+type ReportSection struct {
+	Heading string
+	Tasks   []types.TaskMetaData
+}
+
+// GroupTasks splits the tasks into the sections of a report, leaving out
+// empty ones
+func GroupTasks(tasks []types.TaskMetaData, group string) []ReportSection {
+	var sections []ReportSection
+	add := func(heading string, keep func(types.TaskMetaData) bool) {
+		section := ReportSection{Heading: heading}
+		for _, task := range tasks {
+			if keep(task) {
+				section.Tasks = append(section.Tasks, task)
+			}
+		}
+		if len(section.Tasks) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	if group == "status" {
+		add("To do", func(t types.TaskMetaData) bool { return !t.Completed })
+		add("Done", func(t types.TaskMetaData) bool { return t.Completed })
+		return sections
+	}
+	add("High priority", func(t types.TaskMetaData) bool { return t.Priority == "high" })
+	add("Medium priority", func(t types.TaskMetaData) bool { return t.Priority == "medium" })
+	add("Low priority", func(t types.TaskMetaData) bool { return t.Priority == "low" })
+	// Tasks stored before priorities were validated
+	add("Other", func(t types.TaskMetaData) bool {
+		return t.Priority != "high" && t.Priority != "medium" && t.Priority != "low"
+	})
+	return sections
+}
+
+// formatDue shows a due date as a date alone when it has no time of day
+func formatDue(due time.Time) string {
+	if allDay(due) {
+		return due.UTC().Format("2006-01-02")
+	}
+	return due.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+// markdownEscaper keeps task text from being read as Markdown
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`, "~", `\~`,
+)
+
+// WriteMarkdown writes the tasks as a Markdown checklist, one section per
+// group. A description that differs from the title is indented under its
+// task.
+func WriteMarkdown(w io.Writer, tasks []types.TaskMetaData, opts ReportOptions) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s\n\n", markdownEscaper.Replace(opts.Title))
+	if opts.Filter != "" {
+		fmt.Fprintf(bw, "_%s_\n\n", markdownEscaper.Replace(opts.Filter))
+	}
+	sections := GroupTasks(tasks, opts.Group)
+	if len(sections) == 0 {
+		bw.WriteString("No tasks.\n\n")
+	}
+	for _, section := range sections {
+		fmt.Fprintf(bw, "## %s\n\n", section.Heading)
+		for _, task := range section.Tasks {
+			mark := " "
+			if task.Completed {
+				mark = "x"
+			}
+			title := strings.Join(strings.Fields(task.Title), " ")
+			fmt.Fprintf(bw, "- [%s] %s", mark, markdownEscaper.Replace(title))
+			if opts.Group == "status" {
+				fmt.Fprintf(bw, " (%s)", task.Priority)
+			}
+			if task.DueAt != nil {
+				fmt.Fprintf(bw, " — due %s", formatDue(*task.DueAt))
+			}
+			bw.WriteString("\n")
+			if description := strings.TrimSpace(task.Description); description != "" && description != title {
+				for _, line := range strings.Split(description, "\n") {
+					fmt.Fprintf(bw, "  %s\n", markdownEscaper.Replace(strings.TrimRight(line, "\r")))
+				}
+			}
+		}
+		bw.WriteString("\n")
+	}
+	fmt.Fprintf(bw, "Generated %s\n", opts.Now.UTC().Format("2006-01-02 15:04 UTC"))
+	return bw.Flush()
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"due": formatDue,
+	"showDescription": func(task types.TaskMetaData) bool {
+		description := strings.TrimSpace(task.Description)
+		return description != "" && description != strings.TrimSpace(task.Title)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 45rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+h1 { margin-bottom: 0.25rem; }
+.filter, footer { color: #666; }
+h2 { border-bottom: 1px solid #ccc; padding-bottom: 0.2rem; margin-top: 2rem; }
+ul { list-style: none; padding: 0; }
+li { margin: 0.5rem 0; break-inside: avoid; }
+li.done .title { text-decoration: line-through; color: #666; }
+.meta { color: #666; font-size: 0.9em; margin-left: 0.5rem; }
+.description { margin: 0.2rem 0 0 1.6rem; white-space: pre-wrap; font-size: 0.9em; }
+footer { margin-top: 2rem; font-size: 0.8em; }
+@media print { body { margin: 0; max-width: none; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{with .Filter}}<p class="filter">{{.}}</p>
+{{end}}
+{{- range .Sections}}
+<h2>{{.Heading}}</h2>
+<ul>
+{{- range .Tasks}}
+<li{{if .Completed}} class="done"{{end}}><input type="checkbox" disabled{{if .Completed}} checked{{end}}> <span class="title">{{.Title}}</span>
+{{- if $.ShowPriority}}<span class="meta">{{.Priority}}</span>{{end}}
+{{- with .DueAt}}<span class="meta">due {{due .}}</span>{{end}}
+{{- if showDescription .}}<div class="description">{{.Description}}</div>{{end}}</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>No tasks.</p>
+{{- end}}
+<footer>Generated {{.Generated}}</footer>
+</body>
+</html>
+`))
+
+// WriteHTML writes the tasks as a standalone HTML page laid out for
+// printing, one section per group
+func WriteHTML(w io.Writer, tasks []types.TaskMetaData, opts ReportOptions) error {
+	return reportTemplate.Execute(w, struct {
+		ReportOptions
+		Sections     []ReportSection
+		ShowPriority bool
+		Generated    string
+	}{
+		ReportOptions: opts,
+		Sections:      GroupTasks(tasks, opts.Group),
+		ShowPriority:  opts.Group == "status",
+		Generated:     opts.Now.UTC().Format("2006-01-02 15:04 UTC"),
+	})
+}
@@ -0,0 +1,122 @@
+package taskio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+func reportTasks() []types.TaskMetaData {
+	due := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	dueTime := time.Date(2026, 3, 9, 17, 45, 0, 0, time.UTC)
+	return []types.TaskMetaData{
+		{Id: 1, Title: "Buy *oat* milk", Description: "2 litres\n# not a heading", Priority: "low", DueAt: &due},
+		{Id: 2, Title: "Pay rent", Description: "Pay rent", Priority: "high", Completed: true, DueAt: &dueTime},
+		{Id: 3, Title: "Fix <script>alert(1)</script>", Description: "a & b", Priority: "high"},
+	}
+}
+
+var reportNow = time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteMarkdown(&buf, reportTasks(), ReportOptions{Title: "Tasks of Ann_B", Filter: "Showing tasks matching \"x\"", Group: "priority", Now: reportNow})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Tasks of Ann\_B
+
+_Showing tasks matching "x"_
+
+## High priority
+
+- [x] Pay rent — due 2026-03-09 17:45 UTC
+- [ ] Fix \<script\>alert(1)\</script\>
+  a & b
+
+## Low priority
+
+- [ ] Buy \*oat\* milk — due 2026-03-08
+  2 litres
+  \# not a heading
+
+Generated 2026-03-01 12:30 UTC
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteMarkdownByStatus(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, reportTasks(), ReportOptions{Title: "T", Group: "status", Now: reportNow}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	todo, done := strings.Index(out, "## To do"), strings.Index(out, "## Done")
+	if todo < 0 || done < todo {
+		t.Fatalf("sections out of order:\n%s", out)
+	}
+	if !strings.Contains(out, "- [ ] Buy \\*oat\\* milk (low) — due 2026-03-08\n") || !strings.Contains(out[done:], "- [x] Pay rent (high)") {
+		t.Errorf("status report:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := WriteMarkdown(&buf, nil, ReportOptions{Title: "T", Group: "status", Now: reportNow}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "No tasks.") {
+		t.Errorf("empty report:\n%s", buf.String())
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteHTML(&buf, reportTasks(), ReportOptions{Title: "Tasks of <Ann>", Filter: "Showing completed tasks", Group: "priority", Now: reportNow})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<title>Tasks of &lt;Ann&gt;</title>",
+		`<p class="filter">Showing completed tasks</p>`,
+		"<h2>High priority</h2>",
+		`<li class="done"><input type="checkbox" disabled checked> <span class="title">Pay rent</span><span class="meta">due 2026-03-09 17:45 UTC</span></li>`,
+		`<span class="title">Fix &lt;script&gt;alert(1)&lt;/script&gt;</span><div class="description">a &amp; b</div>`,
+		`<span class="meta">due 2026-03-08</span><div class="description">2 litres` + "\n" + `# not a heading</div>`,
+		"<footer>Generated 2026-03-01 12:30 UTC</footer>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("page does not contain %s", want)
+		}
+	}
+	if strings.Contains(out, "<script>") || strings.Contains(out, "Medium priority") || strings.Contains(out, `class="meta">high`) {
+		t.Errorf("unexpected content:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := WriteHTML(&buf, reportTasks(), ReportOptions{Title: "T", Group: "status", Now: reportNow}); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "<h2>To do</h2>") || !strings.Contains(out, `<span class="meta">high</span>`) {
+		t.Errorf("status page:\n%s", out)
+	}
+}
+
+func TestGroupTasks(t *testing.T) {
+	tasks := append(reportTasks(), types.TaskMetaData{Id: 4, Title: "Legacy", Priority: "urgent"})
+	var headings []string
+	for _, section := range GroupTasks(tasks, "priority") {
+		headings = append(headings, section.Heading)
+	}
+	if strings.Join(headings, ",") != "High priority,Low priority,Other" {
+		t.Errorf("sections %v", headings)
+	}
+	// Tasks keep their order within a section
+	if high := GroupTasks(tasks, "priority")[0].Tasks; high[0].Id != 2 || high[1].Id != 3 {
+		t.Errorf("high priority tasks %+v", high)
+	}
+}
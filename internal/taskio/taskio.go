@@ -0,0 +1,54 @@
+// Package taskio converts tasks to and from the file formats users bring
+// from other tools. Readers only parse; validating and storing the tasks is
+// left to the caller.
+package taskio
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// Row is one task read from a file. Line is where it starts, for error
+// reports; Errors lists what could not be parsed, leaving Task incomplete.
+// UID identifies the task across imports in formats that have one.
+type Row struct {
+	Line   int
+	Task   types.TaskMetaData
+	UID    string
+	Errors []string
+}
+
+// MaxRows bounds how many tasks a single import may hold
+const MaxRows = 10000
+
+// timeLayouts are the timestamp formats accepted on import, tried in order
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or YYYY-MM-DD", value)
+}
+
+// parseCompleted accepts the ways spreadsheets and other tools mark a task
+// done; empty means not done
+func parseCompleted(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "", "no", "n", "open", "todo", "incomplete", "pending":
+		return false, nil
+	case "yes", "y", "x", "done", "completed", "complete":
+		return true, nil
+	}
+	completed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid completed value %q", value)
+	}
+	return completed, nil
+}
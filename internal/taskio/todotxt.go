@@ -0,0 +1,235 @@
+package taskio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// todo.txt (https://github.com/todotxt/todo.txt): one task per line,
+//
+//	x (A) 2026-10-19 2026-10-01 Call the bank +finance @phone due:2026-10-20
+//
+// with the completion mark, priority, completion and creation dates in
+// front. Priorities A, B and C are high, medium and low. +project and
+// @context tokens have no field of their own and stay in the title. Of the
+// key:value extensions, due is the due date, pri the priority of a
+// completed task (the format drops it on completion) and desc the
+// description, percent-encoded, when it differs from the title; others stay
+// in the title too, as do extensions whose value does not parse. A title
+// word that would read as one of the three is written with its colon and
+// percent signs percent-encoded, due%3Anever, and decoded again on import.
+
+const todoTxtDate = "2006-01-02"
+
+// todoTxtKeys are the extensions read into task fields
+var todoTxtKeys = []string{"due", "pri", "desc"}
+
+// todoTxtKey returns the extension key word starts with, followed by sep
+func todoTxtKey(word string, sep string) (string, bool) {
+	for _, key := range todoTxtKeys {
+		if strings.HasPrefix(word, key+sep) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// escapeTodoTxtWord keeps a title word from being read as an extension;
+// words already escaped are escaped again so they come back as written
+func escapeTodoTxtWord(word string) string {
+	_, extension := todoTxtKey(word, ":")
+	_, escaped := todoTxtKey(word, "%")
+	if !extension && !escaped {
+		return word
+	}
+	return strings.NewReplacer("%", "%25", ":", "%3A").Replace(word)
+}
+
+// unescapeTodoTxtWord reverses escapeTodoTxtWord; other words, and escapes
+// it did not write, are left alone
+func unescapeTodoTxtWord(word string) string {
+	if _, escaped := todoTxtKey(word, "%"); !escaped {
+		return word
+	}
+	unescaped, err := url.PathUnescape(word)
+	if err != nil {
+		return word
+	}
+	return unescaped
+}
+
+// todoTxtLetters maps task priorities to todo.txt priority letters
+var todoTxtLetters = map[string]string{"high": "A", "medium": "B", "low": "C"}
+
+// priorityFromLetter maps a priority letter back; letters after C are low
+func priorityFromLetter(letter string) (string, error) {
+	if len(letter) != 1 || letter[0] < 'A' || letter[0] > 'Z' {
+		return "", fmt.Errorf("invalid priority %q, expected A to Z", letter)
+	}
+	switch letter {
+	case "A":
+		return "high", nil
+	case "B":
+		return "medium", nil
+	}
+	return "low", nil
+}
+
+// FormatTodoTxt renders a task as one todo.txt line
+func FormatTodoTxt(task types.TaskMetaData) string {
+	letter := todoTxtLetters[task.Priority]
+	var parts []string
+	if task.Completed {
+		// The completion time is not kept; the last change is the closest
+		parts = append(parts, "x", task.UpdatedAt.UTC().Format(todoTxtDate))
+	} else if letter != "" {
+		parts = append(parts, "("+letter+")")
+	}
+	parts = append(parts, task.CreatedAt.UTC().Format(todoTxtDate))
+	// A line holds a single line of text
+	words := strings.Fields(task.Title)
+	title := strings.Join(words, " ")
+	for _, word := range words {
+		parts = append(parts, escapeTodoTxtWord(word))
+	}
+	if task.DueAt != nil {
+		due := task.DueAt.UTC().Format(time.RFC3339)
+		if allDay(*task.DueAt) {
+			due = task.DueAt.UTC().Format(todoTxtDate)
+		}
+		parts = append(parts, "due:"+due)
+	}
+	if task.Completed && letter != "" {
+		parts = append(parts, "pri:"+letter)
+	}
+	if task.Description != "" && task.Description != title {
+		parts = append(parts, "desc:"+url.PathEscape(task.Description))
+	}
+	return strings.Join(parts, " ")
+}
+
+// WriteTodoTxt writes the tasks one per line
+func WriteTodoTxt(w io.Writer, tasks []types.TaskMetaData) error {
+	bw := bufio.NewWriter(w)
+	for _, task := range tasks {
+		if _, err := bw.WriteString(FormatTodoTxt(task) + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ParseTodoTxt reads one todo.txt line. Tasks without a priority are
+// medium, and without a desc extension the description is the title, as
+// when a task is added from todoctl. Every line makes a task; whether it is
+// a valid one is for the caller to check.
+func ParseTodoTxt(line string) Row {
+	row := Row{Task: types.TaskMetaData{Priority: "medium"}}
+	task := &row.Task
+	tokens := strings.Fields(line)
+	date := func() (time.Time, bool) {
+		if len(tokens) == 0 {
+			return time.Time{}, false
+		}
+		t, err := time.Parse(todoTxtDate, tokens[0])
+		if err != nil {
+			return time.Time{}, false
+		}
+		tokens = tokens[1:]
+		return t, true
+	}
+
+	if len(tokens) > 0 && tokens[0] == "x" {
+		task.Completed = true
+		tokens = tokens[1:]
+	}
+	if len(tokens) > 0 && len(tokens[0]) == 3 && tokens[0][0] == '(' && tokens[0][2] == ')' {
+		if priority, err := priorityFromLetter(tokens[0][1:2]); err == nil {
+			task.Priority = priority
+			tokens = tokens[1:]
+		}
+	}
+	first, hasFirst := date()
+	second, hasSecond := date()
+	switch {
+	case task.Completed && hasSecond:
+		task.UpdatedAt, task.CreatedAt = first, second
+	case task.Completed && hasFirst:
+		task.UpdatedAt = first
+	case hasFirst:
+		task.CreatedAt = first
+		if hasSecond {
+			// Only a completed task has two dates; this one is text
+			tokens = append([]string{second.Format(todoTxtDate)}, tokens...)
+		}
+	}
+
+	var text []string
+	description, hasDescription := "", false
+	for _, token := range tokens {
+		key, value, _ := strings.Cut(token, ":")
+		// An extension that does not parse is someone's text
+		parsed := false
+		switch key {
+		case "due":
+			if due, err := parseTime(value); err == nil {
+				task.DueAt, parsed = &due, true
+			}
+		case "pri":
+			if priority, err := priorityFromLetter(value); err == nil {
+				task.Priority, parsed = priority, true
+			}
+		case "desc":
+			if unescaped, err := url.PathUnescape(value); err == nil && value != "" {
+				description, hasDescription, parsed = unescaped, true, true
+			}
+		}
+		if !parsed {
+			text = append(text, unescapeTodoTxtWord(token))
+		}
+	}
+	task.Title = strings.Join(text, " ")
+	task.Description = task.Title
+	if hasDescription {
+		task.Description = description
+	}
+	return row
+}
+
+// ReadTodoTxt reads a todo.txt file, skipping blank lines. An error is
+// returned only when the file cannot be read.
+func ReadTodoTxt(r io.Reader) ([]Row, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var rows []Row
+	number := 0
+	for scanner.Scan() {
+		number++
+		line := scanner.Text()
+		if number == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(rows) == MaxRows {
+			return nil, fmt.Errorf("more than %d tasks", MaxRows)
+		}
+		row := ParseTodoTxt(line)
+		row.Line = number
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if number == 0 {
+		return nil, fmt.Errorf("empty file")
+	}
+	return rows, nil
+}
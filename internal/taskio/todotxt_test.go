@@ -0,0 +1,138 @@
+package taskio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	created := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	done := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)
+	dueDay := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	dueTime := time.Date(2026, 3, 9, 17, 45, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name string
+		task types.TaskMetaData
+		line string
+	}{
+		{
+			name: "plain",
+			task: types.TaskMetaData{Title: "Call the bank", Description: "Call the bank", Priority: "high"},
+			line: "(A) 2026-03-01 Call the bank",
+		},
+		{
+			name: "description and due day",
+			task: types.TaskMetaData{Title: "Buy milk +home @shop", Description: "2 litres, 100% oat", Priority: "medium", DueAt: &dueDay},
+			line: "(B) 2026-03-01 Buy milk +home @shop due:2026-03-08 desc:2%20litres%2C%20100%25%20oat",
+		},
+		{
+			name: "completed with due time",
+			task: types.TaskMetaData{Title: "Pay rent", Description: "Pay rent", Priority: "low", Completed: true, DueAt: &dueTime},
+			line: "x 2026-03-05 2026-03-01 Pay rent due:2026-03-09T17:45:00Z pri:C",
+		},
+		{
+			name: "title words that look like extensions",
+			task: types.TaskMetaData{Title: "Ask about due:tomorrow and pri:A desc:x", Description: "notes", Priority: "medium"},
+			line: "(B) 2026-03-01 Ask about due%3Atomorrow and pri%3AA desc%3Ax desc:notes",
+		},
+		{
+			name: "title words that look escaped",
+			task: types.TaskMetaData{Title: "due%3Asoon pri% 100%", Description: "due%3Asoon pri% 100%", Priority: "medium"},
+			line: "(B) 2026-03-01 due%253Asoon pri%25 100%",
+		},
+		{
+			name: "title starting like the front of a line",
+			task: types.TaskMetaData{Title: "x (A) 2026-01-01 mark", Description: "x (A) 2026-01-01 mark", Priority: "low", Completed: true},
+			line: "x 2026-03-05 2026-03-01 x (A) 2026-01-01 mark pri:C",
+		},
+		{
+			name: "other extensions",
+			task: types.TaskMetaData{Title: "Read rec:weekly t:2026-03-02 due:", Description: "Read rec:weekly t:2026-03-02 due:", Priority: "medium"},
+			line: "(B) 2026-03-01 Read rec:weekly t:2026-03-02 due%3A",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			task := tc.task
+			task.CreatedAt, task.UpdatedAt = created, created
+			if task.Completed {
+				task.UpdatedAt = done
+			}
+			line := FormatTodoTxt(task)
+			if line != tc.line {
+				t.Errorf("formatted\n\t%s\nwant\n\t%s", line, tc.line)
+			}
+			row := ParseTodoTxt(line)
+			got := row.Task
+			if len(row.Errors) != 0 || got.Title != task.Title || got.Description != task.Description || got.Priority != task.Priority ||
+				got.Completed != task.Completed || !got.CreatedAt.Equal(task.CreatedAt) ||
+				(task.Completed && !got.UpdatedAt.Equal(task.UpdatedAt)) ||
+				(got.DueAt == nil) != (task.DueAt == nil) || (got.DueAt != nil && !got.DueAt.Equal(*task.DueAt)) {
+				t.Errorf("read back %+v %v, want %+v", got, row.Errors, task)
+			}
+		})
+	}
+}
+
+func TestParseTodoTxt(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		want types.TaskMetaData
+		due  string
+	}{
+		// Values that do not parse stay in the title instead of failing
+		{"Meet up due:someday", types.TaskMetaData{Title: "Meet up due:someday", Priority: "medium"}, ""},
+		{"Fix it pri:urgent due:2026-13-40", types.TaskMetaData{Title: "Fix it pri:urgent due:2026-13-40", Priority: "medium"}, ""},
+		{"Note desc:%zz", types.TaskMetaData{Title: "Note desc:%zz", Priority: "medium"}, ""},
+		{"Note desc:", types.TaskMetaData{Title: "Note desc:", Priority: "medium"}, ""},
+		// Escapes nobody wrote are left alone
+		{"Discount due%ZZ off", types.TaskMetaData{Title: "Discount due%ZZ off", Priority: "medium"}, ""},
+		{"(D) Later due:2026-03-08 14:00", types.TaskMetaData{Title: "Later 14:00", Priority: "low"}, "2026-03-08"},
+		{"(a) lower case is text", types.TaskMetaData{Title: "(a) lower case is text", Priority: "medium"}, ""},
+		{"2026-03-01 2026-02-01 only done tasks have two dates", types.TaskMetaData{Title: "2026-02-01 only done tasks have two dates", Priority: "medium",
+			CreatedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}, ""},
+	} {
+		row := ParseTodoTxt(tc.line)
+		got := row.Task
+		got.DueAt = nil
+		want := tc.want
+		want.Description = want.Title
+		if len(row.Errors) != 0 || got != want {
+			t.Errorf("%q: got %+v %v, want %+v", tc.line, got, row.Errors, want)
+		}
+		if due := row.Task.DueAt; (due == nil) != (tc.due == "") || (due != nil && due.Format(todoTxtDate) != tc.due) {
+			t.Errorf("%q: due %v, want %q", tc.line, due, tc.due)
+		}
+	}
+}
+
+func TestReadTodoTxt(t *testing.T) {
+	tasks := []types.TaskMetaData{
+		{Title: "one due:x", Description: "one due:x", Priority: "high", CreatedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Title: "two", Description: "first line\nsecond line", Priority: "low", CreatedAt: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+	if err := WriteTodoTxt(&buf, tasks); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("\n   \n")
+	rows, err := ReadTodoTxt(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Line != 1 || rows[1].Line != 2 {
+		t.Fatalf("rows: %+v", rows)
+	}
+	for i, row := range rows {
+		if row.Task.Title != tasks[i].Title || row.Task.Description != tasks[i].Description {
+			t.Errorf("line %d: %+v", row.Line, row.Task)
+		}
+	}
+	if _, err := ReadTodoTxt(strings.NewReader("")); err == nil {
+		t.Error("an empty file was read")
+	}
+}
@@ -0,0 +1,159 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/srmty09/Todo-App/internal/storage"
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+// Storage decorates a storage.Storage with one client span per method call
+type Storage struct {
+	next   storage.Storage
+	tracer trace.Tracer
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+// WrapStorage returns next instrumented with spans from the global provider
+func WrapStorage(next storage.Storage) *Storage {
+	return &Storage{
+		next:   next,
+		tracer: otel.Tracer(instrumentationName),
+	}
+}
+
+func (s *Storage) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attribute.String("db.system.name", "sqlite"),
+		attribute.String("db.operation.name", method),
+	)
+	return s.tracer.Start(ctx, "storage."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// end records err on the span, if any, and finishes it
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *Storage) CreateUser(ctx context.Context, name string, email string) (int64, error) {
+	ctx, span := s.start(ctx, "CreateUser")
+	res, err := s.next.CreateUser(ctx, name, email)
+	end(span, err)
+	return res, err
+}
+
+func (s *Storage) UserExists(ctx context.Context, userId int64) (bool, error) {
+	ctx, span := s.start(ctx, "UserExists", attribute.Int64("todo.user_id", userId))
+	res, err := s.next.UserExists(ctx, userId)
+	end(span, err)
+	return res, err
+}
+
+func (s *Storage) AddNewTask(ctx context.Context, userId int64, title string, description string, priority string, completed bool, createdAt time.Time, updatedAt time.Time) (int64, error) {
+	ctx, span := s.start(ctx, "AddNewTask", attribute.Int64("todo.user_id", userId))
+	res, err := s.next.AddNewTask(ctx, userId, title, description, priority, completed, createdAt, updatedAt)
+	end(span, err)
+	return res, err
+}
+
+func (s *Storage) GetTaskForId(ctx context.Context, userId int64) ([]types.TaskMetaData, error) {
+	ctx, span := s.start(ctx, "GetTaskForId", attribute.Int64("todo.user_id", userId))
+	res, err := s.next.GetTaskForId(ctx, userId)
+	end(span, err)
+	return res, err
+}
+
+func (s *Storage) GetSingleTask(ctx context.Context, userId int64, taskId int64) (*types.TaskMetaData, error) {
+	ctx, span := s.start(ctx, "GetSingleTask", attribute.Int64("todo.user_id", userId), attribute.Int64("todo.task_id", taskId))
+	res, err := s.next.GetSingleTask(ctx, userId, taskId)
+	end(span, err)
+	return res, err
+}
+
+func (s *Storage) MarkComplete(ctx context.Context, userId int64, taskId int64) error {
+	ctx, span := s.start(ctx, "MarkComplete", attribute.Int64("todo.user_id", userId), attribute.Int64("todo.task_id", taskId))
+	err := s.next.MarkComplete(ctx, userId, taskId)
+	end(span, err)
+	return err
+}
+
+func (s *Storage) MarkIncomplete(ctx context.Context, userId int64, taskId int64) error {
+	ctx, span := s.start(ctx, "MarkIncomplete", attribute.Int64("todo.user_id", userId), attribute.Int64("todo.task_id", taskId))
+	err := s.next.MarkIncomplete(ctx, userId, taskId)
+	end(span, err)
+	return err
+}
+
+func (s *Storage) DeletingTask(ctx context.Context, userId int64, taskId int64) error {
+	ctx, span := s.start(ctx, "DeletingTask", attribute.Int64("todo.user_id", userId), attribute.Int64("todo.task_id", taskId))
+	err := s.next.DeletingTask(ctx, userId, taskId)
+	end(span, err)
+	return err
+}
+
+func (s *Storage) EditTask(ctx context.Context, userId int64, taskId int64, title string, description string, priority string) error {
+	ctx, span := s.start(ctx, "EditTask", attribute.Int64("todo.user_id", userId), attribute.Int64("todo.task_id", taskId))
+	err := s.next.EditTask(ctx, userId, taskId, title, description, priority)
+	end(span, err)
+	return err
+}
+
+func (s *Storage) GetUser(ctx context.Context, userId int64) (*types.User, error) {
+	ctx, span := s.start(ctx, "GetUser", attribute.Int64("todo.user_id", userId))
+	res, err := s.next.GetUser(ctx, userId)
+	end(span, err)
+	return res, err
+}
+
+func (s *Storage) DeleteUser(ctx context.Context, userId int64) error {
+	ctx, span := s.start(ctx, "DeleteUser", attribute.Int64("todo.user_id", userId))
+	err := s.next.DeleteUser(ctx, userId)
+	end(span, err)
+	return err
+}
+
+func (s *Storage) GetCompletedTask(ctx context.Context, userId int64) ([]types.TaskMetaData, error) {
+	ctx, span := s.start(ctx, "GetCompletedTask", attribute.Int64("todo.user_id", userId))
+	res, err := s.next.GetCompletedTask(ctx, userId)
+	end(span, err)
+	return res, err
+}
+
+func (s *Storage) GetIncompletedTask(ctx context.Context, userId int64) ([]types.TaskMetaData, error) {
+	ctx, span := s.start(ctx, "GetIncompletedTask", attribute.Int64("todo.user_id", userId))
+	res, err := s.next.GetIncompletedTask(ctx, userId)
+	end(span, err)
+	return res, err
+}
+
+func (s *Storage) GetTaskWithTitle(ctx context.Context, userId int64, keyword string) ([]types.TaskMetaData, error) {
+	ctx, span := s.start(ctx, "GetTaskWithTitle", attribute.Int64("todo.user_id", userId))
+	res, err := s.next.GetTaskWithTitle(ctx, userId, keyword)
+	end(span, err)
+	return res, err
+}
+
+func (s *Storage) GetTaskWithFilters(ctx context.Context, userId int64, keyword string, status string) ([]types.TaskMetaData, error) {
+	ctx, span := s.start(ctx, "GetTaskWithFilters", attribute.Int64("todo.user_id", userId))
+	res, err := s.next.GetTaskWithFilters(ctx, userId, keyword, status)
+	end(span, err)
+	return res, err
+}
+
+func (s *Storage) Close() error {
+	return s.next.Close()
+}
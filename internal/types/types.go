@@ -1,18 +1,185 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 
 type TaskMetaData struct{
+	Id int64 `json:"id"`
 	Title string `json:"title" validate:"required"`
 	Description string `json:"description" validate:"required"`
 	Priority string `json:"priority" validate:"required,oneof=low medium high"`
 	Completed bool `json:"completed"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// DueAt is when the task is due, if ever; a time of exactly midnight
+	// UTC means the whole day
+	DueAt *time.Time `json:"due_at,omitempty"`
 }
 
 type User struct{
+	Id int64 `json:"id"`
 	Name string `json:"name" validate:"required"`
 	Email string `json:"email" validate:"required,email"`
-}
\ No newline at end of file
+}
+
+// Webhook is a user's subscription to task events. Events lists the event
+// types delivered, "*" meaning all of them. Secret signs the payloads and is
+// only shown when the webhook is created.
+type Webhook struct{
+	Id int64 `json:"id"`
+	UserId int64 `json:"user_id"`
+	URL string `json:"url" validate:"required,http_url"`
+	Events []string `json:"events" validate:"required,min=1"`
+	Secret string `json:"secret,omitempty" validate:"omitempty,min=16"`
+	Active bool `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one event queued for one webhook. Status is "pending"
+// until an attempt succeeds ("delivered") or the attempts run out ("failed").
+type WebhookDelivery struct{
+	Id int64 `json:"id"`
+	WebhookId int64 `json:"webhook_id"`
+	EventType string `json:"event_type"`
+	Payload json.RawMessage `json:"payload"`
+	Status string `json:"status"`
+	Attempts int `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	// Only filled in when a single delivery is fetched
+	History []WebhookAttempt `json:"history,omitempty"`
+}
+
+// WebhookAttempt records one HTTP request made for a delivery. StatusCode is
+// 0 when no response was received.
+type WebhookAttempt struct{
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode int `json:"status_code"`
+	Error string `json:"error,omitempty"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// PendingDelivery is a delivery due for an attempt, with where to send it
+type PendingDelivery struct{
+	Delivery WebhookDelivery
+	URL string
+	Secret string
+}
+
+// SyncTask is a task as seen by a syncing client, with the id the client
+// gave it when it was created offline
+type SyncTask struct{
+	TaskMetaData
+	ClientId string `json:"client_id,omitempty"`
+}
+
+// SyncTombstone reports a task deleted since the client's change token
+type SyncTombstone struct{
+	TaskId int64 `json:"task_id"`
+	ClientId string `json:"client_id,omitempty"`
+}
+
+// SyncChanges is one page of a user's changes after a sequence number. Seq
+// is where the next page starts; More is set when the page was cut short.
+type SyncChanges struct{
+	Tasks []SyncTask
+	Deleted []SyncTombstone
+	Seq int64
+	More bool
+}
+
+// SyncMutation is a change made by a client, possibly while offline. The
+// task is found by TaskId or, for tasks created offline, ClientId. Only the
+// fields present are changed, each only if UpdatedAt is later than that
+// field's last change on the server.
+type SyncMutation struct{
+	Op string `json:"op" validate:"required,oneof=upsert delete"`
+	TaskId int64 `json:"task_id,omitempty"`
+	ClientId string `json:"client_id,omitempty" validate:"required_without=TaskId,max=128"`
+	Title *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Priority *string `json:"priority,omitempty"`
+	Completed *bool `json:"completed,omitempty"`
+	UpdatedAt time.Time `json:"updated_at" validate:"required"`
+}
+
+// SyncResult is the outcome of one mutation: "created", "updated",
+// "deleted", "unchanged" when the server's values won or already matched,
+// "conflict" when the task was deleted on the server, or "invalid".
+type SyncResult struct{
+	Op string `json:"op"`
+	TaskId int64 `json:"task_id,omitempty"`
+	ClientId string `json:"client_id,omitempty"`
+	Status string `json:"status"`
+	// Fields taken from the mutation, and those where a later server change won
+	AppliedFields []string `json:"applied_fields,omitempty"`
+	StaleFields []string `json:"stale_fields,omitempty"`
+	Task *SyncTask `json:"task,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ImportReport answers an import. In a dry run nothing is stored and
+// Imported stays 0; Valid tells how many rows would have been. Skipped counts
+// rows left out because the task is already there.
+type ImportReport struct{
+	DryRun bool `json:"dry_run"`
+	Rows int `json:"rows"`
+	Valid int `json:"valid"`
+	Imported int `json:"imported"`
+	Skipped int `json:"skipped"`
+	TaskIds []int64 `json:"task_ids,omitempty"`
+	IgnoredColumns []string `json:"ignored_columns,omitempty"`
+	Errors []ImportError `json:"errors"`
+	// Error is why the import stopped early; the tasks listed before it
+	// were stored and stay
+	Error string `json:"error,omitempty"`
+}
+
+// ImportError lists the problems of one row; Row is its line in the file
+type ImportError struct{
+	Row int `json:"row"`
+	Errors []string `json:"errors"`
+}
+
+// Archive is all of a user's data, as exported for backups and data
+// requests. Format and Version identify the layout; Version is raised when
+// it changes in a way older readers would get wrong.
+type Archive struct{
+	Format string `json:"format"`
+	Version int `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	User User `json:"user"`
+	Tasks []ArchiveTask `json:"tasks"`
+}
+
+// ArchiveTask is a task in an archive, with the id a sync client gave it
+type ArchiveTask struct{
+	TaskMetaData
+	ClientId string `json:"client_id,omitempty"`
+}
+
+// ArchiveImport answers an archive import. TaskIds maps the id of every
+// task in the archive to the task it became or matched. Changes is kept
+// for announcing the restored tasks and is not part of the answer.
+type ArchiveImport struct{
+	DryRun bool `json:"dry_run"`
+	UserId int64 `json:"user_id"`
+	UserCreated bool `json:"user_created"`
+	Created int `json:"created"`
+	Replaced int `json:"replaced"`
+	Skipped int `json:"skipped"`
+	TaskIds map[int64]int64 `json:"task_ids"`
+	Changes []ArchiveChange `json:"-"`
+}
+
+// ArchiveChange is a task an archive import created or replaced, as stored.
+// WasCompleted is the completion state a replaced task had before.
+type ArchiveChange struct{
+	Task TaskMetaData
+	Replaced bool
+	WasCompleted bool
+}
@@ -1,6 +1,7 @@
 package helpers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -21,3 +22,13 @@ func ParsePathInt64(r *http.Request, key string) (int64, error) {
 	return intValue, nil
 }
 
+
+// BodyErrorStatus is the status for a request body that could not be read:
+// 413 when it went past the size limit, 400 for anything else
+func BodyErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
@@ -78,6 +78,8 @@ func ValidationError(errs validator.ValidationErrors) Response{
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is required",err.Field()))
 		case "email":
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s must be a valid email address",err.Field()))
+		case "oneof":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s must be one of %s",err.Field(),err.Param()))
 		default:
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is invalid",err.Field()))
 		}
@@ -0,0 +1,47 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/srmty09/Todo-App/internal/version.Version=1.2.0 \
+//	  -X github.com/srmty09/Todo-App/internal/version.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/srmty09/Todo-App/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/todo
+var (
+	Version   = "1.0.0"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information. When the commit was not injected it
+// falls back to the VCS revision the Go toolchain embeds in the binary.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range bi.Settings {
+				if setting.Key == "vcs.revision" {
+					info.Commit = setting.Value
+				}
+			}
+		}
+	}
+	return info
+}
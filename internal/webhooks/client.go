@@ -0,0 +1,52 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+
+	"github.com/srmty09/Todo-App/internal/config"
+)
+
+// errPrivateAddress is returned when a webhook URL resolves to an address
+// on the server's own network
+var errPrivateAddress = errors.New("webhook address is not publicly routable")
+
+// newClient builds the client deliveries are sent with. Unless private
+// networks are allowed, connections to loopback, private and link-local
+// addresses are refused at dial time, after name resolution, so a webhook
+// cannot be pointed at services next to the server.
+func newClient(cfg config.Webhooks) *http.Client {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publiclyRoutable(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errPrivateAddress, addrPort.Addr())
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+		// A redirect could lead anywhere; receivers must answer themselves
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func publiclyRoutable(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast()
+}
@@ -0,0 +1,267 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/events"
+	"github.com/srmty09/Todo-App/internal/types"
+	"github.com/srmty09/Todo-App/internal/version"
+)
+
+// Headers sent with every delivery. The signature is the hex HMAC-SHA256,
+// keyed with the webhook secret, of the timestamp, a dot and the body:
+//
+//	X-Todo-Signature-256: sha256=hex(hmac(secret, timestamp + "." + body))
+//
+// Receivers should recompute it and reject stale timestamps.
+const (
+	HeaderEvent     = "X-Todo-Event"
+	HeaderDelivery  = "X-Todo-Delivery"
+	HeaderTimestamp = "X-Todo-Timestamp"
+	HeaderSignature = "X-Todo-Signature-256"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// EventTypes are the event types webhooks can subscribe to. user.deleted is
+// not one of them: a user's webhooks are deleted together with the user, so
+// nothing would be left to deliver it.
+var EventTypes = []string{events.TaskCreated, events.TaskUpdated, events.TaskCompleted, events.TaskIncomplete, events.TaskDeleted}
+
+// Store is the outbox the dispatcher works from
+type Store interface {
+	EnqueueDeliveries(ctx context.Context, userId int64, eventType string, payload []byte, at time.Time) (int, error)
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]types.PendingDelivery, error)
+	RecordAttempt(ctx context.Context, deliveryId int64, attempt types.WebhookAttempt, status string, nextAttemptAt time.Time) error
+}
+
+// Dispatcher turns task events into outbox rows and delivers them. Rows are
+// written before delivery is tried, so nothing is lost across restarts;
+// failed attempts are retried with exponential backoff until MaxAttempts.
+type Dispatcher struct {
+	store  Store
+	cfg    config.Webhooks
+	client *http.Client
+
+	wake     chan struct{}
+	mu       sync.Mutex
+	inFlight map[int64]bool
+}
+
+func NewDispatcher(store Store, cfg config.Webhooks) *Dispatcher {
+	return &Dispatcher{
+		store:    store,
+		cfg:      cfg,
+		client:   newClient(cfg),
+		wake:     make(chan struct{}, 1),
+		inFlight: make(map[int64]bool),
+	}
+}
+
+// Enqueue writes an outbox row for every webhook subscribed to the event.
+// It is meant to be registered with events.Bus.Listen.
+func (d *Dispatcher) Enqueue(ev events.Event) {
+	if !slices.Contains(EventTypes, ev.Type) {
+		return
+	}
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		slog.Error("webhook payload encoding failed", slog.Any("error", err))
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	queued, err := d.store.EnqueueDeliveries(ctx, ev.UserId, ev.Type, payload, time.Now().UTC())
+	if err != nil {
+		slog.Error("webhook enqueue failed", slog.Int64("userId", ev.UserId), slog.String("event", ev.Type), slog.Any("error", err))
+		return
+	}
+	if queued > 0 {
+		d.Wake()
+	}
+}
+
+// Wake makes the dispatcher look for due deliveries now rather than at its
+// next poll
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers due deliveries until ctx is done, polling for retries every
+// PollInterval
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	workers := make(chan struct{}, d.cfg.Workers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		due, err := d.store.DueDeliveries(ctx, time.Now().UTC(), d.cfg.BatchSize)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("loading due webhook deliveries failed", slog.Any("error", err))
+		}
+		for _, p := range due {
+			if !d.claim(p.Delivery.Id) {
+				continue
+			}
+			select {
+			case workers <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-workers }()
+				defer d.release(p.Delivery.Id)
+				d.attempt(ctx, p)
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// claim marks a delivery as being attempted so overlapping polls skip it
+func (d *Dispatcher) claim(id int64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.inFlight[id] {
+		return false
+	}
+	d.inFlight[id] = true
+	return true
+}
+
+func (d *Dispatcher) release(id int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.inFlight, id)
+}
+
+// attempt makes one delivery attempt and records its outcome
+func (d *Dispatcher) attempt(ctx context.Context, p types.PendingDelivery) {
+	delivery := p.Delivery
+	start := time.Now()
+	code, err := d.post(ctx, p)
+	if ctx.Err() != nil {
+		// Interrupted by shutdown; the delivery stays due
+		return
+	}
+	attempt := types.WebhookAttempt{
+		AttemptedAt: start.UTC(),
+		StatusCode:  code,
+		DurationMs:  time.Since(start).Milliseconds(),
+	}
+
+	status, next := StatusDelivered, time.Time{}
+	if err != nil {
+		attempt.Error = err.Error()
+		status = StatusPending
+		next = time.Now().UTC().Add(d.backoff(delivery.Attempts + 1))
+		if delivery.Attempts+1 >= d.cfg.MaxAttempts {
+			status = StatusFailed
+		}
+	}
+
+	log := slog.With(slog.Int64("delivery", delivery.Id), slog.Int64("webhook", delivery.WebhookId),
+		slog.String("event", delivery.EventType), slog.Int("status_code", code))
+	switch status {
+	case StatusDelivered:
+		log.Info("webhook delivered")
+	case StatusPending:
+		log.Warn("webhook attempt failed, will retry", slog.Any("error", err), slog.Time("next_attempt_at", next))
+	default:
+		log.Error("webhook delivery failed, giving up", slog.Any("error", err), slog.Int("attempts", delivery.Attempts+1))
+	}
+
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := d.store.RecordAttempt(recordCtx, delivery.Id, attempt, status, next); err != nil {
+		log.Error("recording webhook attempt failed", slog.Any("error", err))
+	}
+}
+
+// post sends the payload and returns the response status. Any status other
+// than 2xx is an error.
+func (d *Dispatcher) post(ctx context.Context, p types.PendingDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	body := []byte(p.Delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-app-webhooks/"+version.Version)
+	req.Header.Set(HeaderEvent, p.Delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(p.Delivery.Id, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(p.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff is the wait before the given attempt number: BackoffBase doubling
+// per attempt up to BackoffMax, with up to 10% jitter so retries of many
+// deliveries spread out
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.cfg.BackoffBase
+	for i := 1; i < attempt && wait < d.cfg.BackoffMax; i++ {
+		wait *= 2
+	}
+	if wait > d.cfg.BackoffMax {
+		wait = d.cfg.BackoffMax
+	}
+	return wait + time.Duration(rand.Int64N(int64(wait)/10+1))
+}
+
+// Sign computes the signature receivers check, hex encoded
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
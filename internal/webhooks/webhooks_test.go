@@ -0,0 +1,264 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/srmty09/Todo-App/internal/config"
+	"github.com/srmty09/Todo-App/internal/events"
+	"github.com/srmty09/Todo-App/internal/types"
+)
+
+type recordedAttempt struct {
+	deliveryId int64
+	attempt    types.WebhookAttempt
+	status     string
+	next       time.Time
+}
+
+// memoryStore is an outbox that only remembers what it was told
+type memoryStore struct {
+	mu       sync.Mutex
+	enqueued []string
+	attempts []recordedAttempt
+}
+
+func (m *memoryStore) EnqueueDeliveries(ctx context.Context, userId int64, eventType string, payload []byte, at time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enqueued = append(m.enqueued, eventType)
+	return 1, nil
+}
+
+func (m *memoryStore) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]types.PendingDelivery, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) RecordAttempt(ctx context.Context, deliveryId int64, attempt types.WebhookAttempt, status string, next time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts = append(m.attempts, recordedAttempt{deliveryId, attempt, status, next})
+	return nil
+}
+
+func (m *memoryStore) last(t *testing.T) recordedAttempt {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.attempts) == 0 {
+		t.Fatal("no attempt recorded")
+	}
+	return m.attempts[len(m.attempts)-1]
+}
+
+func testConfig() config.Webhooks {
+	return config.Webhooks{
+		Workers:              1,
+		PollInterval:         time.Second,
+		Timeout:              5 * time.Second,
+		MaxAttempts:          3,
+		BackoffBase:          time.Minute,
+		BackoffMax:           time.Hour,
+		BatchSize:            10,
+		AllowPrivateNetworks: true,
+	}
+}
+
+func pending(url string, attempts int) types.PendingDelivery {
+	return types.PendingDelivery{
+		Delivery: types.WebhookDelivery{
+			Id:        7,
+			WebhookId: 3,
+			EventType: events.TaskCreated,
+			Payload:   []byte(`{"type":"task.created","user_id":1}`),
+			Attempts:  attempts,
+		},
+		URL:    url,
+		Secret: "0123456789abcdef",
+	}
+}
+
+func TestDeliverySignature(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan received, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{r.Header.Clone(), body}
+	}))
+	defer receiver.Close()
+
+	store := &memoryStore{}
+	d := NewDispatcher(store, testConfig())
+	p := pending(receiver.URL, 0)
+	d.attempt(context.Background(), p)
+
+	req := <-got
+	if string(req.body) != string(p.Delivery.Payload) {
+		t.Errorf("body %s, want the payload %s", req.body, p.Delivery.Payload)
+	}
+	timestamp := req.header.Get(HeaderTimestamp)
+	if timestamp == "" {
+		t.Fatal("no timestamp header")
+	}
+	// Recomputed the way a receiver would, without Sign
+	mac := hmac.New(sha256.New, []byte(p.Secret))
+	mac.Write([]byte(timestamp + "." + string(req.body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if sig := req.header.Get(HeaderSignature); sig != want {
+		t.Errorf("signature %q, want %q", sig, want)
+	}
+	if req.header.Get(HeaderEvent) != events.TaskCreated || req.header.Get(HeaderDelivery) != "7" {
+		t.Errorf("event and delivery headers: %v", req.header)
+	}
+
+	rec := store.last(t)
+	if rec.status != StatusDelivered || rec.attempt.StatusCode != http.StatusOK || rec.attempt.Error != "" {
+		t.Errorf("recorded %+v", rec)
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	cfg := testConfig()
+	store := &memoryStore{}
+	d := NewDispatcher(store, cfg)
+
+	// First failure: retried after BackoffBase, plus up to 10% jitter
+	before := time.Now()
+	d.attempt(context.Background(), pending(receiver.URL, 0))
+	rec := store.last(t)
+	if rec.status != StatusPending || rec.attempt.StatusCode != http.StatusServiceUnavailable || rec.attempt.Error == "" {
+		t.Fatalf("after a 503: %+v", rec)
+	}
+	if wait := rec.next.Sub(before); wait < cfg.BackoffBase || wait > cfg.BackoffBase*11/10+time.Second {
+		t.Errorf("first retry in %s, want about %s", wait, cfg.BackoffBase)
+	}
+
+	// Second failure: the wait doubles
+	before = time.Now()
+	d.attempt(context.Background(), pending(receiver.URL, 1))
+	rec = store.last(t)
+	if wait := rec.next.Sub(before); rec.status != StatusPending || wait < 2*cfg.BackoffBase || wait > 2*cfg.BackoffBase*11/10+time.Second {
+		t.Errorf("second retry: %s in %s, want pending in about %s", rec.status, wait, 2*cfg.BackoffBase)
+	}
+
+	// The third attempt succeeds
+	d.attempt(context.Background(), pending(receiver.URL, 2))
+	if rec := store.last(t); rec.status != StatusDelivered {
+		t.Errorf("third attempt: %+v", rec)
+	}
+}
+
+func TestGiveUpAfterMaxAttempts(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	cfg := testConfig()
+	store := &memoryStore{}
+	d := NewDispatcher(store, cfg)
+	d.attempt(context.Background(), pending(receiver.URL, cfg.MaxAttempts-1))
+	if rec := store.last(t); rec.status != StatusFailed {
+		t.Errorf("last allowed attempt failed but delivery is %s", rec.status)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(&memoryStore{}, testConfig())
+	for _, tc := range []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, time.Hour},
+		{30, time.Hour},
+	} {
+		for i := 0; i < 20; i++ {
+			if got := d.backoff(tc.attempt); got < tc.want || got > tc.want+tc.want/10 {
+				t.Errorf("backoff(%d) = %s, want %s plus up to 10%%", tc.attempt, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestPrivateTargetsRefused(t *testing.T) {
+	var hit atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit.Store(true)
+	}))
+	defer receiver.Close()
+
+	cfg := testConfig()
+	cfg.AllowPrivateNetworks = false
+	d := NewDispatcher(&memoryStore{}, cfg)
+
+	// httptest listens on loopback, by address and by name
+	for _, url := range []string{receiver.URL, strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)} {
+		_, err := d.post(context.Background(), pending(url, 0))
+		if !errors.Is(err, errPrivateAddress) {
+			t.Errorf("%s: got %v, want %v", url, err, errPrivateAddress)
+		}
+	}
+	if hit.Load() {
+		t.Error("the loopback receiver was reached")
+	}
+}
+
+func TestPubliclyRoutable(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"0.0.0.0":          false,
+		"::ffff:127.0.0.1": false,
+		"::ffff:10.0.0.1":  false,
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+	} {
+		if got := publiclyRoutable(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("publiclyRoutable(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestEnqueueSkipsUnsubscribableEvents(t *testing.T) {
+	store := &memoryStore{}
+	d := NewDispatcher(store, testConfig())
+	d.Enqueue(events.Event{Type: events.TaskDeleted, UserId: 1, TaskId: 2})
+	d.Enqueue(events.Event{Type: events.UserDeleted, UserId: 1})
+	if len(store.enqueued) != 1 || store.enqueued[0] != events.TaskDeleted {
+		t.Errorf("enqueued %v, want only %s", store.enqueued, events.TaskDeleted)
+	}
+}